@@ -0,0 +1,231 @@
+package uni
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	RegisterNamespace[AdminRouter]("admin.api")
+}
+
+// DefaultAdminListen is the address for the local admin
+// listener, if none is specified at startup.
+var DefaultAdminListen = "localhost:2019"
+
+// AdminConfig configures the admin endpoint, which serves the
+// routes of the modules in the `admin.api` namespace. The
+// endpoint is only served for configs which have one.
+type AdminConfig struct {
+	// The address the admin endpoint listens on.
+	// Default: DefaultAdminListen
+	Listen string `json:"listen,omitempty"`
+
+	// The hosts (host:port) that requests may be addressed to
+	// and, if they have an Origin header, made from. Other
+	// requests are rejected, so that web pages can not reach
+	// the endpoint through the browser, for example with DNS
+	// rebinding. Default: the listen address, and localhost,
+	// 127.0.0.1 and [::1] on its port.
+	Origins []string `json:"origins,omitempty"`
+
+	// listener is the listener of the endpoint, once
+	// it is served
+	listener net.Listener
+}
+
+// AdminRouter is a type which can return routes for the admin API.
+// Modules in the `admin.api` namespace must implement it.
+type AdminRouter interface {
+	Routes() []AdminRoute
+}
+
+// AdminRoute represents a route for the admin endpoint.
+type AdminRoute struct {
+	// Pattern is an http.ServeMux pattern, e.g. "GET /logs/".
+	Pattern string
+
+	Handler AdminHandler
+}
+
+// AdminHandler is like http.Handler except ServeHTTP may return an error.
+//
+// If any handler encounters an error, it should be returned for proper
+// handling.
+type AdminHandler interface {
+	ServeHTTP(http.ResponseWriter, *http.Request) error
+}
+
+// AdminHandlerFunc is a convenience type like http.HandlerFunc.
+type AdminHandlerFunc func(http.ResponseWriter, *http.Request) error
+
+// ServeHTTP implements the Handler interface.
+func (f AdminHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	return f(w, r)
+}
+
+// APIError is a structured error that every API
+// handler should return for consistency in logging
+// and client responses. If Message is unset, then
+// Err.Error() will be serialized in its place.
+type APIError struct {
+	HTTPStatus int    `json:"-"`
+	Err        error  `json:"-"`
+	Message    string `json:"error"`
+}
+
+func (e APIError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+// NewAdminHandler loads every module in the `admin.api`
+// namespace and returns a handler that serves all of
+// their routes. Errors returned by route handlers are
+// written to the client as JSON APIError values.
+func NewAdminHandler(ctx Context) (http.Handler, error) {
+	mux := http.NewServeMux()
+	for _, m := range ctx.Registry().GetModules("admin.api") {
+		val, err := ctx.LoadModuleByID(string(m.ID), nil)
+		if err != nil {
+			return nil, fmt.Errorf("loading admin API module: %w", err)
+		}
+		router, ok := val.(AdminRouter)
+		if !ok {
+			return nil, fmt.Errorf("module %s is not an AdminRouter", m.ID)
+		}
+		for _, route := range router.Routes() {
+			mux.Handle(route.Pattern, adminHandler{route.Handler})
+		}
+	}
+	return mux, nil
+}
+
+// serve starts serving the admin API of ctx, and returns the
+// function to stop it.
+func (admin *AdminConfig) serve(ctx Context) (func() error, error) {
+	addr := admin.Listen
+	if addr == "" {
+		addr = DefaultAdminListen
+	}
+
+	handler, err := NewAdminHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("admin endpoint: %w", err)
+	}
+	admin.listener = ln
+
+	srv := &http.Server{
+		Handler:           adminHandler{adminOriginCheck{origins: admin.allowedOrigins(addr, ln.Addr()), next: handler}},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Log().Named("admin").Error("serving admin endpoint", zap.Error(err))
+		}
+	}()
+	Log().Named("admin").Info("admin endpoint started", zap.String("address", ln.Addr().String()))
+
+	return func() error {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("stopping admin endpoint: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// allowedOrigins returns the configured origins, or the default
+// ones for the endpoint listening on addr, bound to boundAddr.
+func (admin *AdminConfig) allowedOrigins(addr string, boundAddr net.Addr) []string {
+	if len(admin.Origins) > 0 {
+		origins := make([]string, len(admin.Origins))
+		for i, origin := range admin.Origins {
+			origins[i] = strings.ToLower(origin)
+		}
+		return origins
+	}
+	_, port, _ := net.SplitHostPort(boundAddr.String())
+	return []string{
+		strings.ToLower(addr),
+		net.JoinHostPort("localhost", port),
+		net.JoinHostPort("127.0.0.1", port),
+		net.JoinHostPort("::1", port),
+	}
+}
+
+// adminOriginCheck rejects requests to the admin endpoint whose
+// Host or Origin header is not one of the allowed origins.
+type adminOriginCheck struct {
+	origins []string
+	next    http.Handler
+}
+
+func (c adminOriginCheck) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	if !slices.Contains(c.origins, strings.ToLower(r.Host)) {
+		return APIError{HTTPStatus: http.StatusForbidden, Err: fmt.Errorf("host not allowed: %s", r.Host)}
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !slices.Contains(c.origins, strings.ToLower(u.Host)) {
+			return APIError{HTTPStatus: http.StatusForbidden, Err: fmt.Errorf("origin not allowed: %s", origin)}
+		}
+	}
+	c.next.ServeHTTP(w, r)
+	return nil
+}
+
+// adminHandler adapts an AdminHandler to http.Handler.
+type adminHandler struct {
+	AdminHandler
+}
+
+func (h adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := h.AdminHandler.ServeHTTP(w, r)
+	if err == nil {
+		return
+	}
+
+	var apiErr APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = APIError{HTTPStatus: http.StatusInternalServerError, Err: err}
+	}
+	if apiErr.HTTPStatus == 0 {
+		apiErr.HTTPStatus = http.StatusInternalServerError
+	}
+	if apiErr.Message == "" && apiErr.Err != nil {
+		apiErr.Message = apiErr.Err.Error()
+	}
+
+	Log().Named("admin.api").Error("request error",
+		zap.String("method", r.Method),
+		zap.String("uri", r.RequestURI),
+		zap.Int("status_code", apiErr.HTTPStatus),
+		zap.Error(err))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.HTTPStatus)
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
+
+// Interface guards
+var (
+	_ AdminHandler = adminOriginCheck{}
+)
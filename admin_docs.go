@@ -0,0 +1,60 @@
+package uni
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func init() {
+	RegisterModule(adminDocs{})
+}
+
+// adminDocs is a module that provides the /docs endpoints
+// for exploring the modules compiled into this binary.
+type adminDocs struct{}
+
+// UniModule returns the Uni module information.
+func (adminDocs) UniModule() ModuleInfo {
+	return ModuleInfo{
+		ID:  "admin.api.docs",
+		New: func() Module { return new(adminDocs) },
+	}
+}
+
+// Routes returns the admin routes for the docs API.
+func (ad *adminDocs) Routes() []AdminRoute {
+	return []AdminRoute{
+		{
+			Pattern: "GET /docs",
+			Handler: AdminHandlerFunc(ad.handleList),
+		},
+		{
+			Pattern: "GET /docs/{id}",
+			Handler: AdminHandlerFunc(ad.handleModule),
+		},
+	}
+}
+
+// handleList responds with the IDs of all registered
+// modules as a JSON array.
+func (ad *adminDocs) handleList(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(Modules())
+}
+
+// handleModule responds with the ModuleDoc of the module
+// given in the path. Doc comments are not available to a
+// running binary, so descriptions and defaults are empty.
+func (ad *adminDocs) handleModule(w http.ResponseWriter, r *http.Request) error {
+	md, err := DocumentModule(r.PathValue("id"), nil)
+	if err != nil {
+		return APIError{HTTPStatus: http.StatusNotFound, Err: err}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(md)
+}
+
+// Interface guards
+var (
+	_ AdminRouter = (*adminDocs)(nil)
+)
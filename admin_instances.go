@@ -0,0 +1,44 @@
+package uni
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func init() {
+	RegisterModule(adminInstances{})
+}
+
+// adminInstances is a module that provides the /instances
+// endpoint, which shows the live tree of module instances.
+type adminInstances struct{}
+
+// UniModule returns the Uni module information.
+func (adminInstances) UniModule() ModuleInfo {
+	return ModuleInfo{
+		ID:  "admin.api.instances",
+		New: func() Module { return new(adminInstances) },
+	}
+}
+
+// Routes returns the admin routes for the instances API.
+func (ai *adminInstances) Routes() []AdminRoute {
+	return []AdminRoute{
+		{
+			Pattern: "GET /instances",
+			Handler: AdminHandlerFunc(ai.handleInstances),
+		},
+	}
+}
+
+// handleInstances responds with the tree of module
+// instances, as returned by ModuleInstances.
+func (ai *adminInstances) handleInstances(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(ModuleInstances())
+}
+
+// Interface guards
+var (
+	_ AdminRouter = (*adminInstances)(nil)
+)
@@ -0,0 +1,66 @@
+package uni
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRunAdmin(t *testing.T) {
+	cfg := &Config{Admin: &AdminConfig{Listen: "localhost:0"}}
+	ctx, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := cfg.Admin.listener.Addr().String()
+
+	get := func(host, origin string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/nothing", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host != "" {
+			req.Host = host
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// the endpoint has no routes in this package
+	if got := get("", ""); got != http.StatusNotFound {
+		t.Errorf("allowed request: status %d, want 404", got)
+	}
+	if got := get("", "http://"+addr); got != http.StatusNotFound {
+		t.Errorf("allowed origin: status %d, want 404", got)
+	}
+	if got := get("rebound.example:80", ""); got != http.StatusForbidden {
+		t.Errorf("other host: status %d, want 403", got)
+	}
+	if got := get("", "https://example.com"); got != http.StatusForbidden {
+		t.Errorf("other origin: status %d, want 403", got)
+	}
+
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := http.Get("http://" + addr + "/nothing"); err == nil {
+		t.Error("admin endpoint still served after the config stopped")
+	}
+
+	// the endpoint is opt-in
+	cfg = &Config{}
+	ctx, err = Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,357 @@
+package uni
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AuditLogName is the name of the log in Logging.Logs that
+// receives audit entries. The audit log should be used for
+// nothing else, and it must use the JSON encoder so that it
+// can be checked with VerifyAuditLog.
+const AuditLogName = "audit"
+
+// ErrNoAuditLog is returned when writing an audit entry
+// while no audit log is configured.
+var ErrNoAuditLog = errors.New("no audit log configured")
+
+// LogEntry represents the log data format.
+type LogEntry struct {
+	Time     string   `json:"ts"`       // Timestamp of the log entry
+	Level    string   `json:"level"`    // Log level (e.g., Trace, Debug, Info, Warning, Error, Fatal and Panic)
+	Category string   `json:"category"` // Category or type of the log (e.g., user-action)
+	Tags     []string `json:"tags"`     // Tags related to the log
+	Msg      Msger    `json:"msg"`      // Msg content, implemented via the interface for customization
+	Extra    Extra    `json:"extra"`    // Extra content, implemented via the interface for customization
+}
+
+type Msger interface {
+	MsgToString() (string, error)
+}
+
+type Extra interface {
+	ExtraToString() (string, error)
+}
+
+// LogMsg is a Msger for plain text messages.
+type LogMsg string
+
+// MsgToString implements Msger.
+func (m LogMsg) MsgToString() (string, error) { return string(m), nil }
+
+// LogExtra is an Extra which is serialized as a JSON object.
+type LogExtra map[string]any
+
+// ExtraToString implements Extra.
+func (e LogExtra) ExtraToString() (string, error) {
+	if len(e) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(e)
+	return string(b), err
+}
+
+// Audit writes an entry to the audit log of the current config.
+// msg and extra may be nil. It returns ErrNoAuditLog if the
+// config does not define a log named AuditLogName.
+func (ctx Context) Audit(category string, tags []string, msg Msger, extra Extra) error {
+	if ctx.cfg == nil || ctx.cfg.auditLog == nil {
+		return ErrNoAuditLog
+	}
+	return ctx.cfg.auditLog.Write(LogEntry{
+		Category: category,
+		Tags:     tags,
+		Msg:      msg,
+		Extra:    extra,
+	})
+}
+
+// AuditLog emits tamper-evident audit entries. Every entry
+// carries a sequence number, the hash of the entry before it
+// and a hash over its own contents, so removing, reordering
+// or modifying entries breaks the chain. Use VerifyAuditLog
+// to check a log written this way.
+//
+// The hash is an HMAC-SHA256 if the log has a key, and plain
+// SHA-256 otherwise. Without a key, anyone who can write to
+// the log can also compute the hashes of entries they forge.
+//
+// Each time an AuditLog is created, a new chain starts at
+// sequence number 1. Closing the log ends the chain with an
+// entry of its own, so that the next chain in the same file
+// is known to follow a complete one.
+//
+// An AuditLog is safe for concurrent use.
+type AuditLog struct {
+	core zapcore.Core
+	key  []byte
+
+	mu     sync.Mutex
+	seq    uint64
+	prev   string
+	closed bool
+}
+
+// NewAuditLog returns an audit log that writes to core. key
+// may be nil, in which case entries are hashed without one.
+func NewAuditLog(core zapcore.Core, key []byte) *AuditLog {
+	return &AuditLog{core: core, key: key}
+}
+
+// Write appends entry to the audit log. An empty Time is set
+// to the current time and an empty Level means INFO.
+//
+// Unlike regular logs, audit entries are never sampled or
+// dropped silently: if the log does not accept the entry's
+// level or the entry can not be written, an error is returned
+// and the chain is not advanced.
+func (al *AuditLog) Write(entry LogEntry) error {
+	return al.write(entry, false)
+}
+
+// Close ends the chain with a final entry, after which no
+// more entries can be written. It does not close the core.
+func (al *AuditLog) Close() error {
+	return al.write(LogEntry{
+		Level:    zapcore.LevelOf(al.core).CapitalString(),
+		Category: "audit",
+		Msg:      LogMsg("audit log closed"),
+	}, true)
+}
+
+func (al *AuditLog) write(entry LogEntry, final bool) error {
+	level, err := parseLogLevel(entry.Level)
+	if err != nil {
+		return err
+	}
+	if !al.core.Enabled(level) {
+		return fmt.Errorf("audit log does not accept %s entries", level)
+	}
+
+	now := nowFunc()
+	rec := auditRecord{
+		Time:     entry.Time,
+		Level:    level.String(),
+		Category: entry.Category,
+		Tags:     entry.Tags,
+		Final:    final,
+	}
+	if rec.Time == "" {
+		rec.Time = now.Format(time.RFC3339Nano)
+	}
+	if entry.Msg != nil {
+		if rec.Msg, err = entry.Msg.MsgToString(); err != nil {
+			return fmt.Errorf("audit message: %v", err)
+		}
+	}
+	if entry.Extra != nil {
+		if rec.Extra, err = entry.Extra.ExtraToString(); err != nil {
+			return fmt.Errorf("audit extra: %v", err)
+		}
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if al.closed {
+		return errors.New("audit log is closed")
+	}
+	rec.Seq = al.seq + 1
+	rec.PrevHash = al.prev
+	rec.Hash = rec.sum(al.key)
+
+	// write to the core directly instead of going through Check,
+	// so that sampling can not drop entries from the chain
+	err = al.core.Write(zapcore.Entry{
+		Level:      level,
+		Time:       now,
+		LoggerName: AuditLogName,
+		Message:    rec.Msg,
+	}, []zapcore.Field{zap.Any("audit", rec)})
+	if err != nil {
+		return fmt.Errorf("writing audit entry: %v", err)
+	}
+
+	al.seq, al.prev, al.closed = rec.Seq, rec.Hash, final
+	return nil
+}
+
+// auditRecord is the part of an audit log entry that is
+// covered by the hash chain. It is emitted as the "audit"
+// field of the log entry.
+type auditRecord struct {
+	Seq      uint64   `json:"seq"`
+	Time     string   `json:"ts"`
+	Level    string   `json:"level"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags,omitempty"`
+	Msg      string   `json:"msg"`
+	Extra    string   `json:"extra,omitempty"`
+	Final    bool     `json:"final,omitempty"` // ends the chain
+	PrevHash string   `json:"prev_hash"`
+	Hash     string   `json:"hash"`
+}
+
+// sum returns the hex-encoded hash of the record, which
+// covers all its fields except Hash itself. With a key, the
+// hash is an HMAC-SHA256.
+func (r auditRecord) sum(key []byte) string {
+	r.Hash = ""
+	b, _ := json.Marshal(r) // cannot fail: only strings, numbers and bools
+	if key == nil {
+		h := sha256.Sum256(b)
+		return hex.EncodeToString(h[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuditProblem describes a line of an audit log that
+// breaks the hash chain.
+type AuditProblem struct {
+	Line   int    `json:"line"`
+	Seq    uint64 `json:"seq,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (p AuditProblem) String() string {
+	if p.Seq == 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Reason)
+	}
+	return fmt.Sprintf("line %d (seq %d): %s", p.Line, p.Seq, p.Reason)
+}
+
+// AuditReport is the result of VerifyAuditLog.
+type AuditReport struct {
+	// Entries is the number of audit entries read.
+	Entries int `json:"entries"`
+
+	// Chains is the number of chains found in the log. A new
+	// chain starts every time the audit log is opened.
+	Chains int `json:"chains"`
+
+	// Problems lists the entries that were modified, are out
+	// of order or follow a gap in the chain, including chains
+	// that start after one which was not closed.
+	Problems []AuditProblem `json:"problems,omitempty"`
+}
+
+// OK returns true if no problems were found.
+func (r AuditReport) OK() bool { return len(r.Problems) == 0 }
+
+// maxAuditLineSize is the longest line VerifyAuditLog accepts.
+const maxAuditLineSize = 1 << 20
+
+// VerifyAuditLog reads a JSON-encoded audit log from r and
+// checks its hash chain, using the key the log was written
+// with (nil if it had none). Lines which are modified, missing
+// or out of order are reported as problems; the returned
+// error is only non-nil if r could not be read.
+//
+// A chain may only start in the middle of the log after a
+// chain that was closed. If the process writing the log
+// exited without closing it, the next chain is reported as
+// well, since that can not be told apart from entries being
+// removed from the end of the previous chain.
+//
+// Truncating the end of a log, or removing whole chains from
+// its beginning, can not be detected from the log alone.
+func VerifyAuditLog(r io.Reader, key []byte) (AuditReport, error) {
+	var (
+		report AuditReport
+		prev   *auditRecord
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry struct {
+			Audit *auditRecord `json:"audit"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			report.Problems = append(report.Problems, AuditProblem{Line: line, Reason: "not a JSON log entry"})
+			continue
+		}
+		rec := entry.Audit
+		if rec == nil {
+			report.Problems = append(report.Problems, AuditProblem{Line: line, Reason: "not an audit entry"})
+			continue
+		}
+		report.Entries++
+
+		problem := AuditProblem{Line: line, Seq: rec.Seq}
+		switch {
+		case rec.Hash != rec.sum(key):
+			problem.Reason = "entry was modified: hash mismatch"
+		case rec.Seq == 1 && rec.PrevHash == "":
+			report.Chains++
+			if prev != nil && !prev.Final {
+				problem.Reason = fmt.Sprintf("new chain started, but the chain of seq %d was not closed", prev.Seq)
+			}
+		case prev == nil:
+			problem.Reason = "entries before this one are missing"
+		case prev.Final:
+			problem.Reason = fmt.Sprintf("entry follows the end of its chain at seq %d", prev.Seq)
+		case rec.PrevHash != prev.Hash && rec.Seq != prev.Seq+1:
+			problem.Reason = fmt.Sprintf("chain broken: expected seq %d after seq %d", prev.Seq+1, prev.Seq)
+		case rec.PrevHash != prev.Hash:
+			problem.Reason = fmt.Sprintf("chain broken: previous hash does not match seq %d", prev.Seq)
+		case rec.Seq != prev.Seq+1:
+			problem.Reason = fmt.Sprintf("unexpected seq after seq %d", prev.Seq)
+		}
+		if problem.Reason != "" {
+			report.Problems = append(report.Problems, problem)
+		}
+
+		// continue checking from this entry, so that one
+		// problem is not reported again for every later entry
+		prev = rec
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// openAuditLog provisions the log named AuditLogName, if one
+// is configured, and returns an AuditLog that writes to it,
+// keyed with AuditKey. The log's writer is recorded in
+// WriterIDs so it is closed along with the other logs.
+func (logging *Logging) openAuditLog(ctx Context) (*AuditLog, error) {
+	cl, ok := logging.Logs[AuditLogName]
+	if !ok || cl == nil {
+		return nil, nil
+	}
+
+	var key []byte
+	if logging.AuditKey != "" {
+		k, err := NewReplacer().ReplaceOrErr(logging.AuditKey, true, true)
+		if err != nil {
+			return nil, &ConfigError{Pointer: "/logging/audit_key", Err: err}
+		}
+		key = []byte(k)
+	}
+
+	if err := cl.Provision(ctx.WithConfigPointer("logging", "logs", AuditLogName)); err != nil {
+		return nil, fmt.Errorf("setting up audit log: %w", err)
+	}
+	logging.WriterIDs = append(logging.WriterIDs, cl.writerProvider.WriterID())
+	return NewAuditLog(cl.Core(), key), nil
+}
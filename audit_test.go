@@ -0,0 +1,232 @@
+package uni
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// writeAuditLog writes n audit entries, hashed with key, as
+// JSON lines and returns them. If closeLog is true, the log
+// is closed afterwards.
+func writeAuditLog(t *testing.T, key []byte, n int, closeLog bool) []string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", TimeKey: "ts", EncodeTime: zapcore.EpochTimeEncoder})
+	al := NewAuditLog(zapcore.NewCore(enc, zapcore.AddSync(&buf), zapcore.InfoLevel), key)
+	ctx := Context{cfg: &Config{auditLog: al}}
+
+	for i := range n {
+		err := ctx.Audit("user-action", []string{"login"}, LogMsg("user logged in"), LogExtra{"n": i})
+		if err != nil {
+			t.Fatalf("Audit() = %v", err)
+		}
+	}
+	if closeLog {
+		if err := al.Close(); err != nil {
+			t.Fatalf("Close() = %v", err)
+		}
+	}
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func verifyLines(t *testing.T, key []byte, lines []string) AuditReport {
+	t.Helper()
+	report, err := VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), key)
+	if err != nil {
+		t.Fatalf("VerifyAuditLog() error = %v", err)
+	}
+	return report
+}
+
+func TestAuditLogChain(t *testing.T) {
+	lines := writeAuditLog(t, nil, 3, true)
+
+	var entry struct {
+		Msg   string      `json:"msg"`
+		Audit auditRecord `json:"audit"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Msg != "user logged in" || entry.Audit.Seq != 2 || entry.Audit.Extra != `{"n":1}` {
+		t.Errorf("unexpected entry: %s", lines[1])
+	}
+
+	if err := json.Unmarshal([]byte(lines[3]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Audit.Final || entry.Audit.Seq != 4 {
+		t.Errorf("unexpected closing entry: %s", lines[3])
+	}
+
+	report := verifyLines(t, nil, lines)
+	if !report.OK() || report.Entries != 4 || report.Chains != 1 {
+		t.Fatalf("report = %+v, want 4 valid entries in 1 chain", report)
+	}
+
+	// a restarted log starts a new chain
+	report = verifyLines(t, nil, append(slices.Clone(lines), writeAuditLog(t, nil, 2, false)...))
+	if !report.OK() || report.Chains != 2 {
+		t.Fatalf("report = %+v, want 2 valid chains", report)
+	}
+}
+
+func TestAuditLogKey(t *testing.T) {
+	key := []byte("secret")
+	lines := writeAuditLog(t, key, 2, true)
+	if report := verifyLines(t, key, lines); !report.OK() {
+		t.Fatalf("report = %+v, want a valid chain", report)
+	}
+	if report := verifyLines(t, []byte("other"), lines); len(report.Problems) != 3 {
+		t.Errorf("with the wrong key: problems = %v, want all 3 entries", report.Problems)
+	}
+
+	// without the key, entries can not be forged
+	forged := append(slices.Clone(lines[:1]), writeAuditLog(t, nil, 1, true)...)
+	if report := verifyLines(t, key, forged); report.OK() {
+		t.Error("forged entries verified with the key")
+	}
+}
+
+func TestVerifyAuditLogProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func([]string) []string
+		want   []AuditProblem
+	}{
+		{
+			name: "modified entry",
+			tamper: func(l []string) []string {
+				l[1] = strings.Replace(l[1], `"category":"user-action"`, `"category":"other"`, 1)
+				return l
+			},
+			want: []AuditProblem{{Line: 2, Seq: 2, Reason: "entry was modified: hash mismatch"}},
+		},
+		{
+			name:   "removed entry",
+			tamper: func(l []string) []string { return append(l[:1], l[2:]...) },
+			want:   []AuditProblem{{Line: 2, Seq: 3, Reason: "chain broken: expected seq 2 after seq 1"}},
+		},
+		{
+			name:   "removed first entry",
+			tamper: func(l []string) []string { return l[1:] },
+			want:   []AuditProblem{{Line: 1, Seq: 2, Reason: "entries before this one are missing"}},
+		},
+		{
+			name: "reordered entries",
+			tamper: func(l []string) []string {
+				l[1], l[2] = l[2], l[1]
+				return l
+			},
+			want: []AuditProblem{
+				{Line: 2, Seq: 3, Reason: "chain broken: expected seq 2 after seq 1"},
+				{Line: 3, Seq: 2, Reason: "chain broken: expected seq 4 after seq 3"},
+				{Line: 4, Seq: 4, Reason: "chain broken: expected seq 3 after seq 2"},
+			},
+		},
+		{
+			name: "replaced end of chain",
+			tamper: func(l []string) []string {
+				return append(l[:2], writeAuditLog(t, nil, 1, true)...)
+			},
+			want: []AuditProblem{{Line: 3, Seq: 1, Reason: "new chain started, but the chain of seq 2 was not closed"}},
+		},
+		{
+			name: "entry after closed chain",
+			tamper: func(l []string) []string {
+				return append(l, writeAuditLog(t, nil, 3, true)[2])
+			},
+			want: []AuditProblem{{Line: 5, Seq: 3, Reason: "entry follows the end of its chain at seq 4"}},
+		},
+		{
+			name: "foreign line",
+			tamper: func(l []string) []string {
+				return append(l, `{"level":"info","msg":"hello"}`, "garbage")
+			},
+			want: []AuditProblem{
+				{Line: 5, Reason: "not an audit entry"},
+				{Line: 6, Reason: "not a JSON log entry"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := verifyLines(t, nil, tt.tamper(writeAuditLog(t, nil, 3, true)))
+			if len(report.Problems) != len(tt.want) {
+				t.Fatalf("problems = %v, want %v", report.Problems, tt.want)
+			}
+			for i := range tt.want {
+				if report.Problems[i] != tt.want[i] {
+					t.Errorf("problem %d = %v, want %v", i, report.Problems[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAuditLogErrors(t *testing.T) {
+	if err := (Context{}).Audit("c", nil, nil, nil); !errors.Is(err, ErrNoAuditLog) {
+		t.Errorf("Audit() without audit log = %v, want ErrNoAuditLog", err)
+	}
+
+	al := NewAuditLog(zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{}), zapcore.AddSync(&bytes.Buffer{}), zapcore.WarnLevel), nil)
+	if err := al.Write(LogEntry{Category: "c"}); err == nil {
+		t.Error("expected error for entry below the log's level")
+	}
+	if err := al.Write(LogEntry{Category: "c", Level: "ERROR"}); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if al.seq != 1 {
+		t.Errorf("seq = %d, want 1: rejected entries must not advance the chain", al.seq)
+	}
+	if err := al.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if err := al.Write(LogEntry{Category: "c", Level: "ERROR"}); err == nil {
+		t.Error("expected error writing to a closed audit log")
+	}
+}
+
+func TestRunAuditLog(t *testing.T) {
+	t.Setenv("UNI_TEST_AUDIT_KEY", "secret")
+
+	cfg := &Config{Logging: &Logging{
+		Logs:     map[string]*CustomLog{AuditLogName: {}},
+		AuditKey: "{env.UNI_TEST_AUDIT_KEY}",
+	}}
+	ctx, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Audit("config", []string{"run"}, LogMsg("config loaded"), nil); err != nil {
+		t.Errorf("Audit() = %v, want the audit log of the config", err)
+	}
+	if got := string(cfg.auditLog.key); got != "secret" {
+		t.Errorf("audit key = %q, want the value of the placeholder", got)
+	}
+
+	// stopping the config ends the chain
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Audit("config", nil, nil, nil); err == nil {
+		t.Error("expected error writing to the audit log of a stopped config")
+	}
+
+	_, err = Run(&Config{Logging: &Logging{
+		Logs:     map[string]*CustomLog{AuditLogName: {}},
+		AuditKey: "{env.UNI_TEST_MISSING_AUDIT_KEY}",
+	}})
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Pointer != "/logging/audit_key" {
+		t.Errorf("Run() with missing audit key = %v, want config error at /logging/audit_key", err)
+	}
+}
@@ -0,0 +1,382 @@
+package uni
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ConfigError is an error in a config, with the location of
+// the value that caused it. Errors from loading modules carry
+// a ConfigError, which can be retrieved with errors.As even if
+// they were wrapped by the modules that loaded them.
+type ConfigError struct {
+	// Pointer is the JSON pointer (RFC 6901) of the offending
+	// value, such as "/logging/logs/default/writer". It is
+	// relative to the document that was being decoded, which
+	// is the whole config unless the module that loaded the
+	// failing module was provisioned without a pointer (see
+	// Context.WithConfigPointer).
+	Pointer string
+
+	// Module is the ID of the module whose config is at
+	// fault, if any.
+	Module string
+
+	// Offset is the byte offset in the decoded document at
+	// which a syntax error was found, or 0 if unknown. It
+	// is only set for errors that are not located by Pointer.
+	Offset int64
+
+	// Line and Column are the 1-based position of the value
+	// in the config file, and Snippet is the line holding it
+	// followed by a line with a caret under the value. They
+	// are set by Locate.
+	Line, Column int
+	Snippet      string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the position, module and cause of the error.
+func (e *ConfigError) Error() string {
+	var sb strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, "line %d, column %d: ", e.Line, e.Column)
+	}
+	if e.Pointer != "" {
+		sb.WriteString(e.Pointer)
+		sb.WriteString(": ")
+	}
+	if e.Module != "" {
+		sb.WriteString(e.Module)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(e.Err.Error())
+	if e.Line == 0 && e.Offset > 0 {
+		fmt.Fprintf(&sb, ", at offset %d", e.Offset)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// Locate sets the Line, Column and Snippet of the error by
+// finding the value at its Pointer in cfgJSON, which must be
+// the document the pointer is relative to. If the value does
+// not exist, for example because the pointer names an unknown
+// field, the closest existing parent is located instead.
+func (e *ConfigError) Locate(cfgJSON []byte) {
+	var offset int64
+	if e.Pointer == "" && e.Offset > 0 {
+		// syntax errors are reported after reading the byte at fault
+		offset = min(e.Offset-1, int64(len(cfgJSON)))
+	} else {
+		var ok bool
+		offset, ok = locateJSONPointer(cfgJSON, e.Pointer)
+		if !ok {
+			return
+		}
+	}
+
+	lineStart := bytes.LastIndexByte(cfgJSON[:offset], '\n') + 1
+	lineEnd := bytes.IndexByte(cfgJSON[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(cfgJSON)
+	} else {
+		lineEnd += lineStart
+	}
+	line := strings.TrimSuffix(string(cfgJSON[lineStart:lineEnd]), "\r")
+	prefix := string(cfgJSON[lineStart:offset])
+
+	e.Line = bytes.Count(cfgJSON[:offset], []byte{'\n'}) + 1
+	e.Column = utf8.RuneCountInString(prefix) + 1
+
+	// keep the tabs in front of the caret, so that it lines
+	// up with the value however wide tabs are displayed
+	caret := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, prefix)
+	gutter := strconv.Itoa(e.Line)
+	e.Snippet = fmt.Sprintf("%s | %s\n%s | %s^", gutter, line, strings.Repeat(" ", len(gutter)), caret)
+}
+
+// WithConfigPointer returns a copy of ctx in which the JSON
+// pointer of the config value being provisioned is extended by
+// the given reference tokens, which are escaped as needed. The
+// pointer is used to locate the ConfigErrors of modules loaded
+// with the returned context. Modules that provision structs
+// which load modules themselves, rather than through LoadModule,
+// should use it to tell where those structs are, for example:
+//
+//	err := route.Provision(ctx.WithConfigPointer("routes", strconv.Itoa(i)))
+func (ctx Context) WithConfigPointer(tokens ...string) Context {
+	for _, token := range tokens {
+		ctx.configPointer += "/" + escapeJSONPointerToken(token)
+	}
+	return ctx
+}
+
+// ConfigPointer returns the JSON pointer of the config value
+// being provisioned with ctx, if known.
+func (ctx Context) ConfigPointer() string {
+	return ctx.configPointer
+}
+
+// escapeJSONPointerToken escapes token for use in a JSON pointer.
+func escapeJSONPointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// newDecodeConfigError returns the ConfigError for err, which
+// was returned by decoding doc into v, or nil if err does not
+// describe a problem with the JSON document.
+func newDecodeConfigError(doc []byte, v any, err error) *ConfigError {
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &ConfigError{Offset: syntaxErr.Offset, Err: err}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		ce := &ConfigError{Err: err}
+		if typeErr.Field != "" {
+			for token := range strings.SplitSeq(typeErr.Field, ".") {
+				ce.Pointer += "/" + escapeJSONPointerToken(token)
+			}
+		} else {
+			ce.Offset = typeErr.Offset
+		}
+		return ce
+	}
+
+	// the decoder does not export the name or the position of
+	// unknown fields, so the name is taken from the message and
+	// the member is found by decoding doc along the type of v;
+	// if it can't be found, the error is not located any closer
+	// than the document itself
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if name, err2 := strconv.Unquote(name); err2 == nil {
+			f := &unknownFieldFinder{dec: json.NewDecoder(bytes.NewReader(doc)), name: name}
+			pointer, _ := f.value("", decodedType(reflect.ValueOf(v)))
+			return &ConfigError{Pointer: pointer, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// unknownFieldFinder walks a JSON document along the Go type it
+// is decoded into to find the member which names no field.
+type unknownFieldFinder struct {
+	dec  *json.Decoder
+	name string
+}
+
+// value reads the value at path, which is decoded into typ, from
+// the decoder and returns the pointer of the first member named
+// f.name that has no field in the struct it is decoded into. typ
+// is nil for values that are not decoded strictly.
+func (f *unknownFieldFinder) value(path string, typ reflect.Type) (string, bool) {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ != nil && (typ.Kind() == reflect.Interface ||
+		reflect.PointerTo(typ).Implements(jsonUnmarshalerType) ||
+		reflect.PointerTo(typ).Implements(textUnmarshalerType)) {
+		// such values, like json.RawMessage, decode themselves
+		typ = nil
+	}
+
+	tok, err := f.dec.Token()
+	if err != nil {
+		return "", false
+	}
+	switch tok {
+	case json.Delim('{'):
+		for f.dec.More() {
+			tok, err := f.dec.Token()
+			if err != nil {
+				return "", false
+			}
+			key := tok.(string)
+			memberPath := path + "/" + escapeJSONPointerToken(key)
+			var elem reflect.Type
+			switch {
+			case typ == nil:
+			case typ.Kind() == reflect.Struct:
+				field, ok := decodedFieldType(typ, key)
+				if !ok && key == f.name {
+					return memberPath, true
+				}
+				elem = field
+			case typ.Kind() == reflect.Map:
+				elem = typ.Elem()
+			}
+			if pointer, ok := f.value(memberPath, elem); ok {
+				return pointer, true
+			}
+		}
+		if _, err := f.dec.Token(); err != nil {
+			return "", false
+		}
+	case json.Delim('['):
+		var elem reflect.Type
+		if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+			elem = typ.Elem()
+		}
+		for i := 0; f.dec.More(); i++ {
+			if pointer, ok := f.value(path+"/"+strconv.Itoa(i), elem); ok {
+				return pointer, true
+			}
+		}
+		if _, err := f.dec.Token(); err != nil {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// decodedType returns the type which encoding/json decodes into
+// when given rv, following the values of non-nil interfaces the
+// way it does.
+func decodedType(rv reflect.Value) reflect.Type {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return rv.Type()
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Type()
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// decodedFieldType returns the type of the field of the struct
+// type typ which encoding/json decodes the member key into,
+// preferring an exact match of the name over a case-insensitive one.
+func decodedFieldType(typ reflect.Type, key string) (reflect.Type, bool) {
+	var folded reflect.Type
+	for _, field := range structJSONFields(typ) {
+		if field.name == key {
+			return field.field.Type, true
+		}
+		if folded == nil && strings.EqualFold(field.name, key) {
+			folded = field.field.Type
+		}
+	}
+	return folded, folded != nil
+}
+
+// locateJSONPointer returns the offset of the value at pointer in
+// doc or, for object members, of its key. If there is no such value,
+// the offset of the deepest existing parent is returned. It returns
+// false if doc does not start with a JSON value.
+func locateJSONPointer(doc []byte, pointer string) (int64, bool) {
+	l := &pointerLocator{
+		doc:     doc,
+		dec:     json.NewDecoder(bytes.NewReader(doc)),
+		target:  pointer,
+		bestLen: -1,
+	}
+	// errors are irrelevant: the best match so far is as close as it gets
+	_ = l.value("")
+	return l.best, l.bestLen >= 0
+}
+
+// errPointerFound stops a pointerLocator once the value is found.
+var errPointerFound = errors.New("found")
+
+// pointerLocator walks a JSON document to find the position of
+// a JSON pointer in it.
+type pointerLocator struct {
+	doc     []byte
+	dec     *json.Decoder
+	target  string
+	best    int64
+	bestLen int
+}
+
+// visit records that the value at path starts at offset.
+func (l *pointerLocator) visit(path string, offset int64) error {
+	if len(path) <= l.bestLen {
+		return nil
+	}
+	if path == l.target {
+		l.best, l.bestLen = offset, len(path)
+		return errPointerFound
+	}
+	if strings.HasPrefix(l.target, path+"/") {
+		l.best, l.bestLen = offset, len(path)
+	}
+	return nil
+}
+
+// next returns the offset of the next token, skipping the
+// separators which the decoder consumes along with tokens.
+func (l *pointerLocator) next() int64 {
+	offset := l.dec.InputOffset()
+	for offset < int64(len(l.doc)) && strings.IndexByte(" \t\r\n,:", l.doc[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// value reads the value at path from the decoder.
+func (l *pointerLocator) value(path string) error {
+	if err := l.visit(path, l.next()); err != nil {
+		return err
+	}
+	tok, err := l.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for l.dec.More() {
+			keyOffset := l.next()
+			key, err := l.dec.Token()
+			if err != nil {
+				return err
+			}
+			memberPath := path + "/" + escapeJSONPointerToken(key.(string))
+			if err := l.visit(memberPath, keyOffset); err != nil {
+				return err
+			}
+			if err := l.value(memberPath); err != nil {
+				return err
+			}
+		}
+		_, err = l.dec.Token()
+	case json.Delim('['):
+		for i := 0; l.dec.More(); i++ {
+			if err := l.value(path + "/" + strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+		_, err = l.dec.Token()
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package uni
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type configErrorTestWriter struct {
+	Mode int `json:"mode,omitempty"`
+}
+
+func (configErrorTestWriter) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "config_error_test.writers.file", New: func() Module { return new(configErrorTestWriter) }}
+}
+
+type configErrorTestHost struct {
+	WritersRaw []json.RawMessage `json:"writers,omitempty" uni:"namespace=config_error_test.writers inline_key=output"`
+}
+
+func (configErrorTestHost) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "config_error_test.host", New: func() Module { return new(configErrorTestHost) }}
+}
+
+func (h *configErrorTestHost) Provision(ctx Context) error {
+	if _, err := ctx.LoadModule(h, "WritersRaw"); err != nil {
+		return fmt.Errorf("loading writers: %w", err)
+	}
+	return nil
+}
+
+func TestConfigError(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	r.RegisterModule(configErrorTestWriter{})
+	r.RegisterModule(configErrorTestHost{})
+
+	for _, tc := range []struct {
+		name        string
+		writer      string
+		wantPointer string
+		wantModule  string
+		wantAt      string // the text at the reported position
+	}{
+		{
+			name:        "wrong type",
+			writer:      `{"output": "file", "mode": "x"}`,
+			wantPointer: "/hosts/a~1b/writers/1/mode",
+			wantModule:  "config_error_test.writers.file",
+			wantAt:      `"mode"`,
+		},
+		{
+			name:        "unknown field",
+			writer:      `{"output": "file", "nope": 1}`,
+			wantPointer: "/hosts/a~1b/writers/1/nope",
+			wantModule:  "config_error_test.writers.file",
+			wantAt:      `"nope"`,
+		},
+		{
+			name:        "unknown module",
+			writer:      `{"output": "pipe"}`,
+			wantPointer: "/hosts/a~1b/writers/1",
+			wantModule:  "config_error_test.writers.pipe",
+			wantAt:      `{"output": "pipe"}`,
+		},
+		{
+			name:        "missing module name",
+			writer:      `{"mode": 1}`,
+			wantPointer: "/hosts/a~1b/writers/1",
+			wantAt:      `{"mode": 1}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfgJSON := []byte("{\n\t\"hosts\": {\n\t\t\"a/b\": {\n\t\t\t\"writers\": [\n\t\t\t\t{\"output\": \"file\"},\n\t\t\t\t" +
+				tc.writer + "\n\t\t\t]\n\t\t}\n\t}\n}\n")
+			var cfg struct {
+				Hosts map[string]json.RawMessage `json:"hosts"`
+			}
+			if err := json.Unmarshal(cfgJSON, &cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+			defer cancel()
+			_, err := ctx.WithConfigPointer("hosts", "a/b").LoadModuleByID("config_error_test.host", cfg.Hosts["a/b"])
+
+			var ce *ConfigError
+			if !errors.As(err, &ce) {
+				t.Fatalf("LoadModuleByID() error = %v, want a ConfigError", err)
+			}
+			if ce.Pointer != tc.wantPointer || ce.Module != tc.wantModule {
+				t.Errorf("Pointer, Module = %q, %q; want %q, %q", ce.Pointer, ce.Module, tc.wantPointer, tc.wantModule)
+			}
+
+			ce.Locate(cfgJSON)
+			if ce.Line != 6 {
+				t.Errorf("Line = %d, want 6", ce.Line)
+			}
+			if want := 5 + strings.Index(tc.writer, tc.wantAt); ce.Column != want {
+				t.Errorf("Column = %d, want %d", ce.Column, want)
+			}
+			wantSnippet := "6 | \t\t\t\t" + tc.writer + "\n  | \t\t\t\t" + strings.Repeat(" ", ce.Column-5) + "^"
+			if ce.Snippet != wantSnippet {
+				t.Errorf("Snippet =\n%s\nwant\n%s", ce.Snippet, wantSnippet)
+			}
+			if want := fmt.Sprintf("line 6, column %d: %s: ", ce.Column, tc.wantPointer); !strings.HasPrefix(ce.Error(), want) {
+				t.Errorf("Error() = %q, want prefix %q", ce.Error(), want)
+			}
+			if !strings.HasPrefix(err.Error(), "config_error_test.host: provision: loading writers: "+tc.wantPointer+": ") {
+				t.Errorf("wrapping error = %q", err)
+			}
+		})
+	}
+}
+
+func TestStrictUnmarshalJSONConfigError(t *testing.T) {
+	var v struct {
+		Logs map[string]struct {
+			Level string `json:"level"`
+		} `json:"logs"`
+	}
+
+	cfgJSON := []byte("{\n  \"logs\": {\n    \"default\": {\"level\": 3}\n  }\n}")
+	var ce *ConfigError
+	if err := StrictUnmarshalJSON(cfgJSON, &v); !errors.As(err, &ce) {
+		t.Fatalf("StrictUnmarshalJSON() error = %v, want a ConfigError", err)
+	}
+	ce.Locate(cfgJSON)
+	if ce.Pointer != "/logs/default/level" || ce.Line != 3 || ce.Column != 17 {
+		t.Errorf("type error at %q, line %d, column %d", ce.Pointer, ce.Line, ce.Column)
+	}
+
+	cfgJSON = []byte("{\n  \"logs\": {}\n  \"x\": 1\n}")
+	if err := StrictUnmarshalJSON(cfgJSON, &v); !errors.As(err, &ce) {
+		t.Fatalf("StrictUnmarshalJSON() error = %v, want a ConfigError", err)
+	}
+	ce.Locate(cfgJSON)
+	if ce.Pointer != "" || ce.Line != 3 || ce.Column != 3 {
+		t.Errorf("syntax error at %q, line %d, column %d", ce.Pointer, ce.Line, ce.Column)
+	}
+}
+
+func TestStrictUnmarshalJSONUnknownField(t *testing.T) {
+	type embedded struct {
+		Name string `json:"name"`
+	}
+	type log struct {
+		embedded
+		Level  string          `json:"level"`
+		Writer json.RawMessage `json:"writer"`
+	}
+	var v struct {
+		Logs  map[string]*log `json:"logs"`
+		Hosts []log           `json:"hosts"`
+	}
+
+	for _, tc := range []struct {
+		doc         string
+		wantPointer string
+	}{
+		{doc: `{"extra": 1}`, wantPointer: "/extra"},
+		{doc: `{"logs": {"a": {"LEVEL": "x", "name": "a"}, "b~/": {"extra": 1}}}`, wantPointer: "/logs/b~0~1/extra"},
+		// members of raw values are not decoded strictly
+		{doc: `{"logs": {"a": {"writer": {"extra": 1}}, "b": {"extra": 2}}}`, wantPointer: "/logs/b/extra"},
+		{doc: `{"hosts": [{"level": "x"}, {"extra": 1}]}`, wantPointer: "/hosts/1/extra"},
+	} {
+		err := StrictUnmarshalJSON([]byte(tc.doc), &v)
+		var ce *ConfigError
+		if !errors.As(err, &ce) {
+			t.Errorf("%s: error = %v, want a ConfigError", tc.doc, err)
+			continue
+		}
+		if ce.Pointer != tc.wantPointer {
+			t.Errorf("%s: Pointer = %q, want %q", tc.doc, ce.Pointer, tc.wantPointer)
+		}
+	}
+}
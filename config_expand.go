@@ -0,0 +1,314 @@
+package uni
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ConfigExpansion configures ExpandConfigPlaceholders.
+type ConfigExpansion struct {
+	// Namespaces are the placeholder namespaces to expand.
+	// Default: env, file and system. Secrets can not be
+	// expanded, so that they never become part of the config.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// If Strict is true, placeholders in the expanded
+	// namespaces that are unknown or whose value can not be
+	// produced (for example a missing file) are an error.
+	// Otherwise they are left in the config as they are.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// ConfigExpansionReport lists the substitutions made by
+// ExpandConfigPlaceholders. It never contains values.
+type ConfigExpansionReport struct {
+	Substitutions []ConfigSubstitution `json:"substitutions,omitempty"`
+}
+
+// ConfigSubstitution describes the placeholders that were
+// expanded in one string value of the config.
+type ConfigSubstitution struct {
+	// Path is the JSON pointer (RFC 6901) of the value.
+	Path string `json:"path"`
+
+	// Placeholders are the keys of the expanded placeholders.
+	Placeholders []string `json:"placeholders"`
+}
+
+// ExpandConfigJSON runs ExpandConfigPlaceholders on cfgJSON if
+// the config opts in to it with its top-level
+// "expand_placeholders" setting, and returns cfgJSON unchanged
+// otherwise. Config loaders call it before decoding the config.
+func ExpandConfigJSON(cfgJSON []byte) ([]byte, ConfigExpansionReport, error) {
+	var head struct {
+		ExpandPlaceholders *ConfigExpansion `json:"expand_placeholders"`
+	}
+	if err := json.Unmarshal(cfgJSON, &head); err != nil {
+		return nil, ConfigExpansionReport{}, err
+	}
+	if head.ExpandPlaceholders == nil {
+		return cfgJSON, ConfigExpansionReport{}, nil
+	}
+	return ExpandConfigPlaceholders(cfgJSON, *head.ExpandPlaceholders)
+}
+
+// ExpandConfigPlaceholders replaces the placeholders of the
+// given namespaces in all string values (not object keys) of
+// cfgJSON and returns the resulting JSON, in compact form but
+// with the order of object keys preserved. It is meant to be
+// run on the whole config before it is decoded, for configs
+// which opt in to it.
+//
+// Placeholders of other namespaces, like the ones which are
+// only known at runtime, and escaped braces are left as they
+// are, so they can still be replaced later.
+//
+// {file.*} placeholders are subject to the "file_placeholders"
+// policy of the config itself, or to the default policy if it
+// has none, since the config is not provisioned yet.
+func ExpandConfigPlaceholders(cfgJSON []byte, opts ConfigExpansion) ([]byte, ConfigExpansionReport, error) {
+	var report ConfigExpansionReport
+
+	namespaces := opts.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{"env", "file", "system"}
+	}
+	if slices.Contains(namespaces, "secret") {
+		return nil, report, fmt.Errorf("secret placeholders can not be expanded in the config")
+	}
+
+	files := defaultFilePlaceholders
+	if slices.Contains(namespaces, "file") {
+		var head struct {
+			FilePlaceholders *FilePlaceholders `json:"file_placeholders"`
+		}
+		if err := json.Unmarshal(cfgJSON, &head); err != nil {
+			return nil, report, err
+		}
+		if head.FilePlaceholders != nil {
+			if err := head.FilePlaceholders.setup(); err != nil {
+				return nil, report, fmt.Errorf("/file_placeholders: %w", err)
+			}
+			files = head.FilePlaceholders
+		}
+	}
+
+	type frame struct {
+		object    bool
+		expectKey bool
+		n         int
+		key       string
+	}
+	var (
+		stack    []frame
+		out      bytes.Buffer
+		topLevel int
+	)
+	pointer := func() string {
+		var sb strings.Builder
+		for _, f := range stack {
+			sb.WriteByte('/')
+			if f.object {
+				sb.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(f.key))
+			} else {
+				sb.WriteString(strconv.Itoa(f.n - 1))
+			}
+		}
+		return sb.String()
+	}
+	// valueDone records that the current value of the innermost
+	// object, if any, is complete, so a key comes next
+	valueDone := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(cfgJSON))
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, report, err
+		}
+
+		// object keys
+		if len(stack) > 0 && stack[len(stack)-1].object && stack[len(stack)-1].expectKey {
+			top := &stack[len(stack)-1]
+			if tok == json.Delim('}') {
+				stack = stack[:len(stack)-1]
+				out.WriteByte('}')
+				valueDone()
+				continue
+			}
+			if top.n > 0 {
+				out.WriteByte(',')
+			}
+			top.n++
+			top.key = tok.(string)
+			top.expectKey = false
+			writeJSONString(&out, top.key)
+			out.WriteByte(':')
+			continue
+		}
+
+		if tok == json.Delim(']') {
+			stack = stack[:len(stack)-1]
+			out.WriteByte(']')
+			valueDone()
+			continue
+		}
+
+		// values
+		if len(stack) == 0 {
+			if topLevel++; topLevel > 1 {
+				return nil, report, errors.New("unexpected data after top-level value")
+			}
+		} else if top := &stack[len(stack)-1]; !top.object {
+			if top.n > 0 {
+				out.WriteByte(',')
+			}
+			top.n++
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteRune(rune(v))
+			stack = append(stack, frame{object: v == '{', expectKey: v == '{'})
+			continue
+		case string:
+			expanded, keys, err := expandPlaceholders(v, namespaces, opts.Strict, files)
+			if err != nil {
+				return nil, report, fmt.Errorf("%s: %v", pointer(), err)
+			}
+			if len(keys) > 0 {
+				report.Substitutions = append(report.Substitutions, ConfigSubstitution{Path: pointer(), Placeholders: keys})
+			}
+			writeJSONString(&out, expanded)
+		case json.Number:
+			out.WriteString(v.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(v))
+		case nil:
+			out.WriteString("null")
+		}
+		valueDone()
+	}
+
+	return out.Bytes(), report, nil
+}
+
+// expandPlaceholders replaces the placeholders of the given
+// namespaces in s and returns the result along with the keys
+// of the replaced placeholders. Files are read subject to the
+// files policy. Everything else, including escaped braces, is
+// kept verbatim.
+func expandPlaceholders(s string, namespaces []string, strict bool, files *FilePlaceholders) (string, []string, error) {
+	if !strings.Contains(s, string(phOpen)) {
+		return s, nil, nil
+	}
+
+	var (
+		sb              strings.Builder
+		keys            []string
+		lastWriteCursor int
+	)
+
+scan:
+	for i := 0; i < len(s); i++ {
+		if s[i] != phOpen || (i > 0 && s[i-1] == phEscape) {
+			continue
+		}
+
+		// find the first closing brace that is not escaped
+		end := strings.IndexByte(s[i:], phClose) + i
+		if end < i {
+			break
+		}
+		for s[end-1] == phEscape {
+			next := strings.IndexByte(s[end+1:], phClose)
+			if next < 0 {
+				break scan
+			}
+			end += next + 1
+		}
+
+		expr := parsePlaceholder(s[i+1 : end])
+		ns, ok := lookupPlaceholderNamespace(expr.key)
+		if !ok || !slices.Contains(namespaces, ns.Name) {
+			i = end
+			continue
+		}
+
+		provider := ns.Provider
+		if _, isFile := provider.(fileReplacementProvider); isFile {
+			provider = fileReplacementProvider{policy: files}
+		}
+		val, err := evaluateConfigPlaceholder(provider, expr)
+		if err != nil {
+			if strict {
+				return "", nil, err
+			}
+			i = end
+			continue
+		}
+
+		sb.WriteString(s[lastWriteCursor:i])
+		sb.WriteString(val)
+		keys = append(keys, expr.key)
+		i = end
+		lastWriteCursor = end + 1
+	}
+
+	if len(keys) == 0 {
+		return s, nil, nil
+	}
+	sb.WriteString(s[lastWriteCursor:])
+	return sb.String(), keys, nil
+}
+
+// evaluateConfigPlaceholder evaluates a placeholder with the
+// provider of its namespace, including its default value and
+// transforms.
+func evaluateConfigPlaceholder(provider PlaceholderProvider, expr placeholderExpr) (string, error) {
+	val, found := provider.Replace(expr.key)
+	if perr, ok := val.(PlaceholderError); ok {
+		if !expr.hasDefault {
+			return "", fmt.Errorf("evaluating placeholder %s%s%s: %w",
+				string(phOpen), expr.key, string(phClose), perr.Err)
+		}
+		val = nil
+	}
+	if expr.hasDefault && (!found || ToString(val) == "") {
+		val, found = expr.def, true
+	}
+	if !found {
+		return "", fmt.Errorf("unrecognized placeholder %s%s%s",
+			string(phOpen), expr.key, string(phClose))
+	}
+
+	out, err := applyTransforms(ToString(val), expr.transforms)
+	if err != nil {
+		return "", fmt.Errorf("evaluating placeholder %s%s%s: %v",
+			string(phOpen), expr.key, string(phClose), err)
+	}
+	return out, nil
+}
+
+// writeJSONString writes s to buf as a JSON string,
+// without escaping HTML characters.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // trailing newline
+}
@@ -0,0 +1,191 @@
+package uni
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandConfigPlaceholders(t *testing.T) {
+	t.Setenv("UNI_TEST_PORT", "8080")
+	name := filepath.Join(t.TempDir(), "host")
+	writeFile(t, name, "example.com\n")
+
+	tests := []struct {
+		name    string
+		opts    ConfigExpansion
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			input: `{"b":"{env.UNI_TEST_PORT}","a":[1,true,null,"x{env.UNI_TEST_PORT}"]}`,
+			want:  `{"b":"8080","a":[1,true,null,"x8080"]}`,
+		},
+		{
+			input: `{"host":"{file.` + name + `}","url":"<{http.request.uri}>"}`,
+			want:  `{"host":"example.com","url":"<{http.request.uri}>"}`,
+		},
+		{
+			name:  "escaped braces and keys are kept",
+			input: `{"{env.UNI_TEST_PORT}":"\\{env.UNI_TEST_PORT\\}"}`,
+			want:  `{"{env.UNI_TEST_PORT}":"\\{env.UNI_TEST_PORT\\}"}`,
+		},
+		{
+			name:  "defaults and transforms",
+			input: `{"a":"{env.UNI_TEST_UNSET:fallback|upper}"}`,
+			want:  `{"a":"FALLBACK"}`,
+		},
+		{
+			name:  "unknown placeholders are kept",
+			input: `{"a":"{system.nope}","b":"{file.` + name + `.missing}"}`,
+			want:  `{"a":"{system.nope}","b":"{file.` + name + `.missing}"}`,
+		},
+		{
+			opts:    ConfigExpansion{Strict: true},
+			input:   `{"a":"{system.nope}"}`,
+			wantErr: true,
+		},
+		{
+			opts:    ConfigExpansion{Strict: true},
+			input:   `{"a":"{file.` + name + `.missing}"}`,
+			wantErr: true,
+		},
+		{
+			name:  "only the given namespaces",
+			opts:  ConfigExpansion{Namespaces: []string{"file"}},
+			input: `{"a":"{env.UNI_TEST_PORT}"}`,
+			want:  `{"a":"{env.UNI_TEST_PORT}"}`,
+		},
+		{
+			opts:    ConfigExpansion{Namespaces: []string{"secret"}},
+			input:   `{}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"a":1} {}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+tt.input, func(t *testing.T) {
+			got, _, err := ExpandConfigPlaceholders([]byte(tt.input), tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %s", got)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("ExpandConfigPlaceholders() = %s, %v; want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandConfigPlaceholdersReport(t *testing.T) {
+	t.Setenv("UNI_TEST_A", "a")
+
+	_, report, err := ExpandConfigPlaceholders([]byte(`{
+		"apps": {"x/y": [{"k": "{env.UNI_TEST_A}-{env.UNI_TEST_B}"}, "plain"]},
+		"other": "{env.UNI_TEST_A}"
+	}`), ConfigExpansion{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ConfigSubstitution{
+		{Path: "/apps/x~1y/0/k", Placeholders: []string{"env.UNI_TEST_A", "env.UNI_TEST_B"}},
+		{Path: "/other", Placeholders: []string{"env.UNI_TEST_A"}},
+	}
+	if !slices.EqualFunc(report.Substitutions, want, func(a, b ConfigSubstitution) bool {
+		return a.Path == b.Path && slices.Equal(a.Placeholders, b.Placeholders)
+	}) {
+		t.Errorf("report = %+v, want %+v", report.Substitutions, want)
+	}
+}
+
+func TestExpandConfigJSON(t *testing.T) {
+	t.Setenv("UNI_TEST_PORT", "8080")
+
+	input := `{"port":"{env.UNI_TEST_PORT}"}`
+	got, _, err := ExpandConfigJSON([]byte(input))
+	if err != nil || string(got) != input {
+		t.Errorf("without opt-in: ExpandConfigJSON() = %s, %v", got, err)
+	}
+
+	input = `{"expand_placeholders":{"strict":true},"port":"{env.UNI_TEST_PORT}"}`
+	got, _, err = ExpandConfigJSON([]byte(input))
+	if want := `{"expand_placeholders":{"strict":true},"port":"8080"}`; err != nil || string(got) != want {
+		t.Errorf("with opt-in: ExpandConfigJSON() = %s, %v; want %s", got, err, want)
+	}
+}
+
+func TestExpandConfigFilePolicy(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret")
+	writeFile(t, outside, "outside")
+	root := t.TempDir()
+	inside := filepath.Join(root, "name")
+	writeFile(t, inside, "uni")
+
+	// the policy of a running config does not apply to the
+	// config being loaded
+	running := &FilePlaceholders{Roots: []string{root}}
+	if err := running.Provision(Context{}); err != nil {
+		t.Fatal(err)
+	}
+	defer running.Cleanup()
+	got, _, err := ExpandConfigJSON([]byte(`{"expand_placeholders":{"strict":true},"v":"{file.` + outside + `}"}`))
+	if err != nil || !strings.Contains(string(got), `"v":"outside"`) {
+		t.Errorf("without file_placeholders: ExpandConfigJSON() = %s, %v", got, err)
+	}
+
+	cfgJSON := func(file string) []byte {
+		return []byte(`{
+			"expand_placeholders": {"strict": true},
+			"file_placeholders": {"roots": ["` + root + `"]},
+			"v": "{file.` + file + `}"
+		}`)
+	}
+	running.Cleanup()
+	if got, _, err := ExpandConfigJSON(cfgJSON(inside)); err != nil || !strings.Contains(string(got), `"v":"uni"`) {
+		t.Errorf("file within root: ExpandConfigJSON() = %s, %v", got, err)
+	}
+	if got, _, err := ExpandConfigJSON(cfgJSON(outside)); err == nil {
+		t.Errorf("file outside the roots: ExpandConfigJSON() = %s, want error", got)
+	}
+	if ctx, err := Load(cfgJSON(outside)); err == nil {
+		ctx.Close()
+		t.Error("Load() expanded a file outside the roots of the config")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("UNI_TEST_ADMIN", "localhost:0")
+
+	ctx, err := Load([]byte(`{
+		"admin": {"listen": "{env.UNI_TEST_ADMIN}"},
+		"expand_placeholders": {"strict": true}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Close()
+	if got := ctx.cfg.Admin.Listen; got != "localhost:0" {
+		t.Errorf("admin listen = %q, want expanded placeholder", got)
+	}
+
+	// without opting in, placeholders are left as they are
+	if _, err := Load([]byte(`{"admin": {"listen": "{env.UNI_TEST_ADMIN}"}}`)); err == nil {
+		t.Error("expected error listening on an unexpanded placeholder")
+	}
+
+	_, err = Load([]byte(`{"admin": {"port": 2019}}`))
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Pointer != "/admin/port" {
+		t.Errorf("got error %v, want config error at /admin/port", err)
+	}
+}
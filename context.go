@@ -1,14 +1,23 @@
 package uni
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/exp/zapslog"
 )
 
 // Context is a type which defines the lifetime of modules that
@@ -27,9 +36,32 @@ type Context struct {
 	moduleInstances map[string][]Module
 	cfg             *Config
 	ancestry        []Module
-	cleanupFuncs    []func()                // invoked at every config unload
 	exitFuncs       []func(context.Context) // invoked at config unload ONLY IF the process is exiting (EXPERIMENTAL)
 	metricsRegistry *prometheus.Registry
+
+	// configPointer is the JSON pointer of the config value
+	// being provisioned, used to locate ConfigErrors
+	configPointer string
+
+	// registry is where modules are loaded from; nil
+	// means the default registry
+	registry *Registry
+
+	// instance is the module instance being provisioned,
+	// which is the parent of the modules loaded with ctx;
+	// instances are the ones loaded with this context or
+	// copies of it, to clean up when it is canceled
+	instance  *instanceNode
+	instances *[]*instanceNode
+
+	// closer closes the context; it is nil if the
+	// context was not made by NewContext
+	closer *contextCloser
+
+	// moduleType, if set, is the type the modules loaded
+	// with ctx must have (see LoadModuleAs), which is
+	// checked before they are provisioned
+	moduleType reflect.Type
 }
 
 // NewContext provides a new context derived from the given
@@ -37,40 +69,86 @@ type Context struct {
 // function unless you are loading modules which have a
 // different lifespan than the ones for the context the
 // module was provisioned with. Be sure to call the cancel
-// func when the context is to be cleaned up so that
-// modules which are loaded will be properly unloaded.
+// func, or Close, when the context is to be cleaned up so
+// that modules which are loaded will be properly unloaded.
 // See standard library context package's documentation.
 func NewContext(ctx Context) (Context, context.CancelFunc) {
 	newCtx := Context{
 		moduleInstances: make(map[string][]Module),
 		cfg:             ctx.cfg,
 		metricsRegistry: prometheus.NewPedanticRegistry(),
+		registry:        ctx.registry,
+		instance:        ctx.instance,
+		instances:       new([]*instanceNode),
 	}
 
 	c, cancel := context.WithCancel(ctx.Context)
 
-	wrappedCancel := func() {
-		cancel()
+	newCtx.Context = c
+	newCtx.closer = &contextCloser{cancel: cancel, instances: newCtx.instances}
+	newCtx.initMetrics()
+	return newCtx, func() { _ = newCtx.Close() }
+}
 
-		for _, f := range ctx.cleanupFuncs {
-			f()
-		}
+// Close cancels ctx, waits for the functions registered with
+// OnCancel to return, and then cleans up the modules loaded
+// with ctx in the reverse order of loading them, so that
+// modules are cleaned up before the modules that loaded them.
+// Each module's Cleanup is given the CleanupTimeout of its
+// ModuleInfo to return. Failures are logged and returned as
+// one joined error. Closing a context more than once returns
+// the same error, and contexts that were not made by
+// NewContext have nothing to close.
+func (ctx Context) Close() error {
+	if ctx.closer == nil {
+		return nil
+	}
+	return ctx.closer.close()
+}
 
-		for modName, modInstances := range newCtx.moduleInstances {
-			for _, inst := range modInstances {
-				if cu, ok := inst.(CleanerUpper); ok {
-					err := cu.Cleanup()
-					if err != nil {
-						log.Printf("[ERROR] %s (%p): cleanup: %v", modName, inst, err)
-					}
-				}
+// contextCloser closes a Context made by NewContext, once.
+type contextCloser struct {
+	cancel    context.CancelFunc
+	instances *[]*instanceNode
+
+	// callbacks counts the OnCancel functions of the context
+	// which have not returned yet; once closing is set, no
+	// more are added (see OnCancel)
+	mu        sync.Mutex
+	closing   bool
+	callbacks sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+func (cc *contextCloser) close() error {
+	cc.once.Do(func() {
+		cc.mu.Lock()
+		cc.closing = true
+		cc.mu.Unlock()
+
+		cc.cancel()
+		cc.callbacks.Wait()
+
+		nodes := slices.Clone(*cc.instances)
+		slices.SortFunc(nodes, func(a, b *instanceNode) int {
+			return cmp.Compare(b.num, a.num)
+		})
+
+		var errs []error
+		for _, node := range nodes {
+			if err := node.cleanup(); err != nil {
+				Log().Error("cleaning up module",
+					zap.String("module", node.id),
+					zap.Uint64("instance", node.num),
+					zap.Error(err))
+				errs = append(errs, fmt.Errorf("%s: cleanup: %w", node.id, err))
 			}
 		}
-	}
-
-	newCtx.Context = c
-	newCtx.initMetrics()
-	return newCtx, wrappedCancel
+		cc.err = errors.Join(errs...)
+	})
+	return cc.err
 }
 
 func (ctx *Context) initMetrics() {
@@ -82,6 +160,7 @@ func (ctx *Context) initMetrics() {
 		adminMetrics.requestErrors,
 		globalMetrics.configSuccess,
 		globalMetrics.configSuccessTime,
+		moduleMetrics.provisionDuration,
 	)
 }
 
@@ -92,20 +171,56 @@ func (ctx *Context) WithValue(key, value any) Context {
 		moduleInstances: ctx.moduleInstances,
 		cfg:             ctx.cfg,
 		ancestry:        ctx.ancestry,
-		cleanupFuncs:    ctx.cleanupFuncs,
 		exitFuncs:       ctx.exitFuncs,
+		configPointer:   ctx.configPointer,
+		registry:        ctx.registry,
+		instance:        ctx.instance,
+		instances:       ctx.instances,
+		closer:          ctx.closer,
 	}
 }
 
-// OnCancel executes f when ctx is canceled.
-//
-// # TODO
-//
-// 目前的问题是 Semantic Drift
-//
-// 使用 context.AfterFunc 解决 Caddy 这种“手动维护清理列表”或“开大量协程监听取消信号”的痛点
+// WithRegistry returns a copy of ctx which loads modules from
+// registry instead of the default registry. Contexts derived
+// from it with NewContext use the same registry.
+func (ctx Context) WithRegistry(registry *Registry) Context {
+	ctx.registry = registry
+	return ctx
+}
+
+// Registry returns the registry ctx loads modules from.
+func (ctx Context) Registry() *Registry {
+	if ctx.registry == nil {
+		return defaultRegistry
+	}
+	return ctx.registry
+}
+
+// OnCancel executes f in its own goroutine when ctx is
+// canceled. For contexts made by NewContext, Close waits for
+// f to return before it cleans up the modules of the context;
+// if ctx is being closed already, f is executed right away,
+// before OnCancel returns.
 func (ctx *Context) OnCancel(f func()) {
-	ctx.cleanupFuncs = append(ctx.cleanupFuncs, f)
+	if ctx.closer == nil {
+		context.AfterFunc(ctx.Context, f)
+		return
+	}
+
+	cc := ctx.closer
+	cc.mu.Lock()
+	if cc.closing {
+		cc.mu.Unlock()
+		f()
+		return
+	}
+	cc.callbacks.Add(1)
+	cc.mu.Unlock()
+
+	context.AfterFunc(ctx.Context, func() {
+		defer cc.callbacks.Done()
+		f()
+	})
 }
 
 // OnExit executes f when the process exits gracefully.
@@ -136,6 +251,30 @@ func (ctx Context) Module() Module {
 	return ctx.ancestry[len(ctx.ancestry)-1]
 }
 
+// Logger returns a logger for the current module (see Module),
+// named after its ID and carrying the number of its instance
+// (see ModuleInstance), so that logs can include or exclude its
+// entries by module namespace. Without a current module, it
+// returns the default logger.
+func (ctx Context) Logger() *zap.Logger {
+	mod := ctx.Module()
+	if mod == nil {
+		return Log()
+	}
+	logger := Log().Named(string(mod.UniModule().ID))
+	if ctx.instance != nil && ctx.instance.module == mod {
+		logger = logger.With(zap.Uint64("instance", ctx.instance.num))
+	}
+	return logger
+}
+
+// Slogger returns the equivalent of Logger as a *slog.Logger,
+// for dependencies that log with log/slog.
+func (ctx Context) Slogger() *slog.Logger {
+	logger := ctx.Logger()
+	return slog.New(zapslog.NewHandler(logger.Core(), zapslog.WithName(logger.Name())))
+}
+
 // Modules returns the lineage of modules that this context provisioned,
 // with the most recent/current module being last in the list.
 func (ctx Context) Modules() []Module {
@@ -147,6 +286,82 @@ func (ctx Context) Modules() []Module {
 // ErrNotConfigured indicates a module is not configured.
 var ErrNotConfigured = fmt.Errorf("module not configured")
 
+// App returns the app with the given name, loading it if it is
+// not loaded yet: with its config if it has one, and with its
+// default config otherwise. Modules of an app may get the app
+// while it is still being provisioned, but apps which get each
+// other while being provisioned form a cycle, which is an error.
+// The name may be an alias of the app's ID (see ModuleInfo).
+func (ctx Context) App(name string) (any, error) {
+	if ctx.cfg == nil {
+		return nil, fmt.Errorf("app module %s: %w", name, ErrNotConfigured)
+	}
+	id, key := ctx.appKey(name)
+	if err, ok := ctx.cfg.failedApps[id]; ok {
+		return nil, fmt.Errorf("app module %s failed to provision: %w", id, err)
+	}
+	if i := slices.Index(ctx.cfg.loadingApps, id); i >= 0 && i < len(ctx.cfg.loadingApps)-1 {
+		return nil, appCycleError(ctx.cfg.loadingApps, id)
+	}
+	if app, ok := ctx.cfg.apps[id]; ok {
+		return app, nil
+	}
+
+	ctx.cfg.loadingApps = append(ctx.cfg.loadingApps, id)
+	defer func() { ctx.cfg.loadingApps = ctx.cfg.loadingApps[:len(ctx.cfg.loadingApps)-1] }()
+
+	// the app is loaded from the top of the config, whichever
+	// module asked for it
+	ctx.configPointer = ""
+	appRaw := ctx.cfg.AppsRaw[key]
+	modVal, err := ctx.WithConfigPointer("apps", key).LoadModuleByID(key, appRaw)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s app module: %w", id, err)
+	}
+	if _, ok := modVal.(App); !ok {
+		return nil, fmt.Errorf("module %s is not an app", id)
+	}
+	if appRaw != nil {
+		ctx.cfg.AppsRaw[key] = nil // allow GC to deallocate
+	}
+	return modVal, nil
+}
+
+// AppIfConfigured is like App, but it only returns apps which
+// are configured or loaded already; for other apps, it returns
+// an error wrapping ErrNotConfigured.
+func (ctx Context) AppIfConfigured(name string) (any, error) {
+	if ctx.cfg == nil {
+		return nil, fmt.Errorf("app module %s: %w", name, ErrNotConfigured)
+	}
+	id, key := ctx.appKey(name)
+	if _, ok := ctx.cfg.apps[id]; !ok && ctx.cfg.AppsRaw[key] == nil {
+		return nil, fmt.Errorf("app module %s: %w", name, ErrNotConfigured)
+	}
+	return ctx.App(name)
+}
+
+// appKey returns the ID of the app with the given name or
+// alias, which loaded apps are known by, and the key of its
+// config in AppsRaw: the ID, or an alias if the config uses
+// that instead. Names of unknown modules are returned as is.
+func (ctx Context) appKey(name string) (id, key string) {
+	info, _, ok := ctx.Registry().lookup(name)
+	if !ok {
+		return name, name
+	}
+	id = string(info.ID)
+	if _, ok := ctx.cfg.AppsRaw[id]; ok {
+		return id, id
+	}
+	for _, alias := range info.Aliases {
+		if _, ok := ctx.cfg.AppsRaw[string(alias)]; ok {
+			return id, string(alias)
+		}
+	}
+	return id, id
+}
+
 // LoadModule loads the Caddy module(s) from the specified field of the parent struct
 // pointer and returns the loaded module(s). The struct pointer and its field name as
 // a string are necessary so that reflection can be used to read the struct tag on the
@@ -163,17 +378,19 @@ var ErrNotConfigured = fmt.Errorf("module not configured")
 //	map[string]json.RawMessage   => map[string]any
 //	[]map[string]json.RawMessage => []map[string]any
 //
-// The field must have a "caddy" struct tag in this format:
+// The field must have a "uni" struct tag in this format:
+//
+//	uni:"key1=val1 key2=val2"
 //
-//	caddy:"key1=val1 key2=val2"
+// The older "caddy" struct tag is accepted as an alias if there is no "uni" tag.
 //
 // To load modules, a "namespace" key is required. For example, to load modules
 // in the "http.handlers" namespace, you'd put: `namespace=http.handlers` in the
-// Caddy struct tag.
+// uni struct tag.
 //
 // The module name must also be available. If the field type is a map or slice of maps,
 // then key is assumed to be the module name if an "inline_key" is NOT specified in the
-// caddy struct tag. In this case, the module name does NOT need to be specified in-line
+// uni struct tag. In this case, the module name does NOT need to be specified in-line
 // with the module itself.
 //
 // If not a map, or if inline_key is non-empty, then the module name must be embedded
@@ -182,19 +399,23 @@ var ErrNotConfigured = fmt.Errorf("module not configured")
 // meaning the key containing the module's name that is defined inline with the module
 // itself. You must specify the inline key in a struct tag, along with the namespace:
 //
-//	caddy:"namespace=http.handlers inline_key=handler"
+//	uni:"namespace=http.handlers inline_key=handler"
 //
 // This will look for a key/value pair like `"handler": "..."` in the json.RawMessage
 // in order to know the module name.
 //
 // To make use of the loaded module(s) (the return value), you will probably want
 // to type-assert each 'any' value(s) to the types that are useful to you
-// and store them on the same struct. Storing them on the same struct makes for
+// (LoadModuleAs, LoadModulesAs and LoadModuleMapAs do this with a helpful
+// error instead of a panic) and store them on the same struct. Storing them on the same struct makes for
 // easy garbage collection when your host module is no longer needed.
 //
 // Loaded modules have already been provisioned and validated. Upon returning
 // successfully, this method clears the json.RawMessage(s) in the field since
 // the raw JSON is no longer needed, and this allows the GC to free up memory.
+//
+// Errors in the config of the modules are returned with a *ConfigError whose
+// pointer names the JSON field of structFieldName, below the pointer of ctx.
 func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, error) {
 	val := reflect.ValueOf(structPointer).Elem().FieldByName(structFieldName)
 	typ := val.Type()
@@ -204,7 +425,8 @@ func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, e
 		panic(fmt.Sprintf("field %s does not exist in %#v", structFieldName, structPointer))
 	}
 
-	opts, err := ParseStructTag(field.Tag.Get("caddy"))
+	tag, _ := moduleTag(field)
+	opts, err := ParseStructTag(tag)
 	if err != nil {
 		panic(fmt.Sprintf("malformed tag on field %s: %v", structFieldName, err))
 	}
@@ -215,6 +437,9 @@ func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, e
 	}
 	inlineModuleKey := opts["inline_key"]
 
+	// errors are located by the JSON pointer of the field
+	ctx = ctx.WithConfigPointer(jsonFieldName(field))
+
 	var result any
 
 	switch val.Kind() {
@@ -234,9 +459,9 @@ func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, e
 			}
 			var all []any
 			for i := 0; i < val.Len(); i++ {
-				val, err := ctx.loadModuleInline(inlineModuleKey, moduleNamespace, val.Index(i).Interface().(json.RawMessage))
+				val, err := ctx.WithConfigPointer(strconv.Itoa(i)).loadModuleInline(inlineModuleKey, moduleNamespace, val.Index(i).Interface().(json.RawMessage))
 				if err != nil {
-					return nil, fmt.Errorf("position %d: %v", i, err)
+					return nil, err
 				}
 				all = append(all, val)
 			}
@@ -252,9 +477,9 @@ func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, e
 				innerVal := val.Index(i)
 				var allInner []any
 				for j := 0; j < innerVal.Len(); j++ {
-					innerInnerVal, err := ctx.loadModuleInline(inlineModuleKey, moduleNamespace, innerVal.Index(j).Interface().(json.RawMessage))
+					innerInnerVal, err := ctx.WithConfigPointer(strconv.Itoa(i), strconv.Itoa(j)).loadModuleInline(inlineModuleKey, moduleNamespace, innerVal.Index(j).Interface().(json.RawMessage))
 					if err != nil {
-						return nil, fmt.Errorf("position %d: %v", j, err)
+						return nil, err
 					}
 					allInner = append(allInner, innerInnerVal)
 				}
@@ -266,7 +491,7 @@ func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, e
 
 			var all []map[string]any
 			for i := 0; i < val.Len(); i++ {
-				thisSet, err := ctx.loadModulesFromSomeMap(moduleNamespace, inlineModuleKey, val.Index(i))
+				thisSet, err := ctx.WithConfigPointer(strconv.Itoa(i)).loadModulesFromSomeMap(moduleNamespace, inlineModuleKey, val.Index(i))
 				if err != nil {
 					return nil, err
 				}
@@ -292,6 +517,79 @@ func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, e
 	return result, nil
 }
 
+// LoadModuleAs is like ctx.LoadModule for a field holding a
+// single module (json.RawMessage), but also checks that the
+// loaded module is a T, which is usually the interface the
+// host module requires. If it is not, the error names the
+// module and T, rather than a failed type assertion panicking.
+// The check is done before the module is provisioned, so a
+// module of the wrong type holds no resources.
+func LoadModuleAs[T any](ctx Context, structPointer any, structFieldName string) (T, error) {
+	var zero T
+	ctx.moduleType = reflect.TypeFor[T]()
+	val, err := ctx.LoadModule(structPointer, structFieldName)
+	if err != nil {
+		return zero, err
+	}
+	return moduleAs[T](val)
+}
+
+// LoadModulesAs is like LoadModuleAs for a field holding a
+// list of modules ([]json.RawMessage).
+func LoadModulesAs[T any](ctx Context, structPointer any, structFieldName string) ([]T, error) {
+	ctx.moduleType = reflect.TypeFor[T]()
+	vals, err := ctx.LoadModule(structPointer, structFieldName)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := vals.([]any)
+	if !ok {
+		return nil, fmt.Errorf("field %s does not hold a list of modules", structFieldName)
+	}
+	mods := make([]T, len(list))
+	for i, val := range list {
+		if mods[i], err = moduleAs[T](val); err != nil {
+			return nil, fmt.Errorf("position %d: %v", i, err)
+		}
+	}
+	return mods, nil
+}
+
+// LoadModuleMapAs is like LoadModuleAs for a field holding a
+// map of modules (map[string]json.RawMessage or ModuleMap).
+func LoadModuleMapAs[T any](ctx Context, structPointer any, structFieldName string) (map[string]T, error) {
+	ctx.moduleType = reflect.TypeFor[T]()
+	vals, err := ctx.LoadModule(structPointer, structFieldName)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := vals.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("field %s does not hold a map of modules", structFieldName)
+	}
+	mods := make(map[string]T, len(m))
+	for key, val := range m {
+		if mods[key], err = moduleAs[T](val); err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return mods, nil
+}
+
+// moduleAs returns val as a T, or an error naming the module
+// and T if val is not a T.
+func moduleAs[T any](val any) (T, error) {
+	mod, ok := val.(T)
+	if !ok {
+		id := "unknown"
+		if m, ok := val.(Module); ok {
+			id = string(m.UniModule().ID)
+		}
+		return mod, fmt.Errorf("module %s (%T) does not implement %s", id, val, reflect.TypeFor[T]())
+	}
+	return mod, nil
+}
+
 // LoadModuleByID decodes rawMsg into a new instance of mod and
 // returns the value. If mod.New is nil, an error is returned.
 // If the module implements Validator or Provisioner interfaces,
@@ -302,12 +600,31 @@ func (ctx Context) LoadModule(structPointer any, structFieldName string) (any, e
 // directly by most modules. However, this method is useful when
 // dynamically loading/unloading modules in their own context,
 // like from embedded scripts, etc.
+//
+// The id may also be an alias of the module, in which case the
+// module is loaded as usual, and a warning is logged the first
+// time the alias is used. The same goes for deprecated modules.
+// If the module has a Migrate function, it is given rawMsg first.
+//
+// Errors carry a *ConfigError located at the JSON pointer of
+// ctx (see WithConfigPointer), unless a module loaded while
+// provisioning this one already returned a more precise one.
 func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error) {
-	modulesMu.RLock()
-	modInfo, ok := modules[id]
-	modulesMu.RUnlock()
+	modInfo, isAlias, ok := ctx.Registry().lookup(id)
 	if !ok {
-		return nil, fmt.Errorf("unknown module: %s", id)
+		return nil, &ConfigError{Pointer: ctx.configPointer, Module: id, Err: errors.New("unknown module")}
+	}
+
+	// from here on, the module goes by its actual ID
+	loadedAs := ModuleID(id)
+	id = string(modInfo.ID)
+	if isAlias {
+		warnDeprecated(string(loadedAs), "module ID is deprecated", zap.String("module", id))
+	}
+	if modInfo.Deprecated != nil {
+		warnDeprecated(id, "module is deprecated",
+			zap.String("removed_in", modInfo.Deprecated.RemovedIn),
+			zap.String("message", modInfo.Deprecated.Message))
 	}
 
 	if modInfo.New == nil {
@@ -341,11 +658,38 @@ func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error
 		val = reflect.New(rv.Type()).Elem().Addr().Interface().(Module)
 	}
 
+	// check the type the caller requires before the module
+	// holds any resources; its own submodules may be of any type
+	if want := ctx.moduleType; want != nil {
+		if !reflect.TypeOf(val).AssignableTo(want) {
+			return nil, &ConfigError{Pointer: ctx.configPointer, Module: id, Err: fmt.Errorf("%T does not implement %s", val, want)}
+		}
+		ctx.moduleType = nil
+	}
+
 	// fill in its config only if there is a config to fill in
+	if len(rawMsg) > 0 && modInfo.Migrate != nil {
+		migrated, err := modInfo.Migrate(loadedAs, rawMsg)
+		if err != nil {
+			return nil, ctx.configError(id, "migrating module config", err)
+		}
+		rawMsg = migrated
+	}
 	if len(rawMsg) > 0 {
 		err := StrictUnmarshalJSON(rawMsg, &val)
 		if err != nil {
-			return nil, fmt.Errorf("decoding module config: %s: %v", modInfo, err)
+			var ce *ConfigError
+			if !errors.As(err, &ce) {
+				return nil, ctx.configError(id, "decoding module config", err)
+			}
+			// the raw message is relative to this module, and it
+			// may have been re-encoded to remove the inline key,
+			// so only the pointer can locate the error
+			ce.Pointer = ctx.configPointer + ce.Pointer
+			ce.Module = id
+			ce.Offset = 0
+			ce.Err = fmt.Errorf("decoding module config: %w", ce.Err)
+			return nil, ce
 		}
 	}
 
@@ -355,7 +699,7 @@ func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error
 		// is no good reason to explicitly declare null modules in
 		// a config; it might be because the user is trying to achieve
 		// a result the developer isn't expecting, which is a smell
-		return nil, fmt.Errorf("module value cannot be null")
+		return nil, &ConfigError{Pointer: ctx.configPointer, Module: id, Err: errors.New("module value cannot be null")}
 	}
 
 	var err error
@@ -376,10 +720,25 @@ func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error
 		}()
 	}
 
+	// the instance is the parent of the modules it loads
+	ctx.instance = newInstanceNode(ctx.instance, id, ctx.configPointer)
+	ctx.instance.module = val
+	ctx.instance.cleanupTimeout = modInfo.CleanupTimeout
+	if ctx.instance.cleanupTimeout == 0 {
+		ctx.instance.cleanupTimeout = DefaultCleanupTimeout
+	}
+	timing := provisionTiming{start: time.Now()}
+	defer func(node *instanceNode) {
+		timing.children = node.provisioned(time.Since(timing.start), err)
+		traceProvision(node, timing, err)
+	}(ctx.instance)
+
 	ctx.ancestry = append(ctx.ancestry, val)
 
 	if prov, ok := val.(Provisioner); ok {
+		start := time.Now()
 		err = prov.Provision(ctx)
+		timing.provision = time.Since(start)
 		if err != nil {
 			// incomplete provisioning could have left state
 			// dangling, so make sure it gets cleaned up
@@ -389,12 +748,14 @@ func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error
 					err = fmt.Errorf("%v; additionally, cleanup: %v", err, err2)
 				}
 			}
-			return nil, fmt.Errorf("provision %s: %v", modInfo, err)
+			return nil, ctx.configError(id, "provision", err)
 		}
 	}
 
 	if validator, ok := val.(Validator); ok {
+		start := time.Now()
 		err = validator.Validate()
+		timing.validate = time.Since(start)
 		if err != nil {
 			// since the module was already provisioned, make sure we clean up
 			if cleanerUpper, ok := val.(CleanerUpper); ok {
@@ -403,11 +764,12 @@ func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error
 					err = fmt.Errorf("%v; additionally, cleanup: %v", err, err2)
 				}
 			}
-			return nil, fmt.Errorf("%s: invalid configuration: %v", modInfo, err)
+			return nil, ctx.configError(id, "invalid configuration", err)
 		}
 	}
 
 	ctx.moduleInstances[id] = append(ctx.moduleInstances[id], val)
+	*ctx.instances = append(*ctx.instances, ctx.instance)
 
 	// if the loaded module happens to be an app that can emit events, store it so the
 	// core can have access to emit events without an import cycle
@@ -420,6 +782,20 @@ func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error
 	return val, nil
 }
 
+// warnedDeprecations holds the module IDs and aliases whose
+// deprecation has been logged already.
+var warnedDeprecations sync.Map
+
+// warnDeprecated logs msg about the deprecated module ID or alias
+// id, unless it was logged before; a deprecated module is often
+// loaded many times, but once is enough to notice.
+func warnDeprecated(id, msg string, fields ...zap.Field) {
+	if _, warned := warnedDeprecations.LoadOrStore(id, struct{}{}); warned {
+		return
+	}
+	Log().Warn(msg, append([]zap.Field{zap.String("id", id)}, fields...)...)
+}
+
 // loadModuleInline loads a module from a JSON raw message which decodes to
 // a map[string]any, where one of the object keys is moduleNameKey
 // and the corresponding value is the module name (as a string) which can
@@ -434,15 +810,22 @@ func (ctx Context) LoadModuleByID(id string, rawMsg json.RawMessage) (any, error
 func (ctx Context) loadModuleInline(moduleInlineKey, moduleNamespace string, raw json.RawMessage) (any, error) {
 	moduleName, raw, err := getModuleNameInline(moduleInlineKey, raw)
 	if err != nil {
-		return nil, err
+		return nil, &ConfigError{Pointer: ctx.configPointer, Err: err}
 	}
 
-	val, err := ctx.LoadModuleByID(moduleNamespace+"."+moduleName, raw)
-	if err != nil {
-		return nil, fmt.Errorf("loading module '%s': %v", moduleName, err)
-	}
+	return ctx.LoadModuleByID(moduleNamespace+"."+moduleName, raw)
+}
 
-	return val, nil
+// configError returns err, which occurred while loading the
+// module id during op, as a ConfigError at the value that
+// ctx loads. If err already carries a ConfigError, that one
+// is more precise, so err is only wrapped.
+func (ctx Context) configError(id, op string, err error) error {
+	var ce *ConfigError
+	if errors.As(err, &ce) {
+		return fmt.Errorf("%s: %s: %w", id, op, err)
+	}
+	return &ConfigError{Pointer: ctx.configPointer, Module: id, Err: fmt.Errorf("%s: %w", op, err)}
 }
 
 // loadModulesFromSomeMap loads modules from val, which must be a type of map[string]any.
@@ -472,9 +855,9 @@ func (ctx Context) loadModulesFromRegularMap(namespace, inlineModuleKey string,
 	for iter.Next() {
 		k := iter.Key()
 		v := iter.Value()
-		mod, err := ctx.loadModuleInline(inlineModuleKey, namespace, v.Interface().(json.RawMessage))
+		mod, err := ctx.WithConfigPointer(k.String()).loadModuleInline(inlineModuleKey, namespace, v.Interface().(json.RawMessage))
 		if err != nil {
-			return nil, fmt.Errorf("key %s: %v", k, err)
+			return nil, err
 		}
 		mods[k.String()] = mod
 	}
@@ -493,9 +876,9 @@ func (ctx Context) loadModuleMap(namespace string, val reflect.Value) (map[strin
 		if namespace == "" {
 			moduleName = k
 		}
-		val, err := ctx.LoadModuleByID(moduleName, v)
+		val, err := ctx.WithConfigPointer(k).LoadModuleByID(moduleName, v)
 		if err != nil {
-			return nil, fmt.Errorf("module name '%s': %v", k, err)
+			return nil, err
 		}
 		all[k] = val
 	}
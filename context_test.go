@@ -1,8 +1,16 @@
 package uni
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
 )
 
 func ExampleContext_LoadModule() {
@@ -12,7 +20,7 @@ func ExampleContext_LoadModule() {
 	var ctx Context
 	myStruct := &struct {
 		// This godoc comment will appear in module documentation.
-		GuestModuleRaw json.RawMessage `json:"guest_module,omitempty" caddy:"namespace=example inline_key=name"`
+		GuestModuleRaw json.RawMessage `json:"guest_module,omitempty" uni:"namespace=example inline_key=name"`
 
 		// this is where the decoded module will be stored; in this
 		// example, we pretend we need an io.Writer but it can be
@@ -37,3 +45,381 @@ func ExampleContext_LoadModule() {
 	// use myStruct.guestModule from now on
 
 }
+
+type testWriterModule struct{}
+
+func (testWriterModule) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "example.test_writer", New: func() Module { return new(testWriterModule) }}
+}
+
+func (testWriterModule) Write(p []byte) (int, error) { return len(p), nil }
+
+type testPlainModule struct{}
+
+func (testPlainModule) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "example.test_plain", New: func() Module { return new(testPlainModule) }}
+}
+
+func TestLoadModuleAs(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	r.RegisterModule(testWriterModule{})
+	r.RegisterModule(testPlainModule{})
+
+	ctx, cancel := NewContext(Context{Context: context.Background()}.WithRegistry(r))
+	defer cancel()
+
+	type host struct {
+		One  json.RawMessage            `caddy:"namespace=example inline_key=name"` // alias of the uni tag
+		List []json.RawMessage          `uni:"namespace=example inline_key=name"`
+		Map  map[string]json.RawMessage `uni:"namespace=example"`
+	}
+
+	h := &host{
+		One:  json.RawMessage(`{"name":"test_writer"}`),
+		List: []json.RawMessage{json.RawMessage(`{"name":"test_writer"}`)},
+		Map:  map[string]json.RawMessage{"test_writer": json.RawMessage(`{}`)},
+	}
+	if w, err := LoadModuleAs[io.Writer](ctx, h, "One"); err != nil || w == nil {
+		t.Errorf("LoadModuleAs() = %v, %v", w, err)
+	}
+	if ws, err := LoadModulesAs[io.Writer](ctx, h, "List"); err != nil || len(ws) != 1 {
+		t.Errorf("LoadModulesAs() = %v, %v", ws, err)
+	}
+	if ws, err := LoadModuleMapAs[io.Writer](ctx, h, "Map"); err != nil || ws["test_writer"] == nil {
+		t.Errorf("LoadModuleMapAs() = %v, %v", ws, err)
+	}
+
+	h = &host{
+		One:  json.RawMessage(`{"name":"test_plain"}`),
+		List: []json.RawMessage{json.RawMessage(`{"name":"test_writer"}`), json.RawMessage(`{"name":"test_plain"}`)},
+		Map:  map[string]json.RawMessage{"test_plain": json.RawMessage(`{}`)},
+	}
+	wantErr := "example.test_plain: *uni.testPlainModule does not implement io.Writer"
+	if _, err := LoadModuleAs[io.Writer](ctx, h, "One"); err == nil || err.Error() != "/One: "+wantErr {
+		t.Errorf("LoadModuleAs() error = %v, want %q", err, "/One: "+wantErr)
+	}
+	if _, err := LoadModulesAs[io.Writer](ctx, h, "List"); err == nil || err.Error() != "/List/1: "+wantErr {
+		t.Errorf("LoadModulesAs() error = %v", err)
+	}
+	if _, err := LoadModuleMapAs[io.Writer](ctx, h, "Map"); err == nil || err.Error() != "/Map/test_plain: "+wantErr {
+		t.Errorf("LoadModuleMapAs() error = %v", err)
+	}
+
+	// modules of the wrong type are rejected before they are
+	// provisioned, so they are not left holding resources
+	for _, node := range *ctx.instances {
+		if node.id == "example.test_plain" {
+			t.Errorf("module %s was loaded at %s", node.id, node.pointer)
+		}
+	}
+}
+
+type testRenamedModule struct {
+	Path string `json:"path"`
+}
+
+func (testRenamedModule) UniModule() ModuleInfo {
+	return ModuleInfo{
+		ID:         "example.test_renamed",
+		Aliases:    []ModuleID{"example.test_old"},
+		Deprecated: &ModuleDeprecation{Message: "use example.test_writer", RemovedIn: "v2.0.0"},
+		Migrate: func(id ModuleID, raw json.RawMessage) (json.RawMessage, error) {
+			if id != "example.test_old" {
+				return raw, nil
+			}
+			// the old module called the path "file"
+			return json.RawMessage(strings.Replace(string(raw), `"file"`, `"path"`, 1)), nil
+		},
+		New: func() Module { return new(testRenamedModule) },
+	}
+}
+
+func TestLoadModuleByIDAlias(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	r.RegisterModule(testRenamedModule{})
+
+	ctx, cancel := NewContext(Context{Context: context.Background()}.WithRegistry(r))
+	defer cancel()
+
+	for _, tc := range []struct {
+		id, config string
+	}{
+		{"example.test_old", `{"file": "a.log"}`},
+		{"example.test_old", `{"file": "a.log"}`},
+		{"example.test_renamed", `{"path": "a.log"}`},
+	} {
+		val, err := ctx.LoadModuleByID(tc.id, json.RawMessage(tc.config))
+		if err != nil {
+			t.Fatalf("LoadModuleByID(%q) error = %v", tc.id, err)
+		}
+		if mod, ok := val.(*testRenamedModule); !ok || mod.Path != "a.log" {
+			t.Errorf("LoadModuleByID(%q) = %#v", tc.id, val)
+		}
+	}
+	if mods := ctx.moduleInstances["example.test_renamed"]; len(mods) != 3 {
+		t.Errorf("got %d instances of the module, want 3", len(mods))
+	}
+	for _, id := range []string{"example.test_old", "example.test_renamed"} {
+		if _, warned := warnedDeprecations.Load(id); !warned {
+			t.Errorf("no deprecation warning for %s", id)
+		}
+	}
+
+	if mi, err := r.GetModule("example.test_old"); err != nil || mi.ID != "example.test_renamed" {
+		t.Errorf("GetModule() by alias = %v, %v", mi, err)
+	}
+	if slices.Contains(r.Modules(), "example.test_old") {
+		t.Error("Modules() lists the alias")
+	}
+}
+
+func TestContextRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	r.RegisterModule(testWriterModule{})
+
+	ctx, cancel := NewContext(Context{Context: context.Background()}.WithRegistry(r))
+	defer cancel()
+	child, cancelChild := NewContext(ctx)
+	defer cancelChild()
+
+	if child.Registry() != r {
+		t.Error("derived context does not use the registry of its parent")
+	}
+	if _, err := child.LoadModuleByID("example.test_writer", nil); err != nil {
+		t.Errorf("loading module from the bound registry: %v", err)
+	}
+
+	def, cancelDef := NewContext(Context{Context: context.Background()})
+	defer cancelDef()
+	if def.Registry() != DefaultRegistry() {
+		t.Error("unbound context does not use the default registry")
+	}
+	if _, err := def.LoadModuleByID("example.test_writer", nil); err == nil {
+		t.Error("module of another registry was loaded from the default registry")
+	}
+}
+
+// closeTestModule records its cleanup in log; it loads a child
+// module if configured to.
+type closeTestModule struct {
+	ChildRaw json.RawMessage `json:"child,omitempty" uni:"namespace=close_test inline_key=name"`
+
+	info ModuleInfo
+	log  *[]string
+	fail bool
+	hang chan struct{}
+}
+
+func (m closeTestModule) UniModule() ModuleInfo {
+	info := m.info
+	info.New = func() Module { return &closeTestModule{info: m.info, log: m.log, fail: m.fail, hang: m.hang} }
+	return info
+}
+
+func (m *closeTestModule) Provision(ctx Context) error {
+	if m.ChildRaw != nil {
+		_, err := ctx.LoadModule(m, "ChildRaw")
+		return err
+	}
+	return nil
+}
+
+func (m *closeTestModule) Cleanup() error {
+	if m.hang != nil {
+		<-m.hang
+	}
+	*m.log = append(*m.log, string(m.info.ID))
+	if m.fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func TestContextClose(t *testing.T) {
+	t.Parallel()
+
+	var log []string
+	hang := make(chan struct{})
+	defer close(hang)
+
+	r := NewRegistry()
+	r.RegisterModule(closeTestModule{info: ModuleInfo{ID: "close_test.parent"}, log: &log})
+	r.RegisterModule(closeTestModule{info: ModuleInfo{ID: "close_test.child"}, log: &log, fail: true})
+	r.RegisterModule(closeTestModule{info: ModuleInfo{ID: "close_test.slow", CleanupTimeout: time.Millisecond}, log: &log, hang: hang})
+
+	ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+	defer cancel()
+	ctx.OnCancel(func() { log = append(log, "on cancel") })
+
+	if _, err := ctx.LoadModuleByID("close_test.slow", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctx.LoadModuleByID("close_test.parent", json.RawMessage(`{"child": {"name": "child"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ctx.Close()
+	if err == nil || !strings.Contains(err.Error(), "close_test.child: cleanup: failed") ||
+		!strings.Contains(err.Error(), "close_test.slow: cleanup: timed out after 1ms") {
+		t.Errorf("Close() error = %v", err)
+	}
+	if want := []string{"on cancel", "close_test.child", "close_test.parent"}; !slices.Equal(log, want) {
+		t.Errorf("cleaned up %v, want %v", log, want)
+	}
+	if ctx.Err() == nil {
+		t.Error("context not canceled")
+	}
+	if err2 := ctx.Close(); err2 != err {
+		t.Errorf("second Close() = %v, want %v", err2, err)
+	}
+}
+
+func TestContextOnCancelWhileClosing(t *testing.T) {
+	t.Parallel()
+
+	for range 50 {
+		ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}})
+		var called atomic.Int32
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				for range 10 {
+					ctx.OnCancel(func() { called.Add(1) })
+				}
+			})
+		}
+		cancel()
+		wg.Wait()
+
+		// after Close, OnCancel runs f before returning
+		ctx.OnCancel(func() { called.Add(1) })
+		if n := called.Load(); n != 41 {
+			t.Fatalf("%d OnCancel functions ran by the time all were registered, want 41", n)
+		}
+	}
+}
+
+type appTestModule struct {
+	info      ModuleInfo
+	deps      []string
+	uses      []string
+	failStart bool
+	log       *[]string
+}
+
+func newAppTestModule(m appTestModule) appTestModule {
+	m.info.New = func() Module { mod := m; return &mod }
+	return m
+}
+
+func (m appTestModule) UniModule() ModuleInfo { return m.info }
+
+func (m *appTestModule) Provision(ctx Context) error {
+	for _, name := range m.uses {
+		if _, err := ctx.App(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *appTestModule) DependsOn() []string { return m.deps }
+
+func (m *appTestModule) Start() error {
+	if m.failStart {
+		return errors.New("failed")
+	}
+	*m.log = append(*m.log, "start "+string(m.info.ID))
+	return nil
+}
+
+func (m *appTestModule) Stop() error {
+	*m.log = append(*m.log, "stop "+string(m.info.ID))
+	return nil
+}
+
+func TestContextApp(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	for _, m := range []appTestModule{
+		{info: ModuleInfo{ID: "a"}, uses: []string{"b"}},
+		{info: ModuleInfo{ID: "b"}},
+		{info: ModuleInfo{ID: "self"}, uses: []string{"self"}},
+		{info: ModuleInfo{ID: "x"}, uses: []string{"y"}},
+		{info: ModuleInfo{ID: "y"}, uses: []string{"z"}},
+		{info: ModuleInfo{ID: "z"}, uses: []string{"x"}},
+		{info: ModuleInfo{ID: "renamed", Aliases: []ModuleID{"legacy"}}},
+		{info: ModuleInfo{ID: "invalid"}},
+	} {
+		r.RegisterModule(newAppTestModule(m))
+	}
+
+	cfg := &Config{
+		AppsRaw: ModuleMap{
+			"a":       json.RawMessage(`{}`),
+			"legacy":  json.RawMessage(`{}`),
+			"invalid": json.RawMessage(`{"unknown": true}`),
+		},
+		apps:       make(map[string]App),
+		failedApps: make(map[string]error),
+	}
+	ctx, cancel := NewContext(Context{Context: context.Background(), cfg: cfg}.WithRegistry(r))
+	defer cancel()
+
+	if _, err := ctx.AppIfConfigured("b"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("AppIfConfigured(b) before loading it: error = %v, want ErrNotConfigured", err)
+	}
+	a, err := ctx.AppIfConfigured("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ctx.App("a"); again != a {
+		t.Error("App() loaded the app again")
+	}
+	if _, err := ctx.AppIfConfigured("b"); err != nil {
+		t.Errorf("AppIfConfigured(b) after a loaded it: %v", err)
+	}
+
+	if _, err := ctx.App("self"); err != nil {
+		t.Errorf("app getting itself while provisioning: %v", err)
+	}
+
+	_, err = ctx.App("x")
+	if err == nil || !strings.Contains(err.Error(), "app dependency cycle: x -> y -> z -> x") {
+		t.Errorf("App(x) error = %v, want dependency cycle", err)
+	}
+	if _, err := ctx.App("x"); err == nil || !strings.Contains(err.Error(), "failed to provision") {
+		t.Errorf("App(x) after failing error = %v", err)
+	}
+	if len(cfg.loadingApps) != 0 {
+		t.Errorf("apps still loading: %v", cfg.loadingApps)
+	}
+
+	// an app is the same whether it is named by its ID or by
+	// an alias, and the config may use either
+	renamed, err := ctx.AppIfConfigured("renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy, err := ctx.App("legacy"); err != nil || legacy != renamed {
+		t.Errorf("App(legacy) = %v, %v; want the renamed app", legacy, err)
+	}
+	if _, ok := cfg.apps["legacy"]; ok || cfg.AppsRaw["legacy"] != nil {
+		t.Error("app was not loaded from its config under the alias")
+	}
+
+	// the pointer of an app is that of its config, not of the
+	// module which asked for it
+	var ce *ConfigError
+	_, err = ctx.WithConfigPointer("apps", "a", "b").App("invalid")
+	if !errors.As(err, &ce) || ce.Pointer != "/apps/invalid/unknown" {
+		t.Errorf("App(invalid) error = %v, want one at /apps/invalid/unknown", err)
+	}
+}
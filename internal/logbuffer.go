@@ -12,14 +12,36 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// DefaultMaxBufferedLogEntries is the number of entries a
+// LogBufferCore retains if no other maximum is given.
+const DefaultMaxBufferedLogEntries = 10000
+
 // LogBufferCore is an in-memory zap logger that implements zapcore.Core.
 //
+// Entries are retained with everything needed to replay them
+// faithfully: their original timestamp, logger name, caller and
+// stack, as well as the fields of the logger's context. Once the
+// maximum number of entries is reached, the oldest entries are
+// dropped and counted.
+//
 // zapcore.Core https://github.com/uber-go/zap/blob/v1.27.1/zapcore/core.go#L25
 type LogBufferCore struct {
+	// shared by all cores derived from this one via With
+	buf *logBuffer
+
+	context []zapcore.Field
+	level   zapcore.LevelEnabler
+}
+
+// logBuffer is the storage of a LogBufferCore. It is a
+// ring: once full, start marks the oldest entry.
+type logBuffer struct {
 	mu      sync.Mutex
 	entries []zapcore.Entry
 	fields  [][]zapcore.Field
-	level   zapcore.LevelEnabler
+	start   int
+	max     int
+	dropped uint64
 }
 
 // Enabled returns true if the given log level is enabled.
@@ -31,9 +53,16 @@ func (c *LogBufferCore) Enabled(lvl zapcore.Level) bool {
 }
 
 // With returns a Core with additional structured fields.
-// This implementation ignores the fields and returns itself.
+// The returned core shares the buffer with c.
 func (c *LogBufferCore) With(fields []zapcore.Field) zapcore.Core {
-	return c
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	context = append(context, fields...)
+	return &LogBufferCore{
+		buf:     c.buf,
+		context: context,
+		level:   c.level,
+	}
 }
 
 // Check determines if the log entry should be logged.
@@ -45,28 +74,82 @@ func (c *LogBufferCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *za
 	return ce
 }
 
-// Write appends the entry and fields to the internal buffer.
+// Write appends the entry and fields, including those of
+// the core's context, to the internal buffer.
 func (c *LogBufferCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.entries = append(c.entries, entry)
-	c.fields = append(c.fields, fields)
+	all := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	all = append(all, c.context...)
+	all = append(all, fields...)
+
+	b := c.buf
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) < b.max {
+		b.entries = append(b.entries, entry)
+		b.fields = append(b.fields, all)
+		return nil
+	}
+	b.entries[b.start] = entry
+	b.fields[b.start] = all
+	b.start = (b.start + 1) % b.max
+	b.dropped++
 	return nil
 }
 
 // Sync is a no-op for the in-memory buffer.
 func (c *LogBufferCore) Sync() error { return nil }
 
+// Len returns the number of buffered entries.
+func (c *LogBufferCore) Len() int {
+	c.buf.mu.Lock()
+	defer c.buf.mu.Unlock()
+	return len(c.buf.entries)
+}
+
+// Dropped returns the number of entries that were dropped
+// because the buffer was full.
+func (c *LogBufferCore) Dropped() uint64 {
+	c.buf.mu.Lock()
+	defer c.buf.mu.Unlock()
+	return c.buf.dropped
+}
+
 // FlushTo writes all buffered log entries to the given zap.Logger
-// and clears the buffer.
+// and clears the buffer. Entries are written directly to the
+// logger's core, so they keep their original timestamp, logger
+// name and caller. If entries were dropped, a warning saying how
+// many is written first.
+//
+// The entries are taken out of the buffer before they are
+// written, so the logger may write to this buffer itself.
 func (c *LogBufferCore) FlushTo(logger *zap.Logger) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	for idx, entry := range c.entries {
-		logger.WithOptions().Check(entry.Level, entry.Message).Write(c.fields[idx]...)
+	b := c.buf
+	b.mu.Lock()
+	n := len(b.entries)
+	entries := make([]zapcore.Entry, n)
+	fields := make([][]zapcore.Field, n)
+	for i := range n {
+		idx := (b.start + i) % n
+		entries[i], fields[i] = b.entries[idx], b.fields[idx]
+	}
+	dropped, maxEntries := b.dropped, b.max
+	b.entries = nil
+	b.fields = nil
+	b.start = 0
+	b.dropped = 0
+	b.mu.Unlock()
+
+	core := logger.Core()
+	if dropped > 0 {
+		logger.Warn("log buffer overflowed; earliest entries were dropped",
+			zap.Uint64("dropped", dropped),
+			zap.Int("max_entries", maxEntries))
+	}
+	for i, entry := range entries {
+		if ce := core.Check(entry, nil); ce != nil {
+			ce.Write(fields[i]...)
+		}
 	}
-	c.entries = nil
-	c.fields = nil
 }
 
 // LogBufferCoreInterface is a helper interface that combines zapcore.Core
@@ -78,9 +161,15 @@ type LogBufferCoreInterface interface {
 	FlushTo(*zap.Logger)
 }
 
-// NewLogBufferCore creates a new LogBufferCore with the specified log level.
-func NewLogBufferCore(level zapcore.LevelEnabler) *LogBufferCore {
+// NewLogBufferCore creates a new LogBufferCore with the specified
+// log level, which retains at most maxEntries entries. If maxEntries
+// is not positive, DefaultMaxBufferedLogEntries is used.
+func NewLogBufferCore(level zapcore.LevelEnabler, maxEntries int) *LogBufferCore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxBufferedLogEntries
+	}
 	return &LogBufferCore{
+		buf:   &logBuffer{max: maxEntries},
 		level: level,
 	}
 }
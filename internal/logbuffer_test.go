@@ -0,0 +1,102 @@
+// Copyright 2025 K2 <skrik2@outlook.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogBufferCoreFlushPreservesEntries(t *testing.T) {
+	buf := NewLogBufferCore(zapcore.DebugLevel, 0)
+	logger := zap.New(buf, zap.AddCaller()).Named("tls").With(zap.String("issuer", "acme"))
+
+	logger.Info("obtaining certificate", zap.String("domain", "example.com"))
+	ts := time.Now()
+
+	// flush later, so timestamps would differ if they were not preserved
+	time.Sleep(time.Millisecond)
+
+	target, logs := observer.New(zapcore.DebugLevel)
+	buf.FlushTo(zap.New(target).Named("ignored"))
+
+	all := logs.All()
+	if len(all) != 1 {
+		t.Fatalf("got %d entries, want 1", len(all))
+	}
+	e := all[0]
+	if e.LoggerName != "tls" {
+		t.Errorf("logger name = %q, want %q", e.LoggerName, "tls")
+	}
+	if !e.Caller.Defined {
+		t.Error("caller was lost")
+	}
+	if e.Time.After(ts) {
+		t.Errorf("timestamp %v was not preserved (flushed after %v)", e.Time, ts)
+	}
+	fields := e.ContextMap()
+	if fields["issuer"] != "acme" || fields["domain"] != "example.com" {
+		t.Errorf("fields = %v, want issuer and domain", fields)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("buffer not cleared: %d entries", buf.Len())
+	}
+}
+
+func TestLogBufferCoreOverflow(t *testing.T) {
+	buf := NewLogBufferCore(zapcore.DebugLevel, 2)
+	logger := zap.New(buf)
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		logger.Info(msg)
+	}
+
+	if buf.Len() != 2 || buf.Dropped() != 2 {
+		t.Fatalf("Len() = %d, Dropped() = %d; want 2, 2", buf.Len(), buf.Dropped())
+	}
+
+	target, logs := observer.New(zapcore.DebugLevel)
+	buf.FlushTo(zap.New(target))
+
+	var msgs []string
+	for _, e := range logs.All() {
+		msgs = append(msgs, e.Message)
+	}
+	if len(msgs) != 3 || msgs[1] != "c" || msgs[2] != "d" {
+		t.Fatalf("flushed %v, want overflow warning followed by c, d", msgs)
+	}
+	if buf.Dropped() != 0 {
+		t.Errorf("overflow counter not reset")
+	}
+}
+
+func TestLogBufferCoreFlushToItself(t *testing.T) {
+	buf := NewLogBufferCore(zapcore.DebugLevel, 0)
+	zap.New(buf).Info("a")
+
+	// a target which tees back into the buffer must not
+	// deadlock, and receives the entries once
+	target, logs := observer.New(zapcore.DebugLevel)
+	done := make(chan struct{})
+	go func() {
+		buf.FlushTo(zap.New(zapcore.NewTee(target, buf)))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("FlushTo deadlocked")
+	}
+
+	if logs.Len() != 1 {
+		t.Errorf("flushed %d entries, want 1", logs.Len())
+	}
+	if buf.Len() != 1 {
+		t.Errorf("buffer has %d entries, want the 1 entry written back while flushing", buf.Len())
+	}
+}
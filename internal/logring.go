@@ -0,0 +1,173 @@
+// Copyright 2025 K2 <skrik2@outlook.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LogRing is a bounded, in-memory store of the most recent
+// log entries. Once full, each new entry overwrites the
+// oldest one. Unlike LogBufferCore, entries are kept along
+// with the fields added to their logger via With.
+type LogRing struct {
+	mu      sync.RWMutex
+	entries []RecordedLogEntry
+	next    int
+	full    bool
+}
+
+// RecordedLogEntry is a log entry together with all of
+// its fields, including those of its logger's context.
+type RecordedLogEntry struct {
+	zapcore.Entry
+	Fields []zapcore.Field
+}
+
+// LogQuery selects entries from a LogRing. Zero values
+// do not restrict the result.
+type LogQuery struct {
+	// MinLevel is the lowest level to return.
+	MinLevel zapcore.Level
+
+	// Logger matches logger names by namespace, i.e.
+	// "http" matches "http" and "http.handlers" but
+	// not "https".
+	Logger string
+
+	// Since and Until bound the entry timestamps (inclusive).
+	Since, Until time.Time
+
+	// Contains is a substring that must appear in the
+	// message or in the string form of any field value.
+	Contains string
+
+	// Limit is the maximum number of entries to return;
+	// the most recent matching entries are kept.
+	Limit int
+}
+
+// NewLogRing returns a LogRing that retains up to size entries.
+func NewLogRing(size int) *LogRing {
+	if size < 1 {
+		size = 1
+	}
+	return &LogRing{entries: make([]RecordedLogEntry, size)}
+}
+
+// Add records an entry, overwriting the oldest one if the
+// ring is full. The fields slice is copied.
+func (r *LogRing) Add(entry zapcore.Entry, fields []zapcore.Field) {
+	recorded := RecordedLogEntry{
+		Entry:  entry,
+		Fields: append([]zapcore.Field(nil), fields...),
+	}
+	r.mu.Lock()
+	r.entries[r.next] = recorded
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+	r.mu.Unlock()
+}
+
+// Len returns the number of entries currently retained.
+func (r *LogRing) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.full {
+		return len(r.entries)
+	}
+	return r.next
+}
+
+// Cap returns the maximum number of entries retained.
+func (r *LogRing) Cap() int {
+	return len(r.entries)
+}
+
+// Query returns the retained entries matching q, oldest first.
+func (r *LogRing) Query(q LogQuery) []RecordedLogEntry {
+	r.mu.RLock()
+	var ordered []RecordedLogEntry
+	if r.full {
+		ordered = append(ordered, r.entries[r.next:]...)
+	}
+	ordered = append(ordered, r.entries[:r.next]...)
+	r.mu.RUnlock()
+
+	matched := ordered[:0]
+	for _, e := range ordered {
+		if q.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[len(matched)-q.Limit:]
+	}
+	return matched
+}
+
+func (q LogQuery) matches(e RecordedLogEntry) bool {
+	if e.Level < q.MinLevel {
+		return false
+	}
+	if q.Logger != "" && e.LoggerName != q.Logger &&
+		!strings.HasPrefix(e.LoggerName, q.Logger+".") {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.Time.After(q.Until) {
+		return false
+	}
+	if q.Contains != "" && !e.contains(q.Contains) {
+		return false
+	}
+	return true
+}
+
+func (e RecordedLogEntry) contains(s string) bool {
+	if strings.Contains(e.Message, s) {
+		return true
+	}
+	for _, v := range e.FieldMap() {
+		if strings.Contains(toString(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldMap encodes the entry's fields into a map, as they
+// would appear in a JSON log.
+func (e RecordedLogEntry) FieldMap() map[string]any {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range e.Fields {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case interface{ String() string }:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+	return fmt.Sprint(v)
+}
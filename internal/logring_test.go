@@ -0,0 +1,81 @@
+// Copyright 2025 K2 <skrik2@outlook.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogRingOverwritesOldest(t *testing.T) {
+	r := NewLogRing(3)
+	for i, msg := range []string{"a", "b", "c", "d", "e"} {
+		r.Add(zapcore.Entry{Message: msg, Time: time.Unix(int64(i), 0)}, nil)
+	}
+
+	if r.Len() != 3 || r.Cap() != 3 {
+		t.Fatalf("Len() = %d, Cap() = %d; want 3, 3", r.Len(), r.Cap())
+	}
+
+	got := r.Query(LogQuery{})
+	want := []string{"c", "d", "e"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Message != want[i] {
+			t.Errorf("entry %d = %q, want %q", i, got[i].Message, want[i])
+		}
+	}
+}
+
+func TestLogRingQuery(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewLogRing(10)
+	add := func(lvl zapcore.Level, logger, msg string, offset time.Duration, fields ...zapcore.Field) {
+		r.Add(zapcore.Entry{Level: lvl, LoggerName: logger, Message: msg, Time: base.Add(offset)}, fields)
+	}
+	add(zapcore.DebugLevel, "http", "one", 0)
+	add(zapcore.InfoLevel, "http.handlers", "two", time.Minute, zap.String("host", "example.com"))
+	add(zapcore.WarnLevel, "https", "three", 2*time.Minute)
+	add(zapcore.ErrorLevel, "tls", "four", 3*time.Minute, zap.Int("code", 42))
+
+	tests := []struct {
+		name  string
+		query LogQuery
+		want  []string
+	}{
+		{"all", LogQuery{MinLevel: zapcore.DebugLevel}, []string{"one", "two", "three", "four"}},
+		{"min level", LogQuery{MinLevel: zapcore.WarnLevel}, []string{"three", "four"}},
+		{"logger namespace", LogQuery{MinLevel: zapcore.DebugLevel, Logger: "http"}, []string{"one", "two"}},
+		{"since", LogQuery{MinLevel: zapcore.DebugLevel, Since: base.Add(2 * time.Minute)}, []string{"three", "four"}},
+		{"until", LogQuery{MinLevel: zapcore.DebugLevel, Until: base.Add(time.Minute)}, []string{"one", "two"}},
+		{"contains message", LogQuery{MinLevel: zapcore.DebugLevel, Contains: "hre"}, []string{"three"}},
+		{"contains string field", LogQuery{MinLevel: zapcore.DebugLevel, Contains: "example"}, []string{"two"}},
+		{"contains number field", LogQuery{MinLevel: zapcore.DebugLevel, Contains: "42"}, []string{"four"}},
+		{"limit keeps most recent", LogQuery{MinLevel: zapcore.DebugLevel, Limit: 2}, []string{"three", "four"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.Query(tt.query)
+			var msgs []string
+			for _, e := range got {
+				msgs = append(msgs, e.Message)
+			}
+			if len(msgs) != len(tt.want) {
+				t.Fatalf("got %v, want %v", msgs, tt.want)
+			}
+			for i := range msgs {
+				if msgs[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", msgs, tt.want)
+				}
+			}
+		})
+	}
+}
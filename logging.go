@@ -2,9 +2,13 @@ package uni
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +23,10 @@ func init() {
 	RegisterModule(StdoutWriter{})
 	RegisterModule(StderrWriter{})
 	RegisterModule(DiscardWriter{})
+
+	RegisterNamespace[WriterProvider]("uni.logging.writers")
+	RegisterNamespace[zapcore.Encoder]("uni.logging.encoders")
+	RegisterNamespace[zapcore.Core]("uni.logging.cores")
 }
 
 // Log returns the current default logger
@@ -67,6 +75,19 @@ type Logging struct {
 	// and filter what kinds of entries they accept.
 	Logs map[string]*CustomLog `json:"logs,omitempty"`
 
+	// AuditKey is the key the hashes of the audit log (see
+	// AuditLogName) are computed with, so that entries can
+	// not be forged without it. It should be given as a
+	// placeholder, for example {secret.vault.audit_key},
+	// rather than in the config itself. Without a key,
+	// entries are hashed with plain SHA-256.
+	AuditKey string `json:"audit_key,omitempty"`
+
+	// StartupBuffer configures the buffer which holds the
+	// entries logged while the config loads, until its logs
+	// are installed.
+	StartupBuffer *StartupLogBuffer `json:"startup_buffer,omitempty"`
+
 	// This ensures that open log streams can be properly closed when the
 	// log configuration is no longer needed, thereby preventing resource leaks.
 	//
@@ -77,6 +98,66 @@ type Logging struct {
 	WriterIDs []string
 }
 
+// openLogs provisions the configured logs, except the audit
+// log (see openAuditLog), and makes the default logger emit
+// to them: the log named "default" replaces the current
+// default log, and the other logs are teed to it. It returns
+// the function which restores the previous default logger, if
+// it was not replaced since, and closes the logs.
+func (logging *Logging) openLogs(ctx Context) (func() error, error) {
+	var opened []*CustomLog
+	closeLogs := func() error {
+		var errs []error
+		for _, cl := range opened {
+			errs = append(errs, cl.Cleanup())
+		}
+		return errors.Join(errs...)
+	}
+
+	var cores []zapcore.Core
+	replaceDefault := false
+	for _, name := range slices.Sorted(maps.Keys(logging.Logs)) {
+		cl := logging.Logs[name]
+		if name == AuditLogName || cl == nil {
+			continue
+		}
+		if err := cl.Provision(ctx.WithConfigPointer("logging", "logs", name)); err != nil {
+			_ = closeLogs()
+			return nil, fmt.Errorf("setting up log %s: %w", name, err)
+		}
+		opened = append(opened, cl)
+		logging.WriterIDs = append(logging.WriterIDs, cl.writerProvider.WriterID())
+		cores = append(cores, cl.Core())
+		replaceDefault = replaceDefault || name == "default"
+	}
+	if len(opened) == 0 {
+		return func() error { return nil }, nil
+	}
+
+	defaultLoggerMu.Lock()
+	prev := defaultLogger.logger
+	if pendingLogBuffer.core != nil && prev == pendingLogBuffer.bufferLogger {
+		// the buffered entries are flushed to the new logger,
+		// so it must not emit to the buffer itself
+		prev = pendingLogBuffer.origLogger
+	}
+	if !replaceDefault {
+		cores = append([]zapcore.Core{prev.Core()}, cores...)
+	}
+	logger := zap.New(zapcore.NewTee(cores...))
+	defaultLogger.logger = logger
+	defaultLoggerMu.Unlock()
+
+	return func() error {
+		defaultLoggerMu.Lock()
+		if defaultLogger.logger == logger {
+			defaultLogger.logger = prev
+		}
+		defaultLoggerMu.Unlock()
+		return closeLogs()
+	}, nil
+}
+
 // SinkLog configures the default Go standard library
 // global logger in the log package. This is necessary because
 // module dependencies which are not built specifically for
@@ -107,19 +188,131 @@ type CustomLog struct {
 	// skipped by this log. For example, to exclude only
 	// HTTP access logs, you would exclude "http.log.access".
 	Exclude []string `json:"exclude,omitempty"`
+
+	// Levels overrides the minimum level for the loggers in
+	// a namespace, keyed by the namespace. For example, to
+	// also emit the DEBUG entries of the admin API, you would
+	// set "admin.api" to "DEBUG". Like with Include and
+	// Exclude, the longest matching namespace has priority.
+	Levels map[string]string `json:"levels,omitempty"`
+
+	level        zapcore.Level
+	loggerLevels map[string]zapcore.Level
+}
+
+// Provision sets up the log like BaseLog.Provision does, and
+// makes it emit only the entries of the loggers it includes,
+// at the level configured for them.
+func (cl *CustomLog) Provision(ctx Context) error {
+	for _, include := range cl.Include {
+		if slices.Contains(cl.Exclude, include) {
+			return fmt.Errorf("include and exclude must not intersect, but found %s in both lists", include)
+		}
+	}
+
+	var err error
+	cl.level, err = parseLogLevel(cl.Level)
+	if err != nil {
+		return err
+	}
+	if len(cl.Levels) > 0 {
+		// the core must let through the entries of any
+		// logger; the filter enforces the level of each
+		lowest := cl.level
+		cl.loggerLevels = make(map[string]zapcore.Level, len(cl.Levels))
+		for ns, level := range cl.Levels {
+			lvl, err := parseLogLevel(level)
+			if err != nil {
+				return fmt.Errorf("level of %s: %v", ns, err)
+			}
+			cl.loggerLevels[ns] = lvl
+			lowest = min(lowest, lvl)
+		}
+		cl.levelEnabler = lowest
+	}
+
+	if err := cl.BaseLog.Provision(ctx); err != nil {
+		return err
+	}
+	if len(cl.Include) > 0 || len(cl.Exclude) > 0 || len(cl.loggerLevels) > 0 {
+		cl.core = &filteringCore{Core: cl.core, cl: cl}
+	}
+	return nil
+}
+
+// loggerAllowed returns true if the log emits the entries of
+// the logger with the given name. A namespace in Include or
+// Exclude matches the logger of that name and all loggers
+// below it, so "admin" matches "admin.api" but not "adminer";
+// the longest matching namespace decides.
+func (cl *CustomLog) loggerAllowed(name string) bool {
+	longest := func(namespaces []string) int {
+		best := -1
+		for _, ns := range namespaces {
+			if inLoggerNamespace(name, ns) && len(ns) > best {
+				best = len(ns)
+			}
+		}
+		return best
+	}
+
+	accept, reject := longest(cl.Include), longest(cl.Exclude)
+	if len(cl.Include) > 0 && accept < 0 {
+		return false
+	}
+	return accept >= reject
+}
+
+// loggerLevel returns the minimum level of the entries the
+// log emits for the logger with the given name: that of the
+// longest namespace in Levels which matches it, or Level.
+func (cl *CustomLog) loggerLevel(name string) zapcore.Level {
+	level, best := cl.level, -1
+	for ns, lvl := range cl.loggerLevels {
+		if inLoggerNamespace(name, ns) && len(ns) > best {
+			level, best = lvl, len(ns)
+		}
+	}
+	return level
+}
+
+// inLoggerNamespace returns true if the logger name is the
+// namespace or below it.
+func inLoggerNamespace(name, ns string) bool {
+	return name == ns || strings.HasPrefix(name, ns+".")
+}
+
+// filteringCore filters the entries of a log by logger name.
+type filteringCore struct {
+	zapcore.Core
+	cl *CustomLog
+}
+
+// With returns a filteringCore of the core with the fields.
+func (fc *filteringCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filteringCore{Core: fc.Core.With(fields), cl: fc.cl}
+}
+
+// Check only adds the entry if its logger is allowed, and
+// the entry is at or above the level of the logger.
+func (fc *filteringCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !fc.cl.loggerAllowed(e.LoggerName) || e.Level < fc.cl.loggerLevel(e.LoggerName) {
+		return ce
+	}
+	return fc.Core.Check(e, ce)
 }
 
 // BaseLog contains the common logging parameters for logging.
 type BaseLog struct {
 	// The module that writes out log entries for the sink.
-	WriterRaw json.RawMessage `json:"writer,omitempty" caddy:"namespace=caddy.logging.writers inline_key=output"`
+	WriterRaw json.RawMessage `json:"writer,omitempty" uni:"namespace=uni.logging.writers inline_key=output"`
 
 	// The encoder is how the log entries are formatted or encoded.
-	EncoderRaw json.RawMessage `json:"encoder,omitempty" caddy:"namespace=caddy.logging.encoders inline_key=format"`
+	EncoderRaw json.RawMessage `json:"encoder,omitempty" uni:"namespace=uni.logging.encoders inline_key=format"`
 
 	// Tees entries through a zap.Core module which can extract
 	// log entry metadata and fields for further processing.
-	CoreRaw json.RawMessage `json:"core,omitempty" caddy:"namespace=caddy.logging.cores inline_key=module"`
+	CoreRaw json.RawMessage `json:"core,omitempty" uni:"namespace=uni.logging.cores inline_key=module"`
 
 	// Level is the minimum level to emit, and is inclusive.
 	// Possible levels: DEBUG, INFO, WARN, ERROR, PANIC, and FATAL
@@ -157,6 +350,100 @@ type BaseLog struct {
 	core         zapcore.Core
 }
 
+// Provision sets up the log: it loads the writer, encoder and
+// core modules, opens the writer and builds the zapcore.Core
+// that emits entries to it. Modules that embed a BaseLog in
+// their configuration (for example to fan out entries to
+// several writers) must call Provision themselves and Cleanup
+// once the log is no longer needed.
+func (cl *BaseLog) Provision(ctx Context) error {
+	level, err := parseLogLevel(cl.Level)
+	if err != nil {
+		return err
+	}
+	// a CustomLog with per-logger levels may have
+	// lowered the level already (see Levels)
+	if cl.levelEnabler == nil {
+		cl.levelEnabler = level
+	}
+
+	if cl.WriterRaw != nil {
+		cl.writerProvider, err = LoadModuleAs[WriterProvider](ctx, cl, "WriterRaw")
+		if err != nil {
+			return fmt.Errorf("loading log writer module: %w", err)
+		}
+	}
+	if cl.writerProvider == nil {
+		cl.writerProvider = StderrWriter{}
+	}
+	cl.writer, err = cl.writerProvider.OpenWriter()
+	if err != nil {
+		return fmt.Errorf("opening log writer using %s: %v", cl.writerProvider, err)
+	}
+
+	if cl.EncoderRaw != nil {
+		cl.encoder, err = LoadModuleAs[zapcore.Encoder](ctx, cl, "EncoderRaw")
+		if err != nil {
+			return fmt.Errorf("loading log encoder module: %w", err)
+		}
+
+		// if the encoder module needs the writer to determine
+		// the correct default to use for a nested encoder, we
+		// pass it down as a secondary provisioning step
+		if cfd, ok := cl.encoder.(interface {
+			SetWriterDefaultFormat(WriterProvider) error
+		}); ok {
+			if err := cfd.SetWriterDefaultFormat(cl.writerProvider); err != nil {
+				return fmt.Errorf("configuring default format for encoder module: %v", err)
+			}
+		}
+	}
+	if cl.encoder == nil {
+		cl.encoder = newDefaultProductionLogEncoder(cl.writerProvider)
+	}
+
+	cl.buildCore()
+
+	if cl.CoreRaw != nil {
+		core, err := LoadModuleAs[zapcore.Core](ctx, cl, "CoreRaw")
+		if err != nil {
+			return fmt.Errorf("loading log core module: %w", err)
+		}
+		cl.core = zapcore.NewTee(cl.core, core)
+	}
+
+	return nil
+}
+
+// Core returns the core that emits entries to this log.
+// It is nil until the log is provisioned.
+func (cl *BaseLog) Core() zapcore.Core {
+	return cl.core
+}
+
+// Cleanup closes the writer opened by Provision.
+func (cl *BaseLog) Cleanup() error {
+	if cl.writer == nil {
+		return nil
+	}
+	err := cl.writer.Close()
+	cl.writer = nil
+	return err
+}
+
+// parseLogLevel converts a configured level name into the
+// zapcore level it enables. An empty level means INFO.
+func parseLogLevel(level string) (zapcore.Level, error) {
+	if level == "" {
+		return zapcore.InfoLevel, nil
+	}
+	lvl, err := zapcore.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return 0, fmt.Errorf("unrecognized log level: %s", level)
+	}
+	return lvl, nil
+}
+
 func (cl *BaseLog) buildCore() {
 	// logs which only discard their output don't need
 	// to perform encoding or any other processing steps
@@ -348,22 +635,107 @@ func newDefaultProductionLogEncoder(wf WriterProvider) zapcore.Encoder {
 	return zapcore.NewJSONEncoder(encCfg)
 }
 
+// StartupLogBuffer configures the buffer of the entries
+// logged while a config loads (see BufferedLog).
+type StartupLogBuffer struct {
+	// The maximum number of entries to retain; older ones
+	// are dropped and counted. Default: BufferedLogMaxEntries.
+	MaxEntries int `json:"max_entries,omitempty"`
+
+	// The minimum level of entries to retain. Default: INFO.
+	Level string `json:"level,omitempty"`
+}
+
+// settings returns the level and maximum number of entries
+// of the buffer. sb may be nil, for the defaults.
+func (sb *StartupLogBuffer) settings() (zapcore.Level, int, error) {
+	if sb == nil {
+		return zapcore.InfoLevel, BufferedLogMaxEntries, nil
+	}
+	level, err := parseLogLevel(sb.Level)
+	if err != nil {
+		return 0, 0, &ConfigError{Pointer: "/logging/startup_buffer/level", Err: err}
+	}
+	if sb.MaxEntries < 0 {
+		return 0, 0, &ConfigError{Pointer: "/logging/startup_buffer/max_entries", Err: errors.New("must not be negative")}
+	}
+	maxEntries := sb.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = BufferedLogMaxEntries
+	}
+	return level, maxEntries, nil
+}
+
 // BufferedLog sets the default logger to one that buffers
-// logs before a config is loaded.
+// logs before a config is loaded. At most BufferedLogMaxEntries
+// entries of level INFO or higher are retained; older ones are
+// dropped and counted.
 //
 // Returns the buffered logger, the original default logger
 // (for flushing on errors), and the buffer core so that the
 // caller can flush the logs after the config is loaded or
-// fails to load.
+// fails to load. Most callers should simply call
+// FlushBufferedLog when loading is done.
 func BufferedLog() (*zap.Logger, *zap.Logger, *internal.LogBufferCore) {
+	return bufferedLog(zapcore.InfoLevel, BufferedLogMaxEntries)
+}
+
+// bufferedLog is BufferedLog with the given level and
+// maximum number of entries.
+func bufferedLog(level zapcore.Level, maxEntries int) (*zap.Logger, *zap.Logger, *internal.LogBufferCore) {
 	defaultLoggerMu.Lock()
 	defer defaultLoggerMu.Unlock()
 	origLogger := defaultLogger.logger
-	bufferCore := internal.NewLogBufferCore(zap.InfoLevel)
+	bufferCore := internal.NewLogBufferCore(level, maxEntries)
 	defaultLogger.logger = zap.New(bufferCore)
+	pendingLogBuffer.core = bufferCore
+	pendingLogBuffer.bufferLogger = defaultLogger.logger
+	pendingLogBuffer.origLogger = origLogger
 	return defaultLogger.logger, origLogger, bufferCore
 }
 
+// FlushBufferedLog ends buffering started by BufferedLog and
+// replays the buffered entries. If loadErr is nil and the
+// default logger has been replaced by a newly configured one,
+// the entries are written to it. Otherwise, including when
+// loading the config failed, the original default logger is
+// restored and receives the entries, followed by loadErr.
+// It does nothing if logs are not being buffered.
+func FlushBufferedLog(loadErr error) {
+	defaultLoggerMu.Lock()
+	pending := pendingLogBuffer
+	pendingLogBuffer.core = nil
+	if pending.core == nil {
+		defaultLoggerMu.Unlock()
+		return
+	}
+	target := defaultLogger.logger
+	if loadErr != nil || target == pending.bufferLogger {
+		defaultLogger.logger = pending.origLogger
+		target = pending.origLogger
+	}
+	defaultLoggerMu.Unlock()
+
+	pending.core.FlushTo(target)
+	if loadErr != nil {
+		target.Error("loading config failed", zap.Error(loadErr))
+	}
+}
+
+// BufferedLogMaxEntries is the maximum number of entries
+// retained by the logger installed by BufferedLog, and by
+// default while a config loads (see StartupLogBuffer).
+var BufferedLogMaxEntries = internal.DefaultMaxBufferedLogEntries
+
+// pendingLogBuffer is the buffer installed by BufferedLog
+// which has not been flushed yet, if any. It is guarded
+// by defaultLoggerMu.
+var pendingLogBuffer struct {
+	core         *internal.LogBufferCore
+	bufferLogger *zap.Logger
+	origLogger   *zap.Logger
+}
+
 var (
 	defaultLoggerMu  sync.RWMutex
 	defaultLogger, _ = newDefaultProductionLog()
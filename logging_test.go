@@ -0,0 +1,379 @@
+package uni
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFlushBufferedLog(t *testing.T) {
+	defaultLoggerMu.RLock()
+	saved := defaultLogger.logger
+	defaultLoggerMu.RUnlock()
+	defer func() {
+		defaultLoggerMu.Lock()
+		defaultLogger.logger = saved
+		defaultLoggerMu.Unlock()
+	}()
+
+	setDefault := func(l *zap.Logger) {
+		defaultLoggerMu.Lock()
+		defaultLogger.logger = l
+		defaultLoggerMu.Unlock()
+	}
+
+	t.Run("failure restores original logger", func(t *testing.T) {
+		origCore, orig := observer.New(zapcore.DebugLevel)
+		setDefault(zap.New(origCore))
+
+		buffered, _, _ := BufferedLog()
+		buffered.Info("starting")
+		FlushBufferedLog(errors.New("boom"))
+
+		if Log().Core() != origCore {
+			t.Error("original logger was not restored")
+		}
+		if got := orig.Len(); got != 2 {
+			t.Fatalf("original logger got %d entries, want 2", got)
+		}
+		if msg := orig.All()[0].Message; msg != "starting" {
+			t.Errorf("first entry = %q, want buffered entry", msg)
+		}
+	})
+
+	t.Run("success flushes to new logger", func(t *testing.T) {
+		origCore, orig := observer.New(zapcore.DebugLevel)
+		setDefault(zap.New(origCore))
+
+		buffered, _, _ := BufferedLog()
+		buffered.Info("starting")
+
+		newCore, configured := observer.New(zapcore.DebugLevel)
+		setDefault(zap.New(newCore))
+		FlushBufferedLog(nil)
+
+		if orig.Len() != 0 || configured.Len() != 1 {
+			t.Errorf("original got %d entries, new logger got %d; want 0, 1", orig.Len(), configured.Len())
+		}
+	})
+
+	t.Run("no-op without buffer", func(t *testing.T) {
+		FlushBufferedLog(nil)
+	})
+}
+
+func TestStartupLogBuffer(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		sb          *StartupLogBuffer
+		wantLevel   zapcore.Level
+		wantMax     int
+		wantPointer string
+	}{
+		{name: "nil", wantLevel: zapcore.InfoLevel, wantMax: BufferedLogMaxEntries},
+		{name: "empty", sb: &StartupLogBuffer{}, wantLevel: zapcore.InfoLevel, wantMax: BufferedLogMaxEntries},
+		{name: "set", sb: &StartupLogBuffer{Level: "DEBUG", MaxEntries: 3}, wantLevel: zapcore.DebugLevel, wantMax: 3},
+		{name: "bad level", sb: &StartupLogBuffer{Level: "loud"}, wantPointer: "/logging/startup_buffer/level"},
+		{name: "negative", sb: &StartupLogBuffer{MaxEntries: -1}, wantPointer: "/logging/startup_buffer/max_entries"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			level, maxEntries, err := tc.sb.settings()
+			if tc.wantPointer != "" {
+				var ce *ConfigError
+				if !errors.As(err, &ce) || ce.Pointer != tc.wantPointer {
+					t.Fatalf("got error %v, want config error at %s", err, tc.wantPointer)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if level != tc.wantLevel || maxEntries != tc.wantMax {
+				t.Errorf("got %v, %d; want %v, %d", level, maxEntries, tc.wantLevel, tc.wantMax)
+			}
+		})
+	}
+
+	t.Run("buffer", func(t *testing.T) {
+		defaultLoggerMu.RLock()
+		saved := defaultLogger.logger
+		defaultLoggerMu.RUnlock()
+		defer func() {
+			defaultLoggerMu.Lock()
+			defaultLogger.logger = saved
+			pendingLogBuffer.core = nil
+			pendingLogBuffer.bufferLogger = nil
+			defaultLoggerMu.Unlock()
+		}()
+
+		buffered, _, core := bufferedLog(zapcore.DebugLevel, 1)
+		buffered.Debug("first")
+		buffered.Debug("second")
+		if core.Len() != 1 || core.Dropped() != 1 {
+			t.Errorf("buffer holds %d entries and dropped %d, want 1 and 1", core.Len(), core.Dropped())
+		}
+	})
+
+	t.Run("run rejects bad settings", func(t *testing.T) {
+		cfg := &Config{Logging: &Logging{StartupBuffer: &StartupLogBuffer{Level: "loud"}}}
+		_, err := Run(cfg)
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Pointer != "/logging/startup_buffer/level" {
+			t.Errorf("Run: got error %v, want config error at the buffer level", err)
+		}
+		if err := Validate(cfg); !errors.As(err, &ce) {
+			t.Errorf("Validate: got error %v, want config error", err)
+		}
+	})
+}
+
+type loggerTestModule struct{}
+
+func (loggerTestModule) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "logger_test.module", New: func() Module { return new(loggerTestModule) }}
+}
+
+func (loggerTestModule) Provision(ctx Context) error {
+	ctx.Logger().Info("from zap")
+	ctx.Slogger().Info("from slog", "key", "value")
+	return nil
+}
+
+// not parallel: the default logger is global
+func TestContextLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	defaultLoggerMu.Lock()
+	saved := defaultLogger.logger
+	defaultLogger.logger = zap.New(core)
+	defaultLoggerMu.Unlock()
+	defer func() {
+		defaultLoggerMu.Lock()
+		defaultLogger.logger = saved
+		defaultLoggerMu.Unlock()
+	}()
+
+	r := NewRegistry()
+	r.RegisterModule(loggerTestModule{})
+	ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+	defer cancel()
+
+	if ctx.Logger().Core() != core {
+		t.Error("Logger() without a module is not the default logger")
+	}
+	if _, err := ctx.LoadModuleByID("logger_test.module", json.RawMessage(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	instance := ModuleInstances()[len(ModuleInstances())-1].Instance
+	for _, e := range entries {
+		if e.LoggerName != "logger_test.module" {
+			t.Errorf("%q: logger name = %q, want logger_test.module", e.Message, e.LoggerName)
+		}
+		if got := e.ContextMap()["instance"]; got != instance {
+			t.Errorf("%q: instance = %v, want %d", e.Message, got, instance)
+		}
+	}
+	if got := entries[1].ContextMap()["key"]; got != "value" {
+		t.Errorf("slog attribute = %v, want value", got)
+	}
+}
+
+func TestCustomLogLoggerAllowed(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		include, exclude []string
+		name             string
+		want             bool
+	}{
+		{name: "admin.api", want: true},
+		{include: []string{"admin"}, name: "admin", want: true},
+		{include: []string{"admin"}, name: "admin.api", want: true},
+		{include: []string{"admin"}, name: "adminer", want: false},
+		{include: []string{"admin"}, name: "", want: false},
+		{exclude: []string{"admin"}, name: "admin.api", want: false},
+		{exclude: []string{"admin"}, name: "uni.logging", want: true},
+		{include: []string{"admin"}, exclude: []string{"admin.api"}, name: "admin.api.instances", want: false},
+		{include: []string{"admin"}, exclude: []string{"admin.api"}, name: "admin.other", want: true},
+		{include: []string{"admin.api.instances"}, exclude: []string{"admin"}, name: "admin.api.instances", want: true},
+		{include: []string{"admin.api.instances"}, exclude: []string{"admin"}, name: "admin.api", want: false},
+	} {
+		cl := &CustomLog{Include: tc.include, Exclude: tc.exclude}
+		if got := cl.loggerAllowed(tc.name); got != tc.want {
+			t.Errorf("include %v, exclude %v: loggerAllowed(%q) = %t, want %t", tc.include, tc.exclude, tc.name, got, tc.want)
+		}
+	}
+
+	cl := &CustomLog{Include: []string{"admin"}, Exclude: []string{"admin"}}
+	if err := cl.Provision(Context{}); err == nil {
+		t.Error("expected error for intersecting include and exclude")
+	}
+}
+
+func TestCustomLogLoggerLevel(t *testing.T) {
+	t.Parallel()
+
+	cl := &CustomLog{
+		BaseLog: BaseLog{Level: "WARN", WriterRaw: json.RawMessage(`{"output":"discard"}`)},
+		Levels:  map[string]string{"admin": "ERROR", "admin.api": "debug"},
+	}
+	ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}})
+	defer cancel()
+	if err := cl.Provision(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Cleanup()
+
+	for name, want := range map[string]zapcore.Level{
+		"":                    zapcore.WarnLevel,
+		"adminer":             zapcore.WarnLevel,
+		"admin":               zapcore.ErrorLevel,
+		"admin.other":         zapcore.ErrorLevel,
+		"admin.api":           zapcore.DebugLevel,
+		"admin.api.instances": zapcore.DebugLevel,
+	} {
+		if got := cl.loggerLevel(name); got != want {
+			t.Errorf("loggerLevel(%q) = %s, want %s", name, got, want)
+		}
+	}
+
+	cl = &CustomLog{Levels: map[string]string{"admin": "loud"}}
+	if err := cl.Provision(ctx); err == nil {
+		t.Error("expected error for unrecognized level")
+	}
+}
+
+// testLogWriter writes to the buffer of its name in
+// testLogBuffers.
+type testLogWriter struct {
+	Name string `json:"name,omitempty"`
+}
+
+func (testLogWriter) UniModule() ModuleInfo {
+	return ModuleInfo{
+		ID:      "uni.logging.writers.test",
+		Aliases: []ModuleID{"uni.logging.writers.test_old"},
+		New:     func() Module { return new(testLogWriter) },
+	}
+}
+
+func (w testLogWriter) String() string   { return "test " + w.Name }
+func (w testLogWriter) WriterID() string { return "test:" + w.Name }
+func (w testLogWriter) OpenWriter() (io.WriteCloser, error) {
+	testLogBuffers.Lock()
+	defer testLogBuffers.Unlock()
+	return notClosable{testLogBuffers.m[w.Name]}, nil
+}
+
+var testLogBuffers struct {
+	sync.Mutex
+	m map[string]*bytes.Buffer
+}
+
+// not parallel: Run uses the default registry and logger
+func TestRunLogs(t *testing.T) {
+	origRegistry := defaultRegistry
+	defaultRegistry = NewRegistry()
+	defer func() { defaultRegistry = origRegistry }()
+	RegisterModule(testLogWriter{})
+
+	core, prev := observer.New(zapcore.DebugLevel)
+	defaultLoggerMu.Lock()
+	saved := defaultLogger.logger
+	defaultLogger.logger = zap.New(core)
+	defaultLoggerMu.Unlock()
+	defer func() {
+		defaultLoggerMu.Lock()
+		defaultLogger.logger = saved
+		defaultLoggerMu.Unlock()
+	}()
+
+	testLogBuffers.m = map[string]*bytes.Buffer{"http": new(bytes.Buffer), "other": new(bytes.Buffer)}
+
+	// only the entries of this test are expected in the logs
+	checkModulesOnce.Do(func() {})
+
+	// the deprecated writer ID is warned about before the logs
+	// are installed, so the warning is buffered until they are
+	warnedDeprecations.Delete("uni.logging.writers.test_old")
+
+	cfg := new(Config)
+	err := json.Unmarshal([]byte(`{
+		"logging": {
+			"logs": {
+				"http": {
+					"writer": {"output": "test", "name": "http"},
+					"level": "WARN",
+					"levels": {"http.handlers": "DEBUG"},
+					"include": ["http"]
+				},
+				"other": {
+					"writer": {"output": "test_old", "name": "other"},
+					"exclude": ["http"]
+				}
+			}
+		}
+	}`), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Log().Named("http.handlers").Debug("handler debug")
+	Log().Named("http").Info("http info")
+	Log().Named("http").Warn("http warn")
+	Log().Named("tls").Debug("tls debug")
+	Log().Named("tls").Info("tls info")
+
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	Log().Named("tls").Info("after close")
+
+	written := func(name string) []string {
+		var msgs []string
+		sc := bufio.NewScanner(bytes.NewReader(testLogBuffers.m[name].Bytes()))
+		for sc.Scan() {
+			var entry struct{ Msg string }
+			if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+				t.Fatalf("%s log: %v", name, err)
+			}
+			msgs = append(msgs, entry.Msg)
+		}
+		return msgs
+	}
+	if got, want := written("http"), []string{"handler debug", "http warn"}; !slices.Equal(got, want) {
+		t.Errorf("http log got %q, want %q", got, want)
+	}
+	if got, want := written("other"), []string{"module ID is deprecated", "tls info"}; !slices.Equal(got, want) {
+		t.Errorf("other log got %q, want %q", got, want)
+	}
+
+	// the logs are teed to the previous default logger,
+	// which is restored once the config stops
+	var got []string
+	for _, e := range prev.All() {
+		got = append(got, e.Message)
+	}
+	want := []string{"module ID is deprecated", "handler debug", "http info", "http warn", "tls debug", "tls info", "after close"}
+	if !slices.Equal(got, want) {
+		t.Errorf("previous default logger got %q, want %q", got, want)
+	}
+}
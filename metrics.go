@@ -13,3 +13,44 @@ var globalMetrics = struct {
 	configSuccess     prometheus.Gauge
 	configSuccessTime prometheus.Gauge
 }{}
+
+// moduleMetrics is a collection of metrics that can be tracked for loading modules.
+var moduleMetrics = struct {
+	provisionDuration *prometheus.HistogramVec
+}{}
+
+func init() {
+	const ns, sub = "uni", "admin"
+
+	adminMetrics.requestCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "http_requests_total",
+		Help:      "Counter of requests made to the Admin API's HTTP endpoints.",
+	}, []string{"handler", "path", "code", "method"})
+	adminMetrics.requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "http_request_errors_total",
+		Help:      "Number of requests resulting in middleware errors.",
+	}, []string{"handler", "path", "method"})
+
+	globalMetrics.configSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: ns,
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last configuration reload attempt was successful.",
+	})
+	globalMetrics.configSuccessTime = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: ns,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful configuration reload.",
+	})
+
+	moduleMetrics.provisionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: ns,
+		Subsystem: "module",
+		Name:      "provision_duration_seconds",
+		Help:      "Time spent in the Provision and Validate methods of modules.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"module", "phase"})
+}
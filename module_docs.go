@@ -0,0 +1,246 @@
+package uni
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ModuleDoc documents a registered module.
+type ModuleDoc struct {
+	ID        string `json:"id"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Aliases are the other IDs of the module, and
+	// Deprecated is set if the module is deprecated.
+	Aliases    []string           `json:"aliases,omitempty"`
+	Deprecated *ModuleDeprecation `json:"deprecated,omitempty"`
+
+	// Package is the import path of the module's Go package,
+	// and Type its Go type.
+	Package string `json:"package"`
+	Type    string `json:"type"`
+
+	// Description is the doc comment of the module's type,
+	// if doc comments are available.
+	Description string `json:"description,omitempty"`
+
+	// Fields are the config fields of the module.
+	Fields []ModuleFieldDoc `json:"fields,omitempty"`
+
+	// Implements lists the well-known interfaces, and the
+	// interfaces of registered namespaces, that the module
+	// implements.
+	Implements []string `json:"implements,omitempty"`
+
+	// LoadedBy lists the fields that can load the module.
+	LoadedBy []ModuleHostField `json:"loaded_by,omitempty"`
+}
+
+// ModuleFieldDoc documents a config field of a module.
+type ModuleFieldDoc struct {
+	// Name is the name of the field in JSON.
+	Name string `json:"name"`
+
+	// Type is the Go type of the field.
+	Type string `json:"type"`
+
+	// Default is the default value, as given by a
+	// "Default: ..." sentence in the doc comment.
+	Default string `json:"default,omitempty"`
+
+	// Namespace and InlineKey are set for fields that
+	// hold modules.
+	Namespace string `json:"namespace,omitempty"`
+	InlineKey string `json:"inline_key,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// ModuleHostField is a field which loads modules.
+type ModuleHostField struct {
+	// Host is the ID of the module the field belongs to
+	// or, if the field is not part of a module, the Go type
+	// of the struct it belongs to.
+	Host string `json:"host"`
+
+	// Field is the name of the field in JSON.
+	Field string `json:"field"`
+
+	// InlineKey is the key holding the module name, if the
+	// module name is not the key of a module map.
+	InlineKey string `json:"inline_key,omitempty"`
+}
+
+// DocumentModule returns the documentation of the module with
+// the given ID. Descriptions and defaults are taken from docs,
+// as returned by LoadDocComments, which may be nil.
+func DocumentModule(id string, docs map[string]string) (ModuleDoc, error) {
+	mi, err := GetModule(id)
+	if err != nil {
+		return ModuleDoc{}, err
+	}
+
+	val := mi.New()
+	typ := reflect.TypeOf(val)
+	structType := typ
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+
+	md := ModuleDoc{
+		ID:          string(mi.ID),
+		Namespace:   mi.ID.Namespace(),
+		Name:        mi.ID.Name(),
+		Package:     structType.PkgPath(),
+		Type:        structType.String(),
+		Description: docs[typeDocKey(structType)],
+		Deprecated:  mi.Deprecated,
+	}
+	for _, alias := range mi.Aliases {
+		md.Aliases = append(md.Aliases, string(alias))
+	}
+
+	if structType.Kind() == reflect.Struct {
+		for _, f := range structJSONFields(structType) {
+			fd := ModuleFieldDoc{
+				Name:        f.name,
+				Type:        f.field.Type.String(),
+				Description: docs[typeDocKey(f.owner)+"."+f.field.Name],
+			}
+			fd.Default = docDefault(fd.Description)
+			if tag, ok := moduleTag(f.field); ok {
+				if opts, err := ParseStructTag(tag); err == nil {
+					fd.Namespace, fd.InlineKey = opts["namespace"], opts["inline_key"]
+				}
+			}
+			md.Fields = append(md.Fields, fd)
+		}
+	}
+
+	for _, iface := range documentedInterfaces() {
+		if typ.Implements(iface) || reflect.PointerTo(typ).Implements(iface) {
+			md.Implements = append(md.Implements, iface.String())
+		}
+	}
+
+	hosts := make(map[reflect.Type]string)
+	for _, hostID := range Modules() {
+		if hmi, err := GetModule(hostID); err == nil {
+			ht := reflect.TypeOf(hmi.New())
+			for ht.Kind() == reflect.Pointer {
+				ht = ht.Elem()
+			}
+			hosts[ht] = hostID
+		}
+	}
+	walkModuleFields(func(host reflect.Type, field reflect.StructField, tag string) {
+		opts, err := ParseStructTag(tag)
+		if err != nil || opts["namespace"] != md.Namespace {
+			return
+		}
+		hf := ModuleHostField{
+			Host:      hosts[host],
+			Field:     jsonFieldName(field),
+			InlineKey: opts["inline_key"],
+		}
+		if hf.Host == "" {
+			hf.Host = host.String()
+		}
+		md.LoadedBy = append(md.LoadedBy, hf)
+	})
+	slices.SortFunc(md.LoadedBy, func(a, b ModuleHostField) int {
+		return strings.Compare(a.Host+"."+a.Field, b.Host+"."+b.Field)
+	})
+
+	return md, nil
+}
+
+// documentedInterfaces returns the interfaces that
+// DocumentModule checks modules for, sorted by name.
+func documentedInterfaces() []reflect.Type {
+	ifaces := []reflect.Type{
+		reflect.TypeFor[Provisioner](),
+		reflect.TypeFor[Validator](),
+		reflect.TypeFor[CleanerUpper](),
+		reflect.TypeFor[App](),
+		reflect.TypeFor[AdminRouter](),
+	}
+
+	moduleNamespacesMu.RLock()
+	for _, iface := range moduleNamespaces {
+		if !slices.Contains(ifaces, iface) {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	moduleNamespacesMu.RUnlock()
+
+	slices.SortFunc(ifaces, func(a, b reflect.Type) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return ifaces
+}
+
+// jsonField is a field of a struct as it appears in JSON.
+type jsonField struct {
+	// owner is the struct the field is declared in, which
+	// differs from the outer struct for embedded fields
+	owner reflect.Type
+	field reflect.StructField
+	name  string
+}
+
+// structJSONFields returns the fields of the struct type typ
+// that are encoded in JSON, including the ones of embedded
+// structs.
+func structJSONFields(typ reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && !strings.Contains(string(field.Tag), `json:"`) && fieldType.Kind() == reflect.Struct {
+			fields = append(fields, structJSONFields(fieldType)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, jsonField{owner: typ, field: field, name: name})
+	}
+	return fields
+}
+
+// jsonFieldName returns the name of field in JSON, or
+// "-" if it is not encoded.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name
+}
+
+// docDefault returns the default value stated in a doc
+// comment as "Default: <value>", if any.
+func docDefault(doc string) string {
+	_, after, ok := strings.Cut(doc, "Default: ")
+	if !ok {
+		return ""
+	}
+	if i := strings.IndexAny(after, "\n"); i >= 0 {
+		after = after[:i]
+	}
+	if i := strings.Index(after, ". "); i >= 0 {
+		after = after[:i]
+	}
+	return strings.TrimSuffix(strings.TrimSpace(after), ".")
+}
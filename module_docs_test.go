@@ -0,0 +1,109 @@
+package uni
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// docsTestWriter is a module in a namespace with a registered
+// interface, which is loaded by docsTestHost.
+type docsTestWriter struct {
+	Path string `json:"path,omitempty"`
+}
+
+func (docsTestWriter) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "docs_test.writers.file", New: func() Module { return new(docsTestWriter) }}
+}
+
+func (*docsTestWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (*docsTestWriter) Provision(Context) error     { return nil }
+
+type docsTestHost struct {
+	WriterRaw json.RawMessage `json:"writer,omitempty" uni:"namespace=docs_test.writers inline_key=output"`
+}
+
+func (docsTestHost) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "docs_test.host", New: func() Module { return new(docsTestHost) }}
+}
+
+func TestDocumentModule(t *testing.T) {
+	RegisterModule(docsTestWriter{})
+	RegisterModule(docsTestHost{})
+	moduleNamespacesMu.Lock()
+	moduleNamespaces["docs_test.writers"] = reflect.TypeFor[io.Writer]()
+	moduleNamespacesMu.Unlock()
+	defer func() {
+		defaultRegistry.mu.Lock()
+		delete(defaultRegistry.modules, "docs_test.writers.file")
+		delete(defaultRegistry.modules, "docs_test.host")
+		defaultRegistry.mu.Unlock()
+		moduleNamespacesMu.Lock()
+		delete(moduleNamespaces, "docs_test.writers")
+		moduleNamespacesMu.Unlock()
+	}()
+
+	docs := map[string]string{
+		"github.com/yonomesh/uni.docsTestWriter":      "docsTestWriter writes nowhere.",
+		"github.com/yonomesh/uni.docsTestWriter.Path": "The file to write to. Default: out.log. Relative paths are fine.",
+	}
+	md, err := DocumentModule("docs_test.writers.file", docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if md.Namespace != "docs_test.writers" || md.Name != "file" ||
+		md.Package != "github.com/yonomesh/uni" || md.Type != "uni.docsTestWriter" {
+		t.Errorf("DocumentModule() = %+v", md)
+	}
+	if md.Description != "docsTestWriter writes nowhere." {
+		t.Errorf("Description = %q", md.Description)
+	}
+	if len(md.Fields) != 1 || md.Fields[0].Name != "path" || md.Fields[0].Type != "string" || md.Fields[0].Default != "out.log" {
+		t.Errorf("Fields = %+v", md.Fields)
+	}
+	if !slices.Contains(md.Implements, "io.Writer") || !slices.Contains(md.Implements, "uni.Provisioner") ||
+		slices.Contains(md.Implements, "uni.Validator") {
+		t.Errorf("Implements = %v", md.Implements)
+	}
+	want := ModuleHostField{Host: "docs_test.host", Field: "writer", InlineKey: "output"}
+	if !slices.Equal(md.LoadedBy, []ModuleHostField{want}) {
+		t.Errorf("LoadedBy = %+v, want %+v", md.LoadedBy, want)
+	}
+
+	host, err := DocumentModule("docs_test.host", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := host.Fields[0]; f.Namespace != "docs_test.writers" || f.InlineKey != "output" {
+		t.Errorf("module field = %+v", f)
+	}
+
+	if _, err := DocumentModule("docs_test.nope", nil); err == nil {
+		t.Error("expected error for unknown module")
+	}
+
+	// admin endpoint
+	ad := new(adminDocs)
+	req := httptest.NewRequest(http.MethodGet, "/docs/docs_test.writers.file", nil)
+	req.SetPathValue("id", "docs_test.writers.file")
+	rec := httptest.NewRecorder()
+	if err := ad.handleModule(rec, req); err != nil {
+		t.Fatal(err)
+	}
+	var served ModuleDoc
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil || served.ID != "docs_test.writers.file" {
+		t.Errorf("handleModule() served %+v, %v", served, err)
+	}
+
+	req.SetPathValue("id", "docs_test.nope")
+	var apiErr APIError
+	if err := ad.handleModule(httptest.NewRecorder(), req); !errors.As(err, &apiErr) || apiErr.HTTPStatus != http.StatusNotFound {
+		t.Errorf("handleModule() for unknown module: %v", err)
+	}
+}
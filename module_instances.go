@@ -0,0 +1,224 @@
+package uni
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ModuleInstance describes a module instance that was loaded
+// by a Context, as part of the tree returned by
+// ModuleInstances.
+type ModuleInstance struct {
+	// ID is the module's ID, and Instance a number that is
+	// unique to this instance of it.
+	ID       string `json:"id"`
+	Instance uint64 `json:"instance"`
+
+	// Parent is the Instance number of the module which
+	// loaded this one, or 0 if it was loaded by the core.
+	Parent uint64 `json:"parent,omitempty"`
+
+	// ConfigPointer is the JSON pointer of the instance's
+	// config, if known (see Context.WithConfigPointer).
+	ConfigPointer string `json:"config_pointer,omitempty"`
+
+	// LoadedAt is when the instance started provisioning,
+	// and ProvisionDuration how long it took to provision
+	// and validate it, including its own submodules. It
+	// is 0 while the instance is still provisioning.
+	LoadedAt          time.Time     `json:"loaded_at"`
+	ProvisionDuration time.Duration `json:"provision_duration"`
+
+	// Error is the error provisioning or validating the
+	// instance returned, if any.
+	Error string `json:"error,omitempty"`
+
+	// CleanedUp is true once the instance is done with,
+	// because its context was canceled or it failed to
+	// provision, and its Cleanup method, if any, has run.
+	// CleanupError is what Cleanup returned.
+	CleanedUp    bool   `json:"cleaned_up"`
+	CleanupError string `json:"cleanup_error,omitempty"`
+
+	// Children are the instances loaded while provisioning
+	// this one, or with a context derived from one that
+	// was.
+	Children []ModuleInstance `json:"children,omitempty"`
+}
+
+// ModuleInstances returns the tree of module instances that are
+// alive, in the order they were loaded. An instance is alive
+// until it is cleaned up; it stays in the tree after that as
+// long as any of its children are alive, which makes instances
+// that outlive their parent, and thus probably leak, easy to
+// spot.
+func ModuleInstances() []ModuleInstance {
+	instanceTreeMu.Lock()
+	defer instanceTreeMu.Unlock()
+	return snapshotInstances(instanceRoots)
+}
+
+var (
+	// instanceTreeMu protects the tree of instanceNodes,
+	// including the mutable fields of every node
+	instanceTreeMu sync.Mutex
+
+	// instanceRoots are the instances loaded by the core
+	instanceRoots = make(map[*instanceNode]struct{})
+
+	// lastInstance numbers the instances
+	lastInstance atomic.Uint64
+)
+
+// instanceNode is a module instance in the tree of instances.
+type instanceNode struct {
+	module         Module
+	num            uint64
+	id             string
+	pointer        string
+	loaded         time.Time
+	parent         *instanceNode
+	cleanupTimeout time.Duration
+	traceID        TraceID
+	spanID         SpanID
+
+	provisionDuration time.Duration
+	childrenDuration  time.Duration
+	err               error
+	cleanedUp         bool
+	cleanupErr        error
+	children          map[*instanceNode]struct{}
+}
+
+// newInstanceNode adds a node for an instance of the module id,
+// loaded at the given config pointer, to the tree below parent,
+// which may be nil.
+func newInstanceNode(parent *instanceNode, id, pointer string) *instanceNode {
+	node := &instanceNode{
+		num:      lastInstance.Add(1),
+		id:       id,
+		pointer:  pointer,
+		loaded:   time.Now(),
+		parent:   parent,
+		children: make(map[*instanceNode]struct{}),
+		spanID:   newSpanID(),
+	}
+	if parent != nil {
+		node.traceID = parent.traceID
+	} else {
+		node.traceID = newTraceID()
+	}
+
+	instanceTreeMu.Lock()
+	defer instanceTreeMu.Unlock()
+	if parent != nil {
+		parent.children[node] = struct{}{}
+	} else {
+		instanceRoots[node] = struct{}{}
+	}
+	return node
+}
+
+// provisioned records the outcome of provisioning the instance,
+// and returns how much of the time d was spent provisioning its
+// children. If provisioning failed, the instance was cleaned up
+// by the caller already.
+func (node *instanceNode) provisioned(d time.Duration, err error) time.Duration {
+	instanceTreeMu.Lock()
+	defer instanceTreeMu.Unlock()
+	node.provisionDuration = d
+	node.err = err
+	if node.parent != nil {
+		node.parent.childrenDuration += d
+	}
+	if err != nil {
+		node.cleanedUp = true
+		node.prune()
+	}
+	return node.childrenDuration
+}
+
+// cleanup runs the Cleanup method of the instance, if any, and
+// records that the instance is done with. If Cleanup does not
+// return within the cleanup timeout of the instance, it is left
+// running and an error is returned.
+func (node *instanceNode) cleanup() error {
+	var err error
+	if cu, ok := node.module.(CleanerUpper); ok {
+		err = runWithTimeout(cu.Cleanup, node.cleanupTimeout)
+	}
+
+	instanceTreeMu.Lock()
+	defer instanceTreeMu.Unlock()
+	node.cleanedUp = true
+	node.cleanupErr = err
+	node.prune()
+	return err
+}
+
+// runWithTimeout returns the result of f, or an error if f does
+// not return within timeout. A timeout of 0 or less means no limit.
+func runWithTimeout(f func() error, timeout time.Duration) error {
+	if timeout <= 0 {
+		return f()
+	}
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// prune removes node from the tree if it is cleaned up and has
+// no children left, and its parent too if that makes it
+// removable. instanceTreeMu must be locked.
+func (node *instanceNode) prune() {
+	for ; node != nil && node.cleanedUp && len(node.children) == 0; node = node.parent {
+		if node.parent != nil {
+			delete(node.parent.children, node)
+		} else {
+			delete(instanceRoots, node)
+		}
+	}
+}
+
+// snapshotInstances returns the ModuleInstances of nodes,
+// ordered by number. instanceTreeMu must be locked.
+func snapshotInstances(nodes map[*instanceNode]struct{}) []ModuleInstance {
+	var list []ModuleInstance
+	for node := range nodes {
+		mi := ModuleInstance{
+			ID:                node.id,
+			Instance:          node.num,
+			ConfigPointer:     node.pointer,
+			LoadedAt:          node.loaded,
+			ProvisionDuration: node.provisionDuration,
+			CleanedUp:         node.cleanedUp,
+			Children:          snapshotInstances(node.children),
+		}
+		if node.parent != nil {
+			mi.Parent = node.parent.num
+		}
+		if node.err != nil {
+			mi.Error = node.err.Error()
+		}
+		if node.cleanupErr != nil {
+			mi.CleanupError = node.cleanupErr.Error()
+		}
+		list = append(list, mi)
+	}
+	slices.SortFunc(list, func(a, b ModuleInstance) int {
+		return cmp.Compare(a.Instance, b.Instance)
+	})
+	return list
+}
@@ -0,0 +1,115 @@
+package uni
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type instancesTestChild struct{}
+
+func (instancesTestChild) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "instances_test.children.child", New: func() Module { return new(instancesTestChild) }}
+}
+
+func (*instancesTestChild) Cleanup() error { return nil }
+
+type instancesTestParent struct {
+	ChildRaw json.RawMessage `json:"child,omitempty" uni:"namespace=instances_test.children inline_key=name"`
+
+	// leak, if set, makes the parent load a second child
+	// with a context that is never canceled
+	Leak bool `json:"leak,omitempty"`
+}
+
+func (instancesTestParent) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "instances_test.parent", New: func() Module { return new(instancesTestParent) }}
+}
+
+func (p *instancesTestParent) Provision(ctx Context) error {
+	if _, err := ctx.LoadModule(p, "ChildRaw"); err != nil {
+		return err
+	}
+	if p.Leak {
+		leakCtx, _ := NewContext(ctx)
+		if _, err := leakCtx.LoadModuleByID("instances_test.children.child", nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findInstance returns the instance with the given ID in the
+// tree, which is shared with other tests.
+func findInstance(list []ModuleInstance, id string) *ModuleInstance {
+	for i := range list {
+		if list[i].ID == id {
+			return &list[i]
+		}
+		if found := findInstance(list[i].Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestModuleInstances(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterModule(instancesTestChild{})
+	r.RegisterModule(instancesTestParent{})
+
+	ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+	_, err := ctx.WithConfigPointer("parent").LoadModuleByID("instances_test.parent",
+		json.RawMessage(`{"child": {"name": "child"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := findInstance(ModuleInstances(), "instances_test.parent")
+	if parent == nil {
+		t.Fatal("parent instance not in the tree")
+	}
+	if parent.Parent != 0 || parent.ConfigPointer != "/parent" || parent.CleanedUp || parent.ProvisionDuration <= 0 {
+		t.Errorf("parent = %+v", parent)
+	}
+	if len(parent.Children) != 1 {
+		t.Fatalf("parent has %d children, want 1", len(parent.Children))
+	}
+	if child := parent.Children[0]; child.ID != "instances_test.children.child" ||
+		child.Parent != parent.Instance || child.ConfigPointer != "/parent/child" {
+		t.Errorf("child = %+v", child)
+	}
+
+	// the admin endpoint serves the same tree
+	rec := httptest.NewRecorder()
+	if err := new(adminInstances).handleInstances(rec, httptest.NewRequest(http.MethodGet, "/instances", nil)); err != nil {
+		t.Fatal(err)
+	}
+	var served []ModuleInstance
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil || findInstance(served, "instances_test.parent") == nil {
+		t.Errorf("handleInstances() served %+v, %v", served, err)
+	}
+
+	cancel()
+	if found := findInstance(ModuleInstances(), "instances_test.parent"); found != nil {
+		t.Errorf("instance still in the tree after cleanup: %+v", found)
+	}
+
+	// a child whose context is never canceled keeps its
+	// cleaned up parent in the tree
+	ctx, cancel = NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+	_, err = ctx.LoadModuleByID("instances_test.parent", json.RawMessage(`{"child": {"name": "child"}, "leak": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	parent = findInstance(ModuleInstances(), "instances_test.parent")
+	if parent == nil || !parent.CleanedUp || len(parent.Children) != 1 {
+		t.Fatalf("leaking parent = %+v", parent)
+	}
+	if child := parent.Children[0]; child.CleanedUp {
+		t.Errorf("leaked child = %+v", child)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Module is a type that is used as a Uni module. In
@@ -65,6 +66,58 @@ type ModuleInfo struct {
 	// in a Provision() method (see the
 	// Provisioner interface).
 	New func() Module
+
+	// Aliases are other IDs the module can be loaded by,
+	// usually the IDs it had before it was renamed. They
+	// share the module ID space, and using them is
+	// deprecated.
+	Aliases []ModuleID
+
+	// Deprecated, if set, marks the module itself as
+	// deprecated. A warning is logged the first time
+	// it is loaded.
+	Deprecated *ModuleDeprecation
+
+	// Migrate, if set, rewrites the raw config of the module,
+	// which was loaded by the given ID (the module's ID or
+	// one of its aliases), to the shape the module decodes.
+	// It is called before every non-empty config is decoded,
+	// so it must recognize configs which need no changes.
+	Migrate func(id ModuleID, raw json.RawMessage) (json.RawMessage, error)
+
+	// CleanupTimeout is how long the Cleanup method of the
+	// module may take when its context is closed, before
+	// the context moves on to clean up other modules. If 0,
+	// DefaultCleanupTimeout applies; if negative, there is
+	// no limit.
+	CleanupTimeout time.Duration
+}
+
+// DefaultCleanupTimeout is the CleanupTimeout of modules
+// which do not set their own.
+const DefaultCleanupTimeout = 10 * time.Second
+
+// ModuleDeprecation describes why a module is deprecated.
+type ModuleDeprecation struct {
+	// Message tells users what to do instead, for
+	// example which module to use.
+	Message string `json:"message,omitempty"`
+
+	// RemovedIn is the version in which the module
+	// will be removed, if planned.
+	RemovedIn string `json:"removed_in,omitempty"`
+}
+
+// String returns the deprecation as a sentence.
+func (d ModuleDeprecation) String() string {
+	s := "deprecated"
+	if d.RemovedIn != "" {
+		s += " and will be removed in " + d.RemovedIn
+	}
+	if d.Message != "" {
+		s += ": " + d.Message
+	}
+	return s
 }
 
 func (mi ModuleInfo) String() string {
@@ -124,10 +177,40 @@ func (id ModuleID) Name() string {
 // Note json.RawMessage 是一种 “保留 JSON 原文以便稍后再解析” 的机制。 适合在结构不固定、或需要动态决定解析方式的场景。
 type ModuleMap map[string]json.RawMessage
 
-var (
-	modules   = make(map[string]ModuleInfo)
-	modulesMu sync.RWMutex
-)
+// Registry is a set of registered modules. Most programs use
+// the default registry, through RegisterModule and the other
+// package-level functions; a separate registry is useful to
+// embed several instances with different sets of modules, and
+// for tests which register modules of their own. Contexts load
+// modules from the registry they are bound to (see
+// Context.WithRegistry).
+//
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	modules map[string]ModuleInfo
+
+	// aliases maps the aliases of modules to their IDs
+	aliases map[string]ModuleID
+}
+
+// NewRegistry returns a new, empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		modules: make(map[string]ModuleInfo),
+		aliases: make(map[string]ModuleID),
+	}
+}
+
+// defaultRegistry holds the modules registered with RegisterModule.
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry which RegisterModule,
+// GetModule, GetModules and Modules operate on, and which
+// contexts use unless they are bound to another one.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
 
 // RegisterModule registers a module by receiving a
 // plain/empty value of the module. For registration to
@@ -135,9 +218,15 @@ var (
 // init phase of runtime. Typically, the module package
 // will do this as a side-effect of being imported.
 // This function panics if the module's info is
-// incomplete or invalid, or if the module is already
-// registered.
+// incomplete or invalid, or if the module or one of its
+// aliases is already registered.
 func RegisterModule(instance Module) {
+	defaultRegistry.RegisterModule(instance)
+}
+
+// RegisterModule registers a module in r, like the
+// package-level RegisterModule does in the default registry.
+func (r *Registry) RegisterModule(instance Module) {
 	mi := instance.UniModule()
 
 	if mi.ID == "" {
@@ -152,22 +241,60 @@ func RegisterModule(instance Module) {
 	if val := mi.New(); val == nil {
 		panic("ModuleInfo.New must return a non-nil module instance")
 	}
-	modulesMu.Lock()
-	defer modulesMu.Unlock()
-	if _, ok := modules[string(mi.ID)]; ok {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.taken(string(mi.ID)) {
 		panic(fmt.Sprintf("module already registered: %s", mi.ID))
 	}
-	modules[string(mi.ID)] = mi
+	for _, alias := range mi.Aliases {
+		if alias == mi.ID || r.taken(string(alias)) {
+			panic(fmt.Sprintf("module %s: alias already registered: %s", mi.ID, alias))
+		}
+	}
+	r.modules[string(mi.ID)] = mi
+	for _, alias := range mi.Aliases {
+		r.aliases[string(alias)] = mi.ID
+	}
+}
+
+// taken returns whether id is the ID or an alias
+// of a registered module. r.mu must be locked.
+func (r *Registry) taken(id string) bool {
+	_, isModule := r.modules[id]
+	_, isAlias := r.aliases[id]
+	return isModule || isAlias
+}
+
+// lookup returns the module with the given ID or alias,
+// and whether id is an alias.
+func (r *Registry) lookup(id string) (ModuleInfo, bool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if m, ok := r.modules[id]; ok {
+		return m, false, true
+	}
+	if canonical, ok := r.aliases[id]; ok {
+		m, ok := r.modules[string(canonical)]
+		return m, true, ok
+	}
+	return ModuleInfo{}, false, false
 }
 
 // Modules returns the names of all registered modules
-// in ascending lexicographical order.
+// in ascending lexicographical order. Aliases are not
+// included.
 func Modules() []string {
-	modulesMu.RLock()
-	defer modulesMu.RUnlock()
+	return defaultRegistry.Modules()
+}
+
+// Modules is like the package-level Modules for the
+// modules registered in r.
+func (r *Registry) Modules() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	names := make([]string, 0, len(modules))
-	for name := range modules {
+	names := make([]string, 0, len(r.modules))
+	for name := range r.modules {
 		names = append(names, name)
 	}
 
@@ -176,11 +303,16 @@ func Modules() []string {
 	return names
 }
 
-// GetModule returns module information from its ID (full name).
+// GetModule returns module information from its ID (full name)
+// or one of its aliases.
 func GetModule(name string) (ModuleInfo, error) {
-	modulesMu.RLock()
-	defer modulesMu.RUnlock()
-	m, ok := modules[name]
+	return defaultRegistry.GetModule(name)
+}
+
+// GetModule is like the package-level GetModule for the
+// modules registered in r.
+func (r *Registry) GetModule(name string) (ModuleInfo, error) {
+	m, _, ok := r.lookup(name)
 	if !ok {
 		return ModuleInfo{}, fmt.Errorf("module not registered: %s", name)
 	}
@@ -197,8 +329,14 @@ func GetModule(name string) (ModuleInfo, error) {
 // Because modules are registered to a map under the hood, the
 // returned slice will be sorted to keep it deterministic.
 func GetModules(scope string) []ModuleInfo {
-	modulesMu.RLock()
-	defer modulesMu.RUnlock()
+	return defaultRegistry.GetModules(scope)
+}
+
+// GetModules is like the package-level GetModules for the
+// modules registered in r.
+func (r *Registry) GetModules(scope string) []ModuleInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
 	scopeParts := strings.Split(scope, ".")
 
@@ -210,7 +348,7 @@ func GetModules(scope string) []ModuleInfo {
 
 	var mods []ModuleInfo
 iterateModules:
-	for id, m := range modules {
+	for id, m := range r.modules {
 		modParts := strings.Split(id, ".")
 
 		// match only the next level of nesting
@@ -290,9 +428,9 @@ type CleanerUpper interface {
 	Cleanup() error
 }
 
-// ParseStructTag parses a kaze struct tag into its keys and values.
+// ParseStructTag parses a uni struct tag into its keys and values.
 // It is very simple. The expected syntax is:
-// `kaze:"key1=val1 key2=val2 ..."`
+// `uni:"key1=val1 key2=val2 ..."`
 func ParseStructTag(tag string) (map[string]string, error) {
 	results := make(map[string]string)
 	pairs := strings.Split(tag, " ")
@@ -312,13 +450,14 @@ func ParseStructTag(tag string) (map[string]string, error) {
 // StrictUnmarshalJSON is like json.Unmarshal but returns an error
 // if any of the fields are unrecognized. Useful when decoding
 // module configurations, where you want to be more sure they're
-// correct.
+// correct. Errors about the document, rather than about v, are
+// returned as a *ConfigError locating the problem in data.
 func StrictUnmarshalJSON(data []byte, v any) error {
 	dec := json.NewDecoder(bytes.NewReader(data))
 	dec.DisallowUnknownFields()
 	err := dec.Decode(v)
-	if jsonErr, ok := err.(*json.SyntaxError); ok {
-		return fmt.Errorf("%w, at offset %d", jsonErr, jsonErr.Offset)
+	if ce := newDecodeConfigError(data, v, err); ce != nil {
+		return ce
 	}
 	return err
 }
@@ -0,0 +1,166 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yonomesh/uni"
+	"github.com/yonomesh/uni/internal"
+)
+
+func init() {
+	uni.RegisterModule(adminLogs{})
+}
+
+// adminLogs is a module that provides the /logs/recent
+// endpoint for querying the entries retained by ring
+// buffer cores.
+type adminLogs struct{}
+
+// UniModule returns the Uni module information.
+func (adminLogs) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:  "admin.api.logs",
+		New: func() uni.Module { return new(adminLogs) },
+	}
+}
+
+// Routes returns the admin routes for the logs API.
+func (al *adminLogs) Routes() []uni.AdminRoute {
+	return []uni.AdminRoute{
+		{
+			Pattern: "GET /logs/recent",
+			Handler: uni.AdminHandlerFunc(al.handleRecent),
+		},
+	}
+}
+
+// handleRecent responds with the entries of a ring buffer
+// as a JSON array, oldest first. The following query
+// parameters are recognized:
+//
+//   - buffer: name of the ring buffer (default "default")
+//   - level: minimum level, e.g. "warn"
+//   - logger: logger name or namespace, e.g. "http"
+//   - since, until: RFC 3339 timestamps, or durations
+//     relative to now such as "5m"
+//   - contains: substring of the message or a field value
+//   - limit: maximum number of (most recent) entries
+func (al *adminLogs) handleRecent(w http.ResponseWriter, r *http.Request) error {
+	params := r.URL.Query()
+
+	name := params.Get("buffer")
+	if name == "" {
+		name = DefaultLoggerName
+	}
+	ringBuffersMu.RLock()
+	ring, ok := ringBuffers[name]
+	ringBuffersMu.RUnlock()
+	if !ok {
+		return uni.APIError{
+			HTTPStatus: http.StatusNotFound,
+			Err:        fmt.Errorf("no ring buffer named '%s'", name),
+		}
+	}
+
+	query, err := parseLogQuery(params.Get, time.Now())
+	if err != nil {
+		return uni.APIError{HTTPStatus: http.StatusBadRequest, Err: err}
+	}
+
+	entries := ring.Query(query)
+	out := make([]recentLogEntry, len(entries))
+	for i, e := range entries {
+		out[i] = newRecentLogEntry(e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(out)
+}
+
+// parseLogQuery builds a query from the parameters
+// returned by get; relative times are based on now.
+func parseLogQuery(get func(string) string, now time.Time) (internal.LogQuery, error) {
+	var q internal.LogQuery
+
+	if lvl := get("level"); lvl != "" {
+		level, err := zapcore.ParseLevel(lvl)
+		if err != nil {
+			return q, fmt.Errorf("invalid level: %s", lvl)
+		}
+		q.MinLevel = level
+	} else {
+		q.MinLevel = zapcore.DebugLevel
+	}
+
+	q.Logger = get("logger")
+	q.Contains = get("contains")
+
+	var err error
+	if q.Since, err = parseQueryTime(get("since"), now); err != nil {
+		return q, fmt.Errorf("invalid since: %v", err)
+	}
+	if q.Until, err = parseQueryTime(get("until"), now); err != nil {
+		return q, fmt.Errorf("invalid until: %v", err)
+	}
+
+	if limit := get("limit"); limit != "" {
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil || q.Limit < 0 {
+			return q, fmt.Errorf("invalid limit: %s", limit)
+		}
+	}
+
+	return q, nil
+}
+
+// parseQueryTime parses s as an RFC 3339 timestamp or
+// as a duration before now. An empty s is the zero time.
+func parseQueryTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	d, err := uni.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 timestamp or duration: %s", s)
+	}
+	return now.Add(-d), nil
+}
+
+// recentLogEntry is the JSON representation of an
+// entry returned by the /logs/recent endpoint.
+type recentLogEntry struct {
+	Time       time.Time      `json:"ts"`
+	Level      string         `json:"level"`
+	Logger     string         `json:"logger,omitempty"`
+	Message    string         `json:"msg"`
+	Caller     string         `json:"caller,omitempty"`
+	Stacktrace string         `json:"stacktrace,omitempty"`
+	Fields     map[string]any `json:"fields,omitempty"`
+}
+
+func newRecentLogEntry(e internal.RecordedLogEntry) recentLogEntry {
+	out := recentLogEntry{
+		Time:       e.Time,
+		Level:      e.Level.String(),
+		Logger:     e.LoggerName,
+		Message:    e.Message,
+		Stacktrace: e.Stack,
+		Fields:     e.FieldMap(),
+	}
+	if e.Caller.Defined {
+		out.Caller = e.Caller.TrimmedPath()
+	}
+	return out
+}
+
+// Interface guard
+var _ uni.AdminRouter = (*adminLogs)(nil)
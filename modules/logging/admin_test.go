@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yonomesh/uni"
+)
+
+func TestAdminLogsRecent(t *testing.T) {
+	rb := &RingBufferCore{Name: "test_recent", Size: 2}
+	if err := rb.Provision(uni.Context{}); err != nil {
+		t.Fatal(err)
+	}
+	defer rb.Cleanup()
+
+	logger := zap.New(rb).Named("http").With(zap.String("server", "srv0"))
+	logger.Debug("first")
+	logger.Info("second", zap.Int("status", 200))
+	logger.Warn("third", zap.Int("status", 500))
+
+	al := new(adminLogs)
+	query := func(uri string) (int, []recentLogEntry) {
+		req := httptest.NewRequest(http.MethodGet, uri, nil)
+		rec := httptest.NewRecorder()
+		err := al.handleRecent(rec, req)
+		if apiErr, ok := err.(uni.APIError); ok {
+			return apiErr.HTTPStatus, nil
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out []recentLogEntry
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		return rec.Code, out
+	}
+
+	code, entries := query("/logs/recent?buffer=test_recent")
+	if code != http.StatusOK || len(entries) != 2 {
+		t.Fatalf("got status %d and %d entries, want 200 and 2", code, len(entries))
+	}
+	if entries[0].Message != "second" || entries[0].Logger != "http" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[0].Fields["server"] != "srv0" || entries[0].Fields["status"] != float64(200) {
+		t.Errorf("expected context and entry fields, got %v", entries[0].Fields)
+	}
+
+	_, entries = query("/logs/recent?buffer=test_recent&level=warn")
+	if len(entries) != 1 || entries[0].Message != "third" {
+		t.Errorf("level filter: got %+v", entries)
+	}
+
+	if code, _ := query("/logs/recent?buffer=nope"); code != http.StatusNotFound {
+		t.Errorf("unknown buffer: got status %d, want 404", code)
+	}
+	if code, _ := query("/logs/recent?buffer=test_recent&since=yesterday"); code != http.StatusBadRequest {
+		t.Errorf("bad since: got status %d, want 400", code)
+	}
+}
+
+func TestRingBufferReplace(t *testing.T) {
+	// the buffer of a new config replaces that of the old
+	// one, which is cleaned up only after the new one runs
+	old := &RingBufferCore{Name: "test_replace"}
+	if err := old.Provision(uni.Context{}); err != nil {
+		t.Fatal(err)
+	}
+	rb := &RingBufferCore{Name: "test_replace"}
+	if err := rb.Provision(uni.Context{}); err != nil {
+		t.Fatalf("provisioning a buffer of the same name: %v", err)
+	}
+	if err := old.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+
+	ringBuffersMu.RLock()
+	ring := ringBuffers["test_replace"]
+	ringBuffersMu.RUnlock()
+	if ring != rb.ring {
+		t.Errorf("buffer was not replaced, or was removed by the old buffer's cleanup")
+	}
+
+	if err := rb.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	ringBuffersMu.RLock()
+	_, ok := ringBuffers["test_replace"]
+	ringBuffersMu.RUnlock()
+	if ok {
+		t.Error("buffer still registered after cleanup")
+	}
+}
+
+func TestParseQueryTime(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	got, err := parseQueryTime("90m", now)
+	if err != nil || !got.Equal(now.Add(-90*time.Minute)) {
+		t.Errorf("relative: got %v, %v", got, err)
+	}
+	got, err = parseQueryTime("2026-01-01T10:00:00Z", now)
+	if err != nil || !got.Equal(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("absolute: got %v, %v", got, err)
+	}
+	if _, err := parseQueryTime("soon", now); err == nil {
+		t.Error("expected error")
+	}
+}
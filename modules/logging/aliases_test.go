@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/yonomesh/uni"
+)
+
+func TestLegacyModuleIDs(t *testing.T) {
+	ctx, cancel := uni.NewContext(uni.Context{Context: context.Background()})
+	defer cancel()
+
+	for _, tc := range []struct {
+		legacy, id, config string
+	}{
+		{"logging.encoders.append", "uni.logging.encoders.append", `{}`},
+		{"logging.cores.mock", "uni.logging.cores.mock", `{}`},
+		{"caddy.logging.encoders.json", "uni.logging.encoders.json", `{}`},
+		{"caddy.logging.encoders.console", "uni.logging.encoders.console", `{}`},
+		{"caddy.logging.encoders.filter", "uni.logging.encoders.filter", `{}`},
+		{"caddy.logging.encoders.filter.delete", "uni.logging.encoders.filter.delete", `{}`},
+		{"caddy.logging.encoders.filter.hash", "uni.logging.encoders.filter.hash", `{}`},
+		{"caddy.logging.encoders.filter.replace", "uni.logging.encoders.filter.replace", `{}`},
+		{"caddy.logging.encoders.filter.ip_mask", "uni.logging.encoders.filter.ip_mask", `{}`},
+		{"caddy.logging.encoders.filter.query", "uni.logging.encoders.filter.query", `{}`},
+		{"caddy.logging.encoders.filter.cookie", "uni.logging.encoders.filter.cookie", `{}`},
+		{"caddy.logging.encoders.filter.regexp", "uni.logging.encoders.filter.regexp", `{}`},
+		{"caddy.logging.encoders.filter.multi_regexp", "uni.logging.encoders.filter.multi_regexp", `{"operations": [{"regexp": "a"}]}`},
+		{"caddy.logging.encoders.filter.rename", "uni.logging.encoders.filter.rename", `{}`},
+		{"caddy.logging.encoders.filter.truncate", "uni.logging.encoders.filter.truncate", `{"max_length": 8}`},
+		{"caddy.logging.encoders.filter.convert", "uni.logging.encoders.filter.convert", `{"type": "int"}`},
+		{"caddy.logging.cores.tee", "uni.logging.cores.tee", `{"cores": [{"writer": {"output": "discard"}}]}`},
+		{"caddy.logging.cores.level_router", "uni.logging.cores.level_router", `{"routes": [{"writer": {"output": "discard"}}]}`},
+		{"caddy.logging.cores.sampler", "uni.logging.cores.sampler", `{"core": {"module": "mock"}}`},
+		{"caddy.logging.cores.ring_buffer", "uni.logging.cores.ring_buffer", `{}`},
+	} {
+		val, err := ctx.LoadModuleByID(tc.legacy, json.RawMessage(tc.config))
+		if err != nil {
+			t.Errorf("LoadModuleByID(%q) error = %v", tc.legacy, err)
+			continue
+		}
+		if got := val.(uni.Module).UniModule().ID; string(got) != tc.id {
+			t.Errorf("LoadModuleByID(%q) loaded %s, want %s", tc.legacy, got, tc.id)
+		}
+	}
+
+	// the writers never had other IDs
+	for _, legacy := range []string{"caddy.logging.writers.stdout", "caddy.logging.writers.stderr", "caddy.logging.writers.discard"} {
+		if _, err := ctx.LoadModuleByID(legacy, nil); err == nil {
+			t.Errorf("LoadModuleByID(%q) loaded a module that never had that ID", legacy)
+		}
+	}
+}
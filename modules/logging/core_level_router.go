@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yonomesh/uni"
+)
+
+func init() {
+	uni.RegisterModule(LevelRouterCore{})
+}
+
+// LevelRouterCore is a core that sends log entries to
+// different logs depending on their level, for example
+// DEBUG and INFO entries to stdout and everything more
+// severe to stderr. An entry is written to every route
+// that accepts its level.
+type LevelRouterCore struct {
+	// The routes, each with its own writer and encoder.
+	Routes []*LevelRoute `json:"routes,omitempty"`
+
+	zapcore.Core `json:"-"`
+}
+
+// LevelRoute is a log which only receives entries
+// with certain levels.
+type LevelRoute struct {
+	// The levels of entries to send to this route, for
+	// example `["debug", "info"]`. If empty, the route
+	// accepts the minimum `level` of the log and higher.
+	Levels []string `json:"levels,omitempty"`
+
+	uni.BaseLog
+
+	levels levelSet
+}
+
+// UniModule returns the Uni module information.
+func (LevelRouterCore) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:      "uni.logging.cores.level_router",
+		Aliases: []uni.ModuleID{"caddy.logging.cores.level_router"},
+		New:     func() uni.Module { return new(LevelRouterCore) },
+	}
+}
+
+// Provision sets up the log of each route.
+func (lr *LevelRouterCore) Provision(ctx uni.Context) error {
+	cores := make([]zapcore.Core, 0, len(lr.Routes))
+	for i, route := range lr.Routes {
+		if err := route.provision(ctx.WithConfigPointer("routes", strconv.Itoa(i))); err != nil {
+			return fmt.Errorf("route %d: %w", i, err)
+		}
+		cores = append(cores, route.core())
+	}
+	lr.Core = zapcore.NewTee(cores...)
+	return nil
+}
+
+// Validate ensures there is at least one route.
+func (lr *LevelRouterCore) Validate() error {
+	if len(lr.Routes) == 0 {
+		return fmt.Errorf("level router requires at least one route")
+	}
+	return nil
+}
+
+// Cleanup closes the writers of all the routes.
+func (lr *LevelRouterCore) Cleanup() error {
+	var errs []error
+	for i, route := range lr.Routes {
+		if err := route.Cleanup(); err != nil {
+			errs = append(errs, fmt.Errorf("route %d: %v", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *LevelRoute) provision(ctx uni.Context) error {
+	for _, name := range r.Levels {
+		lvl, err := zapcore.ParseLevel(strings.ToLower(name))
+		if err != nil {
+			return fmt.Errorf("unrecognized log level: %s", name)
+		}
+		r.levels = r.levels.with(lvl)
+	}
+
+	// the log's own minimum level must not reject
+	// levels that the route explicitly asks for
+	if r.Level == "" && r.levels != 0 {
+		r.Level = r.levels.min().String()
+	}
+
+	return r.BaseLog.Provision(ctx)
+}
+
+func (r *LevelRoute) core() zapcore.Core {
+	if r.levels == 0 {
+		return r.Core()
+	}
+	return levelFilterCore{Core: r.Core(), enabler: r.levels}
+}
+
+// levelSet is a zapcore.LevelEnabler that enables an
+// arbitrary set of levels, as a bitmask indexed by level.
+type levelSet uint16
+
+func (s levelSet) with(lvl zapcore.Level) levelSet {
+	return s | 1<<(lvl-zapcore.DebugLevel)
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (s levelSet) Enabled(lvl zapcore.Level) bool {
+	if lvl < zapcore.DebugLevel || lvl > zapcore.FatalLevel {
+		return false
+	}
+	return s&(1<<(lvl-zapcore.DebugLevel)) != 0
+}
+
+func (s levelSet) min() zapcore.Level {
+	for lvl := zapcore.DebugLevel; lvl <= zapcore.FatalLevel; lvl++ {
+		if s.Enabled(lvl) {
+			return lvl
+		}
+	}
+	return zapcore.InvalidLevel
+}
+
+// levelFilterCore wraps a core so that it only accepts
+// entries whose level is enabled by enabler.
+type levelFilterCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c levelFilterCore) Enabled(lvl zapcore.Level) bool {
+	return c.enabler.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+// With implements zapcore.Core.
+func (c levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelFilterCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+// Check implements zapcore.Core.
+func (c levelFilterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabler.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// Interface guards
+var (
+	_ zapcore.Core         = (*LevelRouterCore)(nil)
+	_ zapcore.Core         = (*levelFilterCore)(nil)
+	_ zapcore.LevelEnabler = levelSet(0)
+	_ uni.Provisioner      = (*LevelRouterCore)(nil)
+	_ uni.Validator        = (*LevelRouterCore)(nil)
+	_ uni.CleanerUpper     = (*LevelRouterCore)(nil)
+)
@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yonomesh/uni"
+	"github.com/yonomesh/uni/internal"
+)
+
+func init() {
+	uni.RegisterModule(RingBufferCore{})
+}
+
+// RingBufferCore is a core that keeps the most recent log
+// entries in memory, including their fields, so that they
+// can be inspected after something went wrong even if no
+// log was being written to a file. Retained entries can be
+// queried through the admin API at `/logs/recent` and with
+// the `uni logs` command.
+type RingBufferCore struct {
+	// The name under which the buffer can be queried.
+	// Default: "default"
+	Name string `json:"name,omitempty"`
+
+	// The number of entries to retain. Default: 1000
+	Size int `json:"size,omitempty"`
+
+	// The minimum level of entries to retain. Because the
+	// buffer is only read on demand, it is often useful to
+	// retain DEBUG entries here even if they are not written
+	// anywhere else. Default: DEBUG
+	Level string `json:"level,omitempty"`
+
+	zapcore.Core `json:"-"`
+
+	ring *internal.LogRing
+}
+
+// UniModule returns the Uni module information.
+func (RingBufferCore) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:      "uni.logging.cores.ring_buffer",
+		Aliases: []uni.ModuleID{"caddy.logging.cores.ring_buffer"},
+		New:     func() uni.Module { return new(RingBufferCore) },
+	}
+}
+
+// Provision sets up the buffer and makes it available
+// to be queried under its name, replacing any buffer
+// of that name.
+func (rb *RingBufferCore) Provision(_ uni.Context) error {
+	if rb.Name == "" {
+		rb.Name = DefaultLoggerName
+	}
+	if rb.Size == 0 {
+		rb.Size = 1000
+	}
+	if rb.Size < 0 {
+		return fmt.Errorf("size must be positive, got %d", rb.Size)
+	}
+
+	level := zapcore.DebugLevel
+	if rb.Level != "" {
+		var err error
+		level, err = zapcore.ParseLevel(rb.Level)
+		if err != nil {
+			return fmt.Errorf("unrecognized log level: %s", rb.Level)
+		}
+	}
+
+	rb.ring = internal.NewLogRing(rb.Size)
+	rb.Core = ringCore{ring: rb.ring, level: level}
+
+	// a buffer of the same name is usually that of the
+	// previous config, which is cleaned up only after this
+	// one is running; from now on, this buffer is queried
+	ringBuffersMu.Lock()
+	ringBuffers[rb.Name] = rb.ring
+	ringBuffersMu.Unlock()
+
+	return nil
+}
+
+// Cleanup removes the buffer from the set of buffers
+// that can be queried.
+func (rb *RingBufferCore) Cleanup() error {
+	ringBuffersMu.Lock()
+	defer ringBuffersMu.Unlock()
+	if ringBuffers[rb.Name] == rb.ring {
+		delete(ringBuffers, rb.Name)
+	}
+	return nil
+}
+
+// ringCore is the zapcore.Core that records entries into
+// a ring buffer. Unlike internal.LogBufferCore, fields
+// added with With are retained.
+type ringCore struct {
+	ring    *internal.LogRing
+	level   zapcore.LevelEnabler
+	context []zapcore.Field
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c ringCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+// With implements zapcore.Core.
+func (c ringCore) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	context = append(context, fields...)
+	return ringCore{ring: c.ring, level: c.level, context: context}
+}
+
+// Check implements zapcore.Core.
+func (c ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.context) == 0 {
+		c.ring.Add(ent, fields)
+		return nil
+	}
+	all := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	all = append(all, c.context...)
+	all = append(all, fields...)
+	c.ring.Add(ent, all)
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (ringCore) Sync() error { return nil }
+
+// ringBuffers holds the provisioned ring buffers by name,
+// so that they can be found by the admin API.
+var (
+	ringBuffers   = make(map[string]*internal.LogRing)
+	ringBuffersMu sync.RWMutex
+)
+
+// Interface guards
+var (
+	_ zapcore.Core     = (*RingBufferCore)(nil)
+	_ zapcore.Core     = (*ringCore)(nil)
+	_ uni.Provisioner  = (*RingBufferCore)(nil)
+	_ uni.CleanerUpper = (*RingBufferCore)(nil)
+)
@@ -0,0 +1,194 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yonomesh/uni"
+)
+
+func init() {
+	uni.RegisterModule(SamplerCore{})
+}
+
+// SamplerCore is a core that samples log entries before
+// passing them to another core. Unlike the `sampling`
+// option of a log, which applies a single policy to all
+// entries, the sampler counts entries per logger name,
+// level and message and allows individual messages to
+// be sampled at their own rate. For example, a noisy
+// "handled request" message can be reduced to one entry
+// in a thousand while rarer messages keep flowing.
+type SamplerCore struct {
+	// The core that receives the sampled entries. Required.
+	CoreRaw json.RawMessage `json:"core,omitempty" uni:"namespace=uni.logging.cores inline_key=module"`
+
+	// The sampling policy for messages that do not have
+	// their own policy in `messages`. Zero values take the
+	// same defaults as a log's `sampling` option.
+	uni.LogSampling
+
+	// Sampling policies for specific log messages, keyed
+	// by the exact message text.
+	Messages map[string]*uni.LogSampling `json:"messages,omitempty"`
+
+	zapcore.Core `json:"-"`
+}
+
+// UniModule returns the Uni module information.
+func (SamplerCore) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:      "uni.logging.cores.sampler",
+		Aliases: []uni.ModuleID{"caddy.logging.cores.sampler"},
+		New:     func() uni.Module { return new(SamplerCore) },
+	}
+}
+
+// Provision loads the wrapped core and sets up the sampler.
+func (sc *SamplerCore) Provision(ctx uni.Context) error {
+	if sc.CoreRaw == nil {
+		return fmt.Errorf("sampler requires a core to wrap")
+	}
+	core, err := uni.LoadModuleAs[zapcore.Core](ctx, sc, "CoreRaw")
+	if err != nil {
+		return fmt.Errorf("loading wrapped core module: %w", err)
+	}
+	sc.Core = newSamplerCore(core, sc.LogSampling, sc.Messages)
+	return nil
+}
+
+// maxSamplerKeys bounds the number of counters a sampler
+// keeps, so that messages with unbounded variety (e.g.
+// ones containing IDs) cannot exhaust memory.
+const maxSamplerKeys = 4096
+
+// samplerKey identifies a group of entries that are
+// counted together.
+type samplerKey struct {
+	level   zapcore.Level
+	logger  string
+	message string
+}
+
+// samplerCounter counts entries seen during the
+// interval that ends at resetAt.
+type samplerCounter struct {
+	resetAt time.Time
+	n       int
+}
+
+// sampler holds the state shared by a sampling core
+// and all the cores derived from it with With.
+type sampler struct {
+	defaults  uni.LogSampling
+	overrides map[string]uni.LogSampling
+
+	mu       sync.Mutex
+	counters map[samplerKey]*samplerCounter
+}
+
+func newSamplerCore(core zapcore.Core, defaults uni.LogSampling, messages map[string]*uni.LogSampling) zapcore.Core {
+	s := &sampler{
+		defaults:  withSamplingDefaults(defaults),
+		overrides: make(map[string]uni.LogSampling, len(messages)),
+		counters:  make(map[samplerKey]*samplerCounter),
+	}
+	for msg, policy := range messages {
+		if policy != nil {
+			s.overrides[msg] = withSamplingDefaults(*policy)
+		}
+	}
+	return samplerCore{Core: core, s: s}
+}
+
+// withSamplingDefaults fills in zero values of a
+// sampling policy the same way a log's sampling is.
+func withSamplingDefaults(p uni.LogSampling) uni.LogSampling {
+	if p.Interval == 0 {
+		p.Interval = 1 * time.Second
+	}
+	if p.First == 0 {
+		p.First = 100
+	}
+	if p.Thereafter == 0 {
+		p.Thereafter = 100
+	}
+	return p
+}
+
+// allow reports whether the entry should be written,
+// counting it in the process.
+func (s *sampler) allow(ent zapcore.Entry) bool {
+	policy, ok := s.overrides[ent.Message]
+	if !ok {
+		policy = s.defaults
+	}
+
+	key := samplerKey{level: ent.Level, logger: ent.LoggerName, message: ent.Message}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok {
+		if len(s.counters) >= maxSamplerKeys {
+			s.evictExpired(ent.Time)
+		}
+		c = new(samplerCounter)
+		s.counters[key] = c
+	}
+	if !ent.Time.Before(c.resetAt) {
+		c.resetAt = ent.Time.Add(policy.Interval)
+		c.n = 0
+	}
+	c.n++
+
+	if c.n <= policy.First {
+		return true
+	}
+	return (c.n-policy.First)%policy.Thereafter == 0
+}
+
+// evictExpired removes counters whose interval has ended;
+// if none have, all counters are dropped so that memory
+// stays bounded. Must be called with s.mu held.
+func (s *sampler) evictExpired(now time.Time) {
+	for key, c := range s.counters {
+		if !now.Before(c.resetAt) {
+			delete(s.counters, key)
+		}
+	}
+	if len(s.counters) >= maxSamplerKeys {
+		clear(s.counters)
+	}
+}
+
+// samplerCore is the zapcore.Core built by SamplerCore.
+type samplerCore struct {
+	zapcore.Core
+	s *sampler
+}
+
+// With implements zapcore.Core.
+func (c samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return samplerCore{Core: c.Core.With(fields), s: c.s}
+}
+
+// Check implements zapcore.Core.
+func (c samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) || !c.s.allow(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// Interface guards
+var (
+	_ zapcore.Core    = (*SamplerCore)(nil)
+	_ zapcore.Core    = (*samplerCore)(nil)
+	_ uni.Provisioner = (*SamplerCore)(nil)
+)
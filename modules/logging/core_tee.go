@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yonomesh/uni"
+)
+
+func init() {
+	uni.RegisterModule(TeeCore{})
+}
+
+// TeeCore is a core that duplicates every log entry to
+// several logs, each with its own writer, encoder and
+// level. It is useful for writing the same entries to,
+// for example, a human-readable console and a JSON file.
+type TeeCore struct {
+	// The logs to write entries to. Each one is configured
+	// just like the writer, encoder and level of a custom log.
+	Cores []*uni.BaseLog `json:"cores,omitempty"`
+
+	zapcore.Core `json:"-"`
+}
+
+// UniModule returns the Uni module information.
+func (TeeCore) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:      "uni.logging.cores.tee",
+		Aliases: []uni.ModuleID{"caddy.logging.cores.tee"},
+		New:     func() uni.Module { return new(TeeCore) },
+	}
+}
+
+// Provision sets up each of the logs and tees them together.
+func (tc *TeeCore) Provision(ctx uni.Context) error {
+	cores := make([]zapcore.Core, 0, len(tc.Cores))
+	for i, cl := range tc.Cores {
+		if err := cl.Provision(ctx.WithConfigPointer("cores", strconv.Itoa(i))); err != nil {
+			return fmt.Errorf("core %d: %w", i, err)
+		}
+		cores = append(cores, cl.Core())
+	}
+	tc.Core = zapcore.NewTee(cores...)
+	return nil
+}
+
+// Validate ensures there is at least one log to write to.
+func (tc *TeeCore) Validate() error {
+	if len(tc.Cores) == 0 {
+		return fmt.Errorf("tee core requires at least one core")
+	}
+	return nil
+}
+
+// Cleanup closes the writers of all the logs.
+func (tc *TeeCore) Cleanup() error {
+	var errs []error
+	for i, cl := range tc.Cores {
+		if err := cl.Cleanup(); err != nil {
+			errs = append(errs, fmt.Errorf("core %d: %v", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Interface guards
+var (
+	_ zapcore.Core     = (*TeeCore)(nil)
+	_ uni.Provisioner  = (*TeeCore)(nil)
+	_ uni.Validator    = (*TeeCore)(nil)
+	_ uni.CleanerUpper = (*TeeCore)(nil)
+)
@@ -18,8 +18,9 @@ type MockCore struct {
 // CaddyModule returns the Caddy module information.
 func (MockCore) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "logging.cores.mock",
-		New: func() uni.Module { return new(MockCore) },
+		ID:      "uni.logging.cores.mock",
+		Aliases: []uni.ModuleID{"logging.cores.mock"},
+		New:     func() uni.Module { return new(MockCore) },
 	}
 }
 
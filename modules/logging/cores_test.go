@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yonomesh/uni"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevelRouting(t *testing.T) {
+	lowCore, low := observer.New(zapcore.DebugLevel)
+	highCore, high := observer.New(zapcore.DebugLevel)
+
+	var lowLevels levelSet
+	lowLevels = lowLevels.with(zapcore.DebugLevel).with(zapcore.InfoLevel)
+	if got := lowLevels.min(); got != zapcore.DebugLevel {
+		t.Fatalf("min() = %v, want debug", got)
+	}
+
+	logger := zap.New(zapcore.NewTee(
+		levelFilterCore{Core: lowCore, enabler: lowLevels},
+		levelFilterCore{Core: highCore, enabler: zapcore.WarnLevel},
+	)).With(zap.String("app", "test"))
+
+	logger.Debug("d")
+	logger.Info("i")
+	logger.Warn("w")
+	logger.Error("e")
+
+	if got := messages(low.All()); !slices.Equal(got, []string{"d", "i"}) {
+		t.Errorf("low route got %v", got)
+	}
+	if got := messages(high.All()); !slices.Equal(got, []string{"w", "e"}) {
+		t.Errorf("high route got %v", got)
+	}
+	if ctx := low.All()[0].Context; len(ctx) != 1 || ctx[0].Key != "app" {
+		t.Errorf("expected With fields to be kept, got %v", ctx)
+	}
+}
+
+func TestSamplerCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	sampled := newSamplerCore(core,
+		uni.LogSampling{Interval: time.Minute, First: 2, Thereafter: 3},
+		map[string]*uni.LogSampling{
+			"noisy": {Interval: time.Minute, First: 1, Thereafter: 1000},
+		},
+	)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(msg string, at time.Time) {
+		ent := zapcore.Entry{Level: zapcore.InfoLevel, Message: msg, Time: at}
+		if ce := sampled.Check(ent, nil); ce != nil {
+			ce.Write()
+		}
+	}
+
+	for range 8 {
+		write("regular", now)
+		write("noisy", now)
+	}
+
+	// regular: entries 1, 2 (first), then 5 and 8 (every 3rd thereafter)
+	if got := logs.FilterMessage("regular").Len(); got != 4 {
+		t.Errorf("regular: got %d entries, want 4", got)
+	}
+	if got := logs.FilterMessage("noisy").Len(); got != 1 {
+		t.Errorf("noisy: got %d entries, want 1", got)
+	}
+
+	// a new interval resets the counters
+	write("noisy", now.Add(2*time.Minute))
+	if got := logs.FilterMessage("noisy").Len(); got != 2 {
+		t.Errorf("noisy after interval: got %d entries, want 2", got)
+	}
+}
+
+func TestCoreModulesValidate(t *testing.T) {
+	if err := (&TeeCore{}).Validate(); err == nil {
+		t.Error("expected error for tee without cores")
+	}
+	if err := (&LevelRouterCore{}).Validate(); err == nil {
+		t.Error("expected error for level router without routes")
+	}
+	if err := (&SamplerCore{}).Provision(uni.Context{}); err == nil {
+		t.Error("expected error for sampler without core")
+	}
+}
+
+func messages(entries []observer.LoggedEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Message
+	}
+	return out
+}
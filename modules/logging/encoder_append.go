@@ -35,17 +35,25 @@ type AppendEncoder struct {
 	// log entries. If not specified, defaults to "json",
 	// unless the output is a terminal, in which case
 	// it defaults to "console".
-	WrappedRaw json.RawMessage `json:"wrap,omitempty" caddy:"namespace=caddy.logging.encoders inline_key=format"`
+	WrappedRaw json.RawMessage `json:"wrap,omitempty" uni:"namespace=uni.logging.encoders inline_key=format"`
 
 	// A map of field names to their values. The values
 	// can be global placeholders (e.g. env vars), or constants.
 	// Note that the encoder does not run as part of an HTTP
 	// request context, so request placeholders are not available.
+	//
+	// A value that is just one placeholder keeps the type of
+	// the placeholder's value; placeholders within a longer
+	// string are replaced in the string.
 	Fields map[string]any `json:"fields,omitempty"`
 
 	wrapped zapcore.Encoder
 	repl    *uni.Replacer
 
+	// the string values of Fields that contain placeholders,
+	// compiled once so they are cheap to evaluate per entry
+	templates map[string]*uni.Template
+
 	wrappedIsDefault bool
 	ctx              uni.Context
 }
@@ -53,8 +61,9 @@ type AppendEncoder struct {
 // CaddyModule returns the Caddy module information.
 func (AppendEncoder) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "logging.encoders.append",
-		New: func() uni.Module { return new(AppendEncoder) },
+		ID:      "uni.logging.encoders.append",
+		Aliases: []uni.ModuleID{"logging.encoders.append"},
+		New:     func() uni.Module { return new(AppendEncoder) },
 	}
 }
 
@@ -63,6 +72,21 @@ func (fe *AppendEncoder) Provision(ctx uni.Context) error {
 	fe.ctx = ctx
 	fe.repl = uni.NewReplacer()
 
+	for key, value := range fe.Fields {
+		str, ok := value.(string)
+		if !ok || !strings.Contains(str, "{") {
+			continue
+		}
+		tmpl, err := fe.repl.Compile(str)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", key, err)
+		}
+		if fe.templates == nil {
+			fe.templates = make(map[string]*uni.Template)
+		}
+		fe.templates[key] = tmpl
+	}
+
 	if fe.WrappedRaw == nil {
 		// if wrap is not specified, default to JSON
 		fe.wrapped = &JSONEncoder{}
@@ -74,11 +98,11 @@ func (fe *AppendEncoder) Provision(ctx uni.Context) error {
 		fe.wrappedIsDefault = true
 	} else {
 		// set up wrapped encoder
-		val, err := ctx.LoadModule(fe, "WrappedRaw")
+		var err error
+		fe.wrapped, err = uni.LoadModuleAs[zapcore.Encoder](ctx, fe, "WrappedRaw")
 		if err != nil {
-			return fmt.Errorf("loading fallback encoder module: %v", err)
+			return fmt.Errorf("loading fallback encoder module: %w", err)
 		}
-		fe.wrapped = val.(zapcore.Encoder)
 	}
 
 	return nil
@@ -144,7 +168,7 @@ func (fe *AppendEncoder) UnmarshalCaddyfile() error {
 	// 			return d.ArgErr()
 	// 		}
 	// 		moduleName := d.Val()
-	// 		moduleID := "caddy.logging.encoders." + moduleName
+	// 		moduleID := "uni.logging.encoders." + moduleName
 	// 		unm, err := caddyfile.UnmarshalModule(d, moduleID)
 	// 		if err != nil {
 	// 			return err
@@ -303,9 +327,10 @@ func (fe AppendEncoder) OpenNamespace(key string) {
 // Clone is part of the zapcore.ObjectEncoder interface.
 func (fe AppendEncoder) Clone() zapcore.Encoder {
 	return AppendEncoder{
-		Fields:  fe.Fields,
-		wrapped: fe.wrapped.Clone(),
-		repl:    fe.repl,
+		Fields:    fe.Fields,
+		wrapped:   fe.wrapped.Clone(),
+		repl:      fe.repl,
+		templates: fe.templates,
 	}
 }
 
@@ -318,19 +343,13 @@ func (fe AppendEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (
 
 	// append fields from config
 	for key, value := range fe.Fields {
-		// if the value is a string
-		if str, ok := value.(string); ok {
-			isPlaceholder := strings.HasPrefix(str, "{") &&
-				strings.HasSuffix(str, "}") &&
-				strings.Count(str, "{") == 1
-			if isPlaceholder {
-				// and it looks like a placeholder, evaluate it
-				replaced, _ := fe.repl.Get(strings.Trim(str, "{}"))
-				zap.Any(key, replaced).AddTo(fe)
-			} else {
-				// just use the string as-is
-				zap.String(key, str).AddTo(fe)
-			}
+		if tmpl, ok := fe.templates[key]; ok {
+			// the value has placeholders, evaluate them
+			replaced, _ := tmpl.Value()
+			zap.Any(key, replaced).AddTo(fe)
+		} else if str, ok := value.(string); ok {
+			// just use the string as-is
+			zap.String(key, str).AddTo(fe)
 		} else {
 			// not a string, so use the value as any
 			zap.Any(key, value).AddTo(fe)
@@ -19,8 +19,9 @@ type ConsoleEncoder struct {
 // UniModule returns the Uni module information.
 func (ConsoleEncoder) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.console",
-		New: func() uni.Module { return new(ConsoleEncoder) },
+		ID:      "uni.logging.encoders.console",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.console"},
+		New:     func() uni.Module { return new(ConsoleEncoder) },
 	}
 }
 
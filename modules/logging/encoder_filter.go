@@ -3,7 +3,12 @@ package logging
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/yonomesh/uni"
@@ -16,6 +21,7 @@ import (
 
 func init() {
 	uni.RegisterModule(FilterEncoder{})
+	uni.RegisterNamespace[LogFieldFilter]("uni.logging.encoders.filter")
 }
 
 // FilterEncoder can filter (manipulate) fields on
@@ -26,7 +32,7 @@ type FilterEncoder struct {
 	// log entries. If not specified, defaults to "json",
 	// unless the output is a terminal, in which case
 	// it defaults to "console".
-	WrappedRaw json.RawMessage `json:"wrap,omitempty" caddy:"namespace=caddy.logging.encoders inline_key=format"`
+	WrappedRaw json.RawMessage `json:"wrap,omitempty" uni:"namespace=uni.logging.encoders inline_key=format"`
 
 	// A map of field names to their filters. Note that this
 	// is not a module map; the keys are field names.
@@ -40,7 +46,13 @@ type FilterEncoder struct {
 	// cannot be filtered because they are added by the
 	// underlying logging library as special cases: ts,
 	// level, logger, and msg.
-	FieldsRaw map[string]json.RawMessage `json:"fields,omitempty" caddy:"namespace=caddy.logging.encoders.filter inline_key=filter"`
+	FieldsRaw map[string]json.RawMessage `json:"fields,omitempty" uni:"namespace=uni.logging.encoders.filter inline_key=filter"`
+
+	// A list of filters for fields whose names are not known
+	// ahead of time. Each pattern matches field names by glob
+	// or regular expression, and patterns are tried in order
+	// for any field that has no exact entry in `fields`.
+	Patterns []*FieldPattern `json:"patterns,omitempty"`
 
 	wrapped zapcore.Encoder
 
@@ -58,8 +70,9 @@ type FilterEncoder struct {
 // CaddyModule returns the Caddy module information.
 func (FilterEncoder) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter",
-		New: func() uni.Module { return new(FilterEncoder) },
+		ID:      "uni.logging.encoders.filter",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter"},
+		New:     func() uni.Module { return new(FilterEncoder) },
 	}
 }
 
@@ -78,11 +91,11 @@ func (fe *FilterEncoder) Provision(ctx uni.Context) error {
 		fe.wrappedIsDefault = true
 	} else {
 		// set up wrapped encoder
-		val, err := ctx.LoadModule(fe, "WrappedRaw")
+		var err error
+		fe.wrapped, err = uni.LoadModuleAs[zapcore.Encoder](ctx, fe, "WrappedRaw")
 		if err != nil {
-			return fmt.Errorf("loading fallback encoder module: %v", err)
+			return fmt.Errorf("loading fallback encoder module: %w", err)
 		}
-		fe.wrapped = val.(zapcore.Encoder)
 	}
 
 	// set up each field filter
@@ -90,13 +103,17 @@ func (fe *FilterEncoder) Provision(ctx uni.Context) error {
 		fe.Fields = make(map[string]LogFieldFilter)
 	}
 
-	vals, err := ctx.LoadModule(fe, "FieldsRaw")
+	filters, err := uni.LoadModuleMapAs[LogFieldFilter](ctx, fe, "FieldsRaw")
 	if err != nil {
-		return fmt.Errorf("loading log filter modules: %v", err)
+		return fmt.Errorf("loading log filter modules: %w", err)
 	}
+	maps.Copy(fe.Fields, filters)
 
-	for fieldName, modIface := range vals.(map[string]any) {
-		fe.Fields[fieldName] = modIface.(LogFieldFilter)
+	// set up each pattern filter
+	for i, p := range fe.Patterns {
+		if err := p.provision(ctx.WithConfigPointer("patterns", strconv.Itoa(i))); err != nil {
+			return fmt.Errorf("pattern %d: %w", i, err)
+		}
 	}
 
 	return nil
@@ -154,7 +171,7 @@ func (fe *FilterEncoder) UnmarshalUniConfigfile() error {
 //
 // https://github.com/uber-go/zap/blob/7b755a3910491932656b01f2013b8bf41e74d4e8/zapcore/encoder.go#L364
 func (fe FilterEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
-	if filter, ok := fe.Fields[fe.keyPrefix+key]; ok {
+	if filter, ok := fe.filterFor(key); ok {
 		filter.Filter(zap.Array(key, marshaler)).AddTo(fe.wrapped)
 		return nil
 	}
@@ -341,6 +358,7 @@ func (fe FilterEncoder) OpenNamespace(key string) {
 func (fe FilterEncoder) Clone() zapcore.Encoder {
 	return FilterEncoder{
 		Fields:    fe.Fields,
+		Patterns:  fe.Patterns,
 		wrapped:   fe.wrapped.Clone(),
 		keyPrefix: fe.keyPrefix,
 	}
@@ -365,7 +383,7 @@ func (fe FilterEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (
 // that again). If false was returned, the field has
 // not yet been added to the underlying encoder.
 func (fe FilterEncoder) filtered(key string, value any) bool {
-	filter, ok := fe.Fields[fe.keyPrefix+key]
+	filter, ok := fe.filterFor(key)
 	if !ok {
 		return false
 	}
@@ -373,6 +391,103 @@ func (fe FilterEncoder) filtered(key string, value any) bool {
 	return true
 }
 
+// filterFor returns the filter for the field with the given
+// key at the current nesting level. Exact names configured in
+// Fields take precedence over Patterns.
+func (fe FilterEncoder) filterFor(key string) (LogFieldFilter, bool) {
+	fullKey := fe.keyPrefix + key
+	if filter, ok := fe.Fields[fullKey]; ok {
+		return filter, true
+	}
+	for _, p := range fe.Patterns {
+		if filter, ok := p.match(fullKey); ok {
+			return filter, true
+		}
+	}
+	return nil, false
+}
+
+// FieldPattern applies a filter to every field whose name
+// matches either a glob or a regular expression. Names of
+// nested fields are matched in their `a>b` form, just like
+// the keys of FilterEncoder's `fields`.
+type FieldPattern struct {
+	// A glob pattern as understood by Go's path.Match,
+	// for example `*_token` or `request>headers>*`.
+	Glob string `json:"glob,omitempty"`
+
+	// A regular expression matched against the whole field
+	// name. If the filter renames the field, the new name
+	// may refer to capture groups, e.g. `$1`.
+	Regexp string `json:"regexp,omitempty"`
+
+	// The filter to apply to matching fields.
+	FilterRaw json.RawMessage `json:"filter,omitempty" uni:"namespace=uni.logging.encoders.filter inline_key=filter"`
+
+	filter LogFieldFilter
+	regexp *regexp.Regexp
+}
+
+// provision validates the pattern and loads its filter.
+func (p *FieldPattern) provision(ctx uni.Context) error {
+	if (p.Glob == "") == (p.Regexp == "") {
+		return fmt.Errorf("exactly one of glob or regexp is required")
+	}
+	if p.Glob != "" {
+		if _, err := path.Match(p.Glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %v", p.Glob, err)
+		}
+	} else {
+		re, err := regexp.Compile("^(?:" + p.Regexp + ")$")
+		if err != nil {
+			return fmt.Errorf("compiling regexp %q: %v", p.Regexp, err)
+		}
+		p.regexp = re
+	}
+	if p.FilterRaw == nil {
+		return fmt.Errorf("filter is required")
+	}
+	var err error
+	p.filter, err = uni.LoadModuleAs[LogFieldFilter](ctx, p, "FilterRaw")
+	if err != nil {
+		return fmt.Errorf("loading filter module: %w", err)
+	}
+	return nil
+}
+
+// match returns the filter to use for the field named key,
+// and whether the pattern matched at all.
+func (p *FieldPattern) match(key string) (LogFieldFilter, bool) {
+	if p.regexp == nil {
+		ok, _ := path.Match(p.Glob, key)
+		return p.filter, ok
+	}
+	submatches := p.regexp.FindStringSubmatchIndex(key)
+	if submatches == nil {
+		return nil, false
+	}
+	return patternRenameFilter{p: p, key: key, submatches: submatches}, true
+}
+
+// patternRenameFilter runs a pattern's filter and expands
+// capture group references in the name it renames the
+// field to.
+type patternRenameFilter struct {
+	p          *FieldPattern
+	key        string
+	submatches []int
+}
+
+// Filter implements LogFieldFilter.
+func (f patternRenameFilter) Filter(in zapcore.Field) zapcore.Field {
+	origKey := in.Key
+	out := f.p.filter.Filter(in)
+	if out.Key != origKey && strings.Contains(out.Key, "$") {
+		out.Key = string(f.p.regexp.ExpandString(nil, out.Key, f.key, f.submatches))
+	}
+	return out
+}
+
 // logObjectMarshalerWrapper allows us to recursively
 // filter fields of objects as they get encoded.
 type logObjectMarshalerWrapper struct {
@@ -389,6 +504,7 @@ func (mom logObjectMarshalerWrapper) MarshalLogObject(_ zapcore.ObjectEncoder) e
 var (
 	_ zapcore.Encoder                   = (*FilterEncoder)(nil)
 	_ zapcore.ObjectMarshaler           = (*logObjectMarshalerWrapper)(nil)
+	_ LogFieldFilter                    = (*patternRenameFilter)(nil)
 	_ DelegateSetDefaultFormatForWriter = (*FilterEncoder)(nil)
 	//_ caddyfile.Unmarshaler            = (*FilterEncoder)(nil)
 )
@@ -19,8 +19,9 @@ type JSONEncoder struct {
 // UniModule returns the Uni module information.
 func (JSONEncoder) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.json",
-		New: func() uni.Module { return new(JSONEncoder) },
+		ID:      "uni.logging.encoders.json",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.json"},
+		New:     func() uni.Module { return new(JSONEncoder) },
 	}
 }
 
@@ -5,18 +5,37 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
 	"net/netip"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/yonomesh/uni"
 )
 
+func init() {
+	uni.RegisterModule(DeleteFilter{})
+	uni.RegisterModule(HashFilter{})
+	uni.RegisterModule(ReplaceFilter{})
+	uni.RegisterModule(IPMaskFilter{})
+	uni.RegisterModule(QueryFilter{})
+	uni.RegisterModule(CookieFilter{})
+	uni.RegisterModule(RegexpFilter{})
+	uni.RegisterModule(MultiRegexpFilter{})
+	uni.RegisterModule(RenameFilter{})
+	uni.RegisterModule(TruncateFilter{})
+	uni.RegisterModule(ConvertFilter{})
+}
+
 // LogFieldFilter can filter (or manipulate) a field in a log entry.
 type LogFieldFilter interface {
 	Filter(zapcore.Field) zapcore.Field
@@ -28,8 +47,9 @@ type DeleteFilter struct{}
 // UniModule returns the Uni module information.
 func (DeleteFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.delete",
-		New: func() uni.Module { return new(DeleteFilter) },
+		ID:      "uni.logging.encoders.filter.delete",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.delete"},
+		New:     func() uni.Module { return new(DeleteFilter) },
 	}
 }
 
@@ -56,8 +76,9 @@ type HashFilter struct{}
 // UniModule returns the Uni module information.
 func (HashFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.hash",
-		New: func() uni.Module { return new(HashFilter) },
+		ID:      "uni.logging.encoders.filter.hash",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.hash"},
+		New:     func() uni.Module { return new(HashFilter) },
 	}
 }
 
@@ -103,8 +124,9 @@ type ReplaceFilter struct {
 // UniModule returns the Uni module information.
 func (ReplaceFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.replace",
-		New: func() uni.Module { return new(ReplaceFilter) },
+		ID:      "uni.logging.encoders.filter.replace",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.replace"},
+		New:     func() uni.Module { return new(ReplaceFilter) },
 	}
 }
 
@@ -138,8 +160,9 @@ type IPMaskFilter struct {
 // UniModule returns the Uni module information.
 func (IPMaskFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.ip_mask",
-		New: func() uni.Module { return new(IPMaskFilter) },
+		ID:      "uni.logging.encoders.filter.ip_mask",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.ip_mask"},
+		New:     func() uni.Module { return new(IPMaskFilter) },
 	}
 }
 
@@ -259,8 +282,9 @@ func (f *QueryFilter) Validate() error {
 // CaddyModule returns the Caddy module information.
 func (QueryFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.query",
-		New: func() uni.Module { return new(QueryFilter) },
+		ID:      "uni.logging.encoders.filter.query",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.query"},
+		New:     func() uni.Module { return new(QueryFilter) },
 	}
 }
 
@@ -353,8 +377,9 @@ func (f *CookieFilter) Validate() error {
 // CaddyModule returns the Caddy module information.
 func (CookieFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.cookie",
-		New: func() uni.Module { return new(CookieFilter) },
+		ID:      "uni.logging.encoders.filter.cookie",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.cookie"},
+		New:     func() uni.Module { return new(CookieFilter) },
 	}
 }
 
@@ -425,8 +450,9 @@ type RegexpFilter struct {
 // CaddyModule returns the Caddy module information.
 func (RegexpFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.regexp",
-		New: func() uni.Module { return new(RegexpFilter) },
+		ID:      "uni.logging.encoders.filter.regexp",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.regexp"},
+		New:     func() uni.Module { return new(RegexpFilter) },
 	}
 }
 
@@ -504,8 +530,9 @@ const (
 // CaddyModule returns the Caddy module information.
 func (MultiRegexpFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.multi_regexp",
-		New: func() uni.Module { return new(MultiRegexpFilter) },
+		ID:      "uni.logging.encoders.filter.multi_regexp",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.multi_regexp"},
+		New:     func() uni.Module { return new(MultiRegexpFilter) },
 	}
 }
 
@@ -646,8 +673,9 @@ type RenameFilter struct {
 // CaddyModule returns the Caddy module information.
 func (RenameFilter) UniModule() uni.ModuleInfo {
 	return uni.ModuleInfo{
-		ID:  "caddy.logging.encoders.filter.rename",
-		New: func() uni.Module { return new(RenameFilter) },
+		ID:      "uni.logging.encoders.filter.rename",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.rename"},
+		New:     func() uni.Module { return new(RenameFilter) },
 	}
 }
 
@@ -662,6 +690,239 @@ func (f *RenameFilter) Filter(in zapcore.Field) zapcore.Field {
 	return in
 }
 
+// TruncateFilter is a Uni log field filter that shortens
+// string and byte string fields to a maximum number of bytes,
+// appending a marker so that readers can tell the value was
+// cut. Strings are never cut in the middle of a UTF-8 sequence.
+// If the field is an array of strings, each of them will be
+// truncated.
+type TruncateFilter struct {
+	// The maximum length of a value in bytes, not including
+	// the marker. Required.
+	MaxLength int `json:"max_length,omitempty"`
+
+	// The marker appended to values that were truncated.
+	// Default: `…`
+	Ellipsis *string `json:"ellipsis,omitempty"`
+}
+
+// UniModule returns the Uni module information.
+func (TruncateFilter) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:      "uni.logging.encoders.filter.truncate",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.truncate"},
+		New:     func() uni.Module { return new(TruncateFilter) },
+	}
+}
+
+// Validate ensures a positive maximum length is configured.
+func (f *TruncateFilter) Validate() error {
+	if f.MaxLength <= 0 {
+		return fmt.Errorf("max_length must be positive, got %d", f.MaxLength)
+	}
+	return nil
+}
+
+// Filter truncates the input field if it is too long.
+func (f *TruncateFilter) Filter(in zapcore.Field) zapcore.Field {
+	switch in.Type {
+	case zapcore.StringType:
+		in.String = f.truncateString(in.String)
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		if b, ok := in.Interface.([]byte); ok && len(b) > f.MaxLength {
+			out := make([]byte, 0, f.MaxLength+len(f.ellipsis()))
+			out = append(out, b[:f.MaxLength]...)
+			in.Interface = append(out, f.ellipsis()...)
+		}
+	default:
+		if array, ok := in.Interface.([]string); ok {
+			newArray := make([]string, len(array))
+			for i, s := range array {
+				newArray[i] = f.truncateString(s)
+			}
+			in.Interface = newArray
+		}
+	}
+	return in
+}
+
+func (f *TruncateFilter) truncateString(s string) string {
+	if len(s) <= f.MaxLength {
+		return s
+	}
+	n := f.MaxLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + f.ellipsis()
+}
+
+func (f *TruncateFilter) ellipsis() string {
+	if f.Ellipsis == nil {
+		return "…"
+	}
+	return *f.Ellipsis
+}
+
+// ConvertFilter is a Uni log field filter that changes
+// the type of a field's value, for example to turn a
+// duration into a number of milliseconds or a numeric
+// string into an integer. Values that cannot be converted
+// are left unchanged.
+type ConvertFilter struct {
+	// The type to convert to. Recognized values are:
+	//
+	// - `string`
+	// - `int` (durations become nanoseconds)
+	// - `float` (durations become seconds)
+	// - `bool`
+	// - `duration_ms`: integer milliseconds of a duration
+	// - `duration_s`: fractional seconds of a duration
+	//
+	// Strings are parsed as needed; for the duration types,
+	// strings like `1.5s` or `2d` are accepted.
+	Type string `json:"type,omitempty"`
+}
+
+// UniModule returns the Uni module information.
+func (ConvertFilter) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:      "uni.logging.encoders.filter.convert",
+		Aliases: []uni.ModuleID{"caddy.logging.encoders.filter.convert"},
+		New:     func() uni.Module { return new(ConvertFilter) },
+	}
+}
+
+// Validate checks that the target type is recognized.
+func (f *ConvertFilter) Validate() error {
+	switch f.Type {
+	case "string", "int", "float", "bool", "duration_ms", "duration_s":
+		return nil
+	}
+	return fmt.Errorf("unrecognized conversion type: %q", f.Type)
+}
+
+// Filter converts the input field to the configured type.
+func (f *ConvertFilter) Filter(in zapcore.Field) zapcore.Field {
+	val, ok := fieldValue(in)
+	if !ok {
+		return in
+	}
+
+	switch f.Type {
+	case "string":
+		return zap.String(in.Key, uni.ToString(val))
+
+	case "int":
+		switch v := val.(type) {
+		case int64:
+			return zap.Int64(in.Key, v)
+		case uint64:
+			return zap.Uint64(in.Key, v)
+		case float64:
+			return zap.Int64(in.Key, int64(v))
+		case bool:
+			if v {
+				return zap.Int64(in.Key, 1)
+			}
+			return zap.Int64(in.Key, 0)
+		case time.Duration:
+			return zap.Int64(in.Key, int64(v))
+		case string:
+			s := strings.TrimSpace(v)
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return zap.Int64(in.Key, i)
+			}
+			if fl, err := strconv.ParseFloat(s, 64); err == nil {
+				return zap.Int64(in.Key, int64(fl))
+			}
+		}
+
+	case "float":
+		switch v := val.(type) {
+		case int64:
+			return zap.Float64(in.Key, float64(v))
+		case uint64:
+			return zap.Float64(in.Key, float64(v))
+		case float64:
+			return zap.Float64(in.Key, v)
+		case time.Duration:
+			return zap.Float64(in.Key, v.Seconds())
+		case string:
+			if fl, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return zap.Float64(in.Key, fl)
+			}
+		}
+
+	case "bool":
+		switch v := val.(type) {
+		case bool:
+			return zap.Bool(in.Key, v)
+		case int64:
+			return zap.Bool(in.Key, v != 0)
+		case uint64:
+			return zap.Bool(in.Key, v != 0)
+		case float64:
+			return zap.Bool(in.Key, v != 0)
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return zap.Bool(in.Key, b)
+			}
+		}
+
+	case "duration_ms", "duration_s":
+		var dur time.Duration
+		switch v := val.(type) {
+		case time.Duration:
+			dur = v
+		case string:
+			d, err := uni.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				return in
+			}
+			dur = d
+		default:
+			return in
+		}
+		if f.Type == "duration_ms" {
+			return zap.Int64(in.Key, dur.Milliseconds())
+		}
+		return zap.Float64(in.Key, dur.Seconds())
+	}
+
+	return in
+}
+
+// fieldValue extracts the scalar value carried by a field,
+// normalized to one of string, int64, uint64, float64, bool
+// or time.Duration. It returns false for other field types.
+func fieldValue(in zapcore.Field) (any, bool) {
+	switch in.Type {
+	case zapcore.StringType:
+		return in.String, true
+	case zapcore.ByteStringType:
+		b, ok := in.Interface.([]byte)
+		return string(b), ok
+	case zapcore.BoolType:
+		return in.Integer == 1, true
+	case zapcore.DurationType:
+		return time.Duration(in.Integer), true
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return in.Integer, true
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+		return uint64(in.Integer), true
+	case zapcore.Float64Type:
+		return math.Float64frombits(uint64(in.Integer)), true
+	case zapcore.Float32Type:
+		return float64(math.Float32frombits(uint32(in.Integer))), true
+	case zapcore.StringerType:
+		if s, ok := in.Interface.(fmt.Stringer); ok {
+			return s.String(), true
+		}
+	}
+	return nil, false
+}
+
 // Interface Guards
 var (
 	_ LogFieldFilter = (*DeleteFilter)(nil)
@@ -673,6 +934,8 @@ var (
 	_ LogFieldFilter = (*RegexpFilter)(nil)
 	_ LogFieldFilter = (*RenameFilter)(nil)
 	_ LogFieldFilter = (*MultiRegexpFilter)(nil)
+	_ LogFieldFilter = (*TruncateFilter)(nil)
+	_ LogFieldFilter = (*ConvertFilter)(nil)
 
 	// _ caddyfile.Unmarshaler = (*DeleteFilter)(nil)
 	// _ caddyfile.Unmarshaler = (*HashFilter)(nil)
@@ -689,4 +952,6 @@ var (
 
 	_ uni.Validator = (*QueryFilter)(nil)
 	_ uni.Validator = (*MultiRegexpFilter)(nil)
+	_ uni.Validator = (*TruncateFilter)(nil)
+	_ uni.Validator = (*ConvertFilter)(nil)
 )
@@ -0,0 +1,190 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yonomesh/uni"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTruncateFilter(t *testing.T) {
+	empty := ""
+	tests := []struct {
+		name   string
+		filter TruncateFilter
+		in     zapcore.Field
+		want   string
+	}{
+		{
+			name:   "short string is kept",
+			filter: TruncateFilter{MaxLength: 10},
+			in:     zap.String("k", "hello"),
+			want:   "hello",
+		},
+		{
+			name:   "long string gets default marker",
+			filter: TruncateFilter{MaxLength: 5},
+			in:     zap.String("k", "hello world"),
+			want:   "hello…",
+		},
+		{
+			name:   "custom empty marker",
+			filter: TruncateFilter{MaxLength: 5, Ellipsis: &empty},
+			in:     zap.String("k", "hello world"),
+			want:   "hello",
+		},
+		{
+			name:   "does not split utf-8 sequence",
+			filter: TruncateFilter{MaxLength: 4},
+			in:     zap.String("k", "ab你好"),
+			want:   "ab…",
+		},
+		{
+			name:   "byte string",
+			filter: TruncateFilter{MaxLength: 3},
+			in:     zap.ByteString("k", []byte("abcdef")),
+			want:   "abc…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := tt.filter.Filter(tt.in)
+			got := out.String
+			if b, ok := out.Interface.([]byte); ok {
+				got = string(b)
+			}
+			if got != tt.want {
+				t.Fatalf("Filter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      string
+		in       zapcore.Field
+		wantType zapcore.FieldType
+		want     any
+	}{
+		{
+			name:     "duration to milliseconds",
+			typ:      "duration_ms",
+			in:       zap.Duration("k", 1500*time.Millisecond),
+			wantType: zapcore.Int64Type,
+			want:     int64(1500),
+		},
+		{
+			name:     "duration string to milliseconds",
+			typ:      "duration_ms",
+			in:       zap.String("k", "2s"),
+			wantType: zapcore.Int64Type,
+			want:     int64(2000),
+		},
+		{
+			name:     "numeric string to int",
+			typ:      "int",
+			in:       zap.String("k", " 42 "),
+			wantType: zapcore.Int64Type,
+			want:     int64(42),
+		},
+		{
+			name:     "int to string",
+			typ:      "string",
+			in:       zap.Int("k", 7),
+			wantType: zapcore.StringType,
+			want:     "7",
+		},
+		{
+			name:     "string to bool",
+			typ:      "bool",
+			in:       zap.String("k", "true"),
+			wantType: zapcore.BoolType,
+			want:     true,
+		},
+		{
+			name:     "unparsable value is unchanged",
+			typ:      "int",
+			in:       zap.String("k", "abc"),
+			wantType: zapcore.StringType,
+			want:     "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ConvertFilter{Type: tt.typ}
+			if err := f.Validate(); err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			out := f.Filter(tt.in)
+			if out.Key != tt.in.Key {
+				t.Fatalf("key changed from %q to %q", tt.in.Key, out.Key)
+			}
+			if out.Type != tt.wantType {
+				t.Fatalf("type = %v, want %v", out.Type, tt.wantType)
+			}
+			got, _ := fieldValue(out)
+			if got != tt.want {
+				t.Fatalf("value = %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+
+	if err := (&ConvertFilter{Type: "uuid"}).Validate(); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+}
+
+func TestFilterEncoderPatterns(t *testing.T) {
+	fe := FilterEncoder{
+		wrapped: zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}),
+		Fields: map[string]LogFieldFilter{
+			"x_keep": &ReplaceFilter{Value: "exact"},
+		},
+		Patterns: []*FieldPattern{
+			{Glob: "*_token", filter: DeleteFilter{}},
+			{
+				Regexp: "x_(.*)",
+				regexp: regexp.MustCompile("^(?:x_(.*))$"),
+				filter: &RenameFilter{Name: "$1"},
+			},
+		},
+	}
+
+	buf, err := fe.EncodeEntry(zapcore.Entry{Message: "m"}, []zapcore.Field{
+		zap.String("auth_token", "secret"),
+		zap.String("x_user", "bob"),
+		zap.String("x_keep", "v"),
+		zap.String("other", "o"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.TrimSpace(buf.String())
+	want := `{"msg":"m","user":"bob","x_keep":"exact","other":"o"}`
+	if got != want {
+		t.Fatalf("EncodeEntry() = %s, want %s", got, want)
+	}
+}
+
+func TestFieldPatternProvisionErrors(t *testing.T) {
+	for _, p := range []*FieldPattern{
+		{},
+		{Glob: "a", Regexp: "b"},
+		{Glob: "[", FilterRaw: []byte(`{"filter":"delete"}`)},
+		{Regexp: "(", FilterRaw: []byte(`{"filter":"delete"}`)},
+		{Glob: "a"},
+	} {
+		if err := p.provision(uni.Context{}); err == nil {
+			t.Errorf("expected error for pattern %+v", p)
+		}
+	}
+}
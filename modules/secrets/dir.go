@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/yonomesh/uni"
+)
+
+func init() {
+	uni.RegisterModule(DirStore{})
+}
+
+// DirStore reads secrets from a directory with one file per
+// secret, where the file name is the key, like the secrets
+// that Kubernetes and Docker mount into containers. A single
+// trailing newline is removed from the contents.
+//
+// Keys may refer to files in subdirectories, but never to
+// files outside of the root directory, including through
+// symbolic links.
+type DirStore struct {
+	// The directory that holds the secret files.
+	Root string `json:"root,omitempty"`
+
+	// The maximum size of a secret file. Default: 1 MiB.
+	MaxSize int64 `json:"max_size,omitempty"`
+}
+
+// UniModule returns the Uni module information.
+func (DirStore) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:  "secrets.stores.dir",
+		New: func() uni.Module { return new(DirStore) },
+	}
+}
+
+// Provision sets up the store.
+func (ds *DirStore) Provision(ctx uni.Context) error {
+	if ds.Root == "" {
+		return fmt.Errorf("root is required")
+	}
+	if ds.MaxSize == 0 {
+		ds.MaxSize = defaultMaxSecretSize
+	}
+	return nil
+}
+
+// Secret implements uni.SecretStore.
+func (ds *DirStore) Secret(_ context.Context, key string) ([]byte, error) {
+	if !filepath.IsLocal(key) {
+		return nil, fmt.Errorf("invalid secret key: %s", key)
+	}
+
+	root, err := os.OpenRoot(ds.Root)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	f, err := root.Open(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", uni.ErrSecretNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(io.LimitReader(f, ds.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > ds.MaxSize {
+		return nil, fmt.Errorf("secret %s is larger than %d bytes", key, ds.MaxSize)
+	}
+
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	b = bytes.TrimSuffix(b, []byte("\r"))
+	return b, nil
+}
+
+const defaultMaxSecretSize = 1024 * 1024
+
+// Interface guards
+var (
+	_ uni.Provisioner = (*DirStore)(nil)
+	_ uni.SecretStore = (*DirStore)(nil)
+)
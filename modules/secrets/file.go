@@ -0,0 +1,161 @@
+// Package secrets provides the standard secret stores, which
+// supply the values of {secret.<store>.<key>} placeholders.
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yonomesh/uni"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+func init() {
+	uni.RegisterModule(FileStore{})
+}
+
+// FileStore reads secrets from a file holding a JSON object
+// of secret names to values, encrypted with NaCl secretbox
+// (XSalsa20 and Poly1305). The file consists of the 24-byte
+// nonce followed by the sealed box; SealSecrets produces it.
+//
+// The key is 32 bytes, base64-encoded, and is read from an
+// environment variable or a file. The encrypted file is read
+// again whenever a secret is not cached, so it can be
+// replaced without reloading the config.
+type FileStore struct {
+	// The path to the encrypted secrets file.
+	Path string `json:"path,omitempty"`
+
+	// The name of the environment variable that holds the key.
+	KeyEnv string `json:"key_env,omitempty"`
+
+	// The path to a file that holds the key.
+	KeyFile string `json:"key_file,omitempty"`
+
+	key *[keySize]byte
+}
+
+// UniModule returns the Uni module information.
+func (FileStore) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:  "secrets.stores.file",
+		New: func() uni.Module { return new(FileStore) },
+	}
+}
+
+// Provision loads the key.
+func (fs *FileStore) Provision(ctx uni.Context) error {
+	if fs.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	var encoded string
+	switch {
+	case fs.KeyEnv != "" && fs.KeyFile != "":
+		return fmt.Errorf("key_env and key_file are mutually exclusive")
+	case fs.KeyEnv != "":
+		encoded = os.Getenv(fs.KeyEnv)
+		if encoded == "" {
+			return fmt.Errorf("environment variable %s is empty", fs.KeyEnv)
+		}
+	case fs.KeyFile != "":
+		b, err := os.ReadFile(fs.KeyFile)
+		if err != nil {
+			return fmt.Errorf("reading key file: %v", err)
+		}
+		encoded = string(b)
+	default:
+		return fmt.Errorf("one of key_env or key_file is required")
+	}
+
+	key, err := ParseKey(encoded)
+	if err != nil {
+		return err
+	}
+	fs.key = key
+
+	return nil
+}
+
+// Secret implements uni.SecretStore.
+func (fs *FileStore) Secret(_ context.Context, key string) ([]byte, error) {
+	sealed, err := os.ReadFile(fs.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets file: %v", err)
+	}
+	secrets, err := OpenSecrets(fs.key, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fs.Path, err)
+	}
+	val, ok := secrets[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", uni.ErrSecretNotFound, key)
+	}
+	return []byte(val), nil
+}
+
+// ParseKey decodes a base64-encoded secretbox key.
+// Surrounding whitespace is ignored.
+func ParseKey(encoded string) (*[keySize]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key: %v", err)
+	}
+	if len(b) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(b))
+	}
+	key := new([keySize]byte)
+	copy(key[:], b)
+	return key, nil
+}
+
+// SealSecrets encrypts secrets with key in the format read
+// by FileStore.
+func SealSecrets(key *[keySize]byte, secrets map[string]string) ([]byte, error) {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	return secretbox.Seal(nonce[:], plain, &nonce, key), nil
+}
+
+// OpenSecrets decrypts secrets sealed by SealSecrets.
+func OpenSecrets(key *[keySize]byte, sealed []byte) (map[string]string, error) {
+	if len(sealed) < nonceSize+secretbox.Overhead {
+		return nil, errors.New("secrets file is too short")
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], sealed[:nonceSize])
+	plain, ok := secretbox.Open(nil, sealed[nonceSize:], &nonce, key)
+	if !ok {
+		return nil, errors.New("decrypting secrets file failed: wrong key or corrupted file")
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		// the error could quote parts of the plaintext
+		return nil, errors.New("decrypted secrets are not a JSON object of strings")
+	}
+	return secrets, nil
+}
+
+const (
+	keySize   = 32
+	nonceSize = 24
+)
+
+// Interface guards
+var (
+	_ uni.Provisioner = (*FileStore)(nil)
+	_ uni.SecretStore = (*FileStore)(nil)
+)
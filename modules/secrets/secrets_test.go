@@ -0,0 +1,186 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yonomesh/uni"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	rawKey := strings.Repeat("k", keySize)
+	t.Setenv("UNI_TEST_SECRETS_KEY", base64.StdEncoding.EncodeToString([]byte(rawKey)))
+
+	key, err := ParseKey(os.Getenv("UNI_TEST_SECRETS_KEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := SealSecrets(key, map[string]string{"db_password": "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "secrets.enc")
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sealed), "hunter2") {
+		t.Fatal("secret is not encrypted")
+	}
+
+	fs := &FileStore{Path: path, KeyEnv: "UNI_TEST_SECRETS_KEY"}
+	if err := fs.Provision(uni.Context{}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fs.Secret(context.Background(), "db_password")
+	if err != nil || string(got) != "hunter2" {
+		t.Fatalf("Secret() = %q, %v; want hunter2", got, err)
+	}
+	if _, err := fs.Secret(context.Background(), "other"); !errors.Is(err, uni.ErrSecretNotFound) {
+		t.Errorf("Secret() error = %v, want ErrSecretNotFound", err)
+	}
+
+	wrongKey, _ := ParseKey(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", keySize))))
+	if _, err := OpenSecrets(wrongKey, sealed); err == nil {
+		t.Error("expected error decrypting with the wrong key")
+	}
+
+	for _, bad := range []*FileStore{
+		{KeyEnv: "UNI_TEST_SECRETS_KEY"},
+		{Path: path},
+		{Path: path, KeyEnv: "UNI_TEST_SECRETS_KEY", KeyFile: "key"},
+		{Path: path, KeyEnv: "UNI_TEST_UNSET"},
+	} {
+		if err := bad.Provision(uni.Context{}); err == nil {
+			t.Errorf("expected error provisioning %+v", bad)
+		}
+	}
+	if _, err := ParseKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func TestDirStore(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "leak"), []byte("outside"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "token"), []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "db"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "db", "password"), []byte("pw"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "big"), []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "leak"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	ds := &DirStore{Root: root, MaxSize: 8}
+	if err := ds.Provision(uni.Context{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key      string
+		want     string
+		notFound bool
+		wantErr  bool
+	}{
+		{key: "token", want: "s3cret"},
+		{key: "db/password", want: "pw"},
+		{key: "missing", notFound: true},
+		{key: "../" + filepath.Base(outside) + "/leak", wantErr: true},
+		{key: "link", wantErr: true},
+		{key: "big", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := ds.Secret(context.Background(), tt.key)
+			switch {
+			case tt.notFound:
+				if !errors.Is(err, uni.ErrSecretNotFound) {
+					t.Fatalf("error = %v, want ErrSecretNotFound", err)
+				}
+			case tt.wantErr:
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+			case err != nil || string(got) != tt.want:
+				t.Fatalf("Secret() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultStore(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/app/db":
+			w.Write([]byte(`{"data":{"data":{"password":"hunter2","port":5432},"metadata":{"version":3}}}`))
+		case "/v1/kv/data/app/plain":
+			w.Write([]byte(`{"data":{"data":{"value":"v"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("UNI_TEST_VAULT_TOKEN", "test-token")
+	vs := &VaultStore{Address: srv.URL, Mount: "/kv/", Token: "{env.UNI_TEST_VAULT_TOKEN}"}
+	if err := vs.Provision(uni.Context{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key      string
+		want     string
+		notFound bool
+	}{
+		{key: "app/db#password", want: "hunter2"},
+		{key: "app/db#port", want: "5432"},
+		{key: "app/plain", want: "v"},
+		{key: "app/db#missing", notFound: true},
+		{key: "app/none#password", notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := vs.Secret(context.Background(), tt.key)
+			if tt.notFound {
+				if !errors.Is(err, uni.ErrSecretNotFound) {
+					t.Fatalf("error = %v, want ErrSecretNotFound", err)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("Secret() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	vs.token = "wrong"
+	_, err := vs.Secret(context.Background(), "app/db#password")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 403: permission denied") {
+		t.Errorf("Secret() error = %v, want permission denied", err)
+	}
+}
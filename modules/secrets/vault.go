@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yonomesh/uni"
+)
+
+func init() {
+	uni.RegisterModule(VaultStore{})
+}
+
+// VaultStore reads secrets from a key/value secrets engine
+// (version 2) of HashiCorp Vault, or any server with a
+// compatible API.
+//
+// Keys have the form `<path>#<field>`, for example
+// {secret.vault.app/db#password} reads the field "password"
+// of the secret at "app/db". If the field is omitted, it
+// defaults to "value".
+type VaultStore struct {
+	// The address of the server, for example
+	// "https://vault.example.com:8200".
+	Address string `json:"address,omitempty"`
+
+	// The mount path of the secrets engine. Default: "secret".
+	Mount string `json:"mount,omitempty"`
+
+	// The token to authenticate with. Global placeholders are
+	// replaced, so it can be read from the environment with
+	// "{env.MY_TOKEN}". Default: the VAULT_TOKEN environment
+	// variable.
+	Token string `json:"token,omitempty"`
+
+	// The Vault Enterprise namespace to use, if any.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Timeout for requests to the server. Default: 10s.
+	Timeout uni.Duration `json:"timeout,omitempty"`
+
+	token  string
+	client *http.Client
+}
+
+// UniModule returns the Uni module information.
+func (VaultStore) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:  "secrets.stores.vault",
+		New: func() uni.Module { return new(VaultStore) },
+	}
+}
+
+// Provision sets up the store.
+func (vs *VaultStore) Provision(ctx uni.Context) error {
+	if vs.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if _, err := url.Parse(vs.Address); err != nil {
+		return fmt.Errorf("invalid address: %v", err)
+	}
+	if vs.Mount == "" {
+		vs.Mount = "secret"
+	}
+	vs.Mount = strings.Trim(vs.Mount, "/")
+
+	vs.token = os.Getenv("VAULT_TOKEN")
+	if vs.Token != "" {
+		vs.token = uni.NewReplacer().WithoutFile().ReplaceAll(vs.Token, "")
+	}
+	if vs.token == "" {
+		return fmt.Errorf("no token configured")
+	}
+
+	timeout := time.Duration(vs.Timeout)
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	vs.client = &http.Client{Timeout: timeout}
+
+	return nil
+}
+
+// Secret implements uni.SecretStore.
+func (vs *VaultStore) Secret(ctx context.Context, key string) ([]byte, error) {
+	path, field, ok := strings.Cut(key, "#")
+	if !ok {
+		field = "value"
+	}
+	path = strings.Trim(path, "/")
+	if path == "" || field == "" {
+		return nil, fmt.Errorf("invalid secret key: %s", key)
+	}
+
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if seg == "." || seg == ".." {
+			return nil, fmt.Errorf("invalid secret key: %s", key)
+		}
+		segments[i] = url.PathEscape(seg)
+	}
+	uri := strings.TrimSuffix(vs.Address, "/") + "/v1/" + vs.Mount + "/data/" + strings.Join(segments, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", vs.token)
+	if vs.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vs.Namespace)
+	}
+
+	resp, err := vs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, defaultMaxSecretSize))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", uni.ErrSecretNotFound, path)
+	case resp.StatusCode != http.StatusOK:
+		// error responses only carry error messages, never secrets
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(body, &errResp)
+		return nil, fmt.Errorf("reading %s: HTTP %d: %s", path, resp.StatusCode, strings.Join(errResp.Errors, "; "))
+	}
+
+	var secretResp struct {
+		Data struct {
+			Data map[string]json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secretResp); err != nil {
+		return nil, fmt.Errorf("reading %s: decoding response failed", path)
+	}
+	raw, ok := secretResp.Data.Data[field]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no field %s", uni.ErrSecretNotFound, path, field)
+	}
+
+	// string values are returned as-is, anything else as JSON
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return []byte(str), nil
+	}
+	return raw, nil
+}
+
+// Interface guards
+var (
+	_ uni.Provisioner = (*VaultStore)(nil)
+	_ uni.SecretStore = (*VaultStore)(nil)
+)
@@ -5,4 +5,7 @@ package standard
 
 import (
 	_ "github.com/yonomesh/uni/modules/demo"
+	_ "github.com/yonomesh/uni/modules/logging"
+	_ "github.com/yonomesh/uni/modules/secrets"
+	_ "github.com/yonomesh/uni/modules/tracing"
 )
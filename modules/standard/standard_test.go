@@ -0,0 +1,13 @@
+package standard
+
+import (
+	"testing"
+
+	"github.com/yonomesh/uni"
+)
+
+func TestStandardModulesFitTheirHosts(t *testing.T) {
+	for _, p := range uni.CheckModules() {
+		t.Error(p)
+	}
+}
@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/yonomesh/uni"
+)
+
+func init() {
+	uni.RegisterModule(FileExporter{})
+}
+
+// FileExporter appends spans to a file, one OTLP/JSON export
+// request per line, which the OpenTelemetry collector's
+// "otlpjsonfile" receiver can read.
+type FileExporter struct {
+	// The file to append the spans to.
+	Filename string `json:"filename,omitempty"`
+
+	out *spanFile
+}
+
+// spanFile is the file that a FileExporter writes to; it may
+// be closed while spans are being exported.
+type spanFile struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// UniModule returns the Uni module information.
+func (FileExporter) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:  "uni.tracing.exporters.file",
+		New: func() uni.Module { return new(FileExporter) },
+	}
+}
+
+// Provision opens the file.
+func (fe *FileExporter) Provision(ctx uni.Context) error {
+	if fe.Filename == "" {
+		return fmt.Errorf("filename is required")
+	}
+	file, err := os.OpenFile(fe.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	fe.out = &spanFile{file: file}
+	return nil
+}
+
+// ExportSpans implements uni.SpanExporter.
+func (fe *FileExporter) ExportSpans(_ context.Context, spans []uni.Span) error {
+	line, err := uni.MarshalOTLPJSON(spans)
+	if err != nil {
+		return err
+	}
+
+	fe.out.mu.Lock()
+	defer fe.out.mu.Unlock()
+	if fe.out.file == nil {
+		return os.ErrClosed
+	}
+	_, err = fe.out.file.Write(append(line, '\n'))
+	return err
+}
+
+// Cleanup closes the file.
+func (fe *FileExporter) Cleanup() error {
+	if fe.out == nil {
+		return nil
+	}
+	fe.out.mu.Lock()
+	defer fe.out.mu.Unlock()
+	if fe.out.file == nil {
+		return nil
+	}
+	err := fe.out.file.Close()
+	fe.out.file = nil
+	return err
+}
+
+// Interface guards
+var (
+	_ uni.Provisioner  = (*FileExporter)(nil)
+	_ uni.CleanerUpper = (*FileExporter)(nil)
+	_ uni.SpanExporter = (*FileExporter)(nil)
+)
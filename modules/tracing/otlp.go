@@ -0,0 +1,105 @@
+// Package tracing provides the standard span exporters, which
+// send the spans of module loads to where they can be viewed.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/yonomesh/uni"
+)
+
+func init() {
+	uni.RegisterModule(OTLPExporter{})
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector, or
+// any other server that accepts OTLP over HTTP with JSON
+// encoding.
+type OTLPExporter struct {
+	// The URL that spans are posted to.
+	// Default: "http://localhost:4318/v1/traces".
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Headers to send with each request, for example to
+	// authenticate. Global placeholders are replaced, so
+	// values can be read from the environment with
+	// "{env.MY_TOKEN}".
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Timeout for requests to the endpoint. Default: 10s.
+	Timeout uni.Duration `json:"timeout,omitempty"`
+
+	headers http.Header
+	client  *http.Client
+}
+
+// UniModule returns the Uni module information.
+func (OTLPExporter) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{
+		ID:  "uni.tracing.exporters.otlp",
+		New: func() uni.Module { return new(OTLPExporter) },
+	}
+}
+
+// Provision sets up the exporter.
+func (oe *OTLPExporter) Provision(ctx uni.Context) error {
+	if oe.Endpoint == "" {
+		oe.Endpoint = "http://localhost:4318/v1/traces"
+	}
+	if _, err := url.Parse(oe.Endpoint); err != nil {
+		return fmt.Errorf("invalid endpoint: %v", err)
+	}
+
+	repl := uni.NewReplacer().WithoutFile()
+	oe.headers = make(http.Header)
+	for name, value := range oe.Headers {
+		oe.headers.Set(name, repl.ReplaceAll(value, ""))
+	}
+	oe.headers.Set("Content-Type", "application/json")
+
+	timeout := time.Duration(oe.Timeout)
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	oe.client = &http.Client{Timeout: timeout}
+
+	return nil
+}
+
+// ExportSpans implements uni.SpanExporter.
+func (oe *OTLPExporter) ExportSpans(ctx context.Context, spans []uni.Span) error {
+	body, err := uni.MarshalOTLPJSON(spans)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oe.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = oe.headers.Clone()
+
+	resp, err := oe.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Interface guards
+var (
+	_ uni.Provisioner  = (*OTLPExporter)(nil)
+	_ uni.SpanExporter = (*OTLPExporter)(nil)
+)
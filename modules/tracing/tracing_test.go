@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yonomesh/uni"
+)
+
+var testSpans = []uni.Span{{
+	TraceID: uni.TraceID{1},
+	SpanID:  uni.SpanID{2},
+	Name:    "provision test",
+	Start:   time.Unix(1, 0),
+	End:     time.Unix(2, 0),
+}}
+
+func TestOTLPExporter(t *testing.T) {
+	t.Setenv("UNI_TEST_OTLP_TOKEN", "s3cret")
+
+	var gotAuth, gotType string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuth, gotType = r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		if strings.Contains(string(gotBody), "reject") {
+			http.Error(w, "no thanks", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	oe := &OTLPExporter{
+		Endpoint: srv.URL + "/v1/traces",
+		Headers:  map[string]string{"Authorization": "Bearer {env.UNI_TEST_OTLP_TOKEN}"},
+	}
+	if err := oe.Provision(uni.Context{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := oe.ExportSpans(context.Background(), testSpans); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer s3cret" || gotType != "application/json" {
+		t.Errorf("headers = %q, %q", gotAuth, gotType)
+	}
+	if !strings.Contains(string(gotBody), `"traceId":"01000000000000000000000000000000"`) {
+		t.Errorf("body = %s", gotBody)
+	}
+
+	rejected := []uni.Span{{Name: "reject", Start: time.Unix(1, 0), End: time.Unix(2, 0)}}
+	if err := oe.ExportSpans(context.Background(), rejected); err == nil || !strings.Contains(err.Error(), "HTTP 400: no thanks") {
+		t.Errorf("ExportSpans() error = %v, want HTTP 400", err)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	fe := &FileExporter{Filename: path}
+	if err := fe.Provision(uni.Context{}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := fe.ExportSpans(context.Background(), testSpans); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fe.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fe.ExportSpans(context.Background(), testSpans); err == nil {
+		t.Error("expected error exporting after cleanup")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines int
+	for sc := bufio.NewScanner(f); sc.Scan(); lines++ {
+		var req map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil || req["resourceSpans"] == nil {
+			t.Errorf("line %d is not an export request: %s", lines+1, sc.Bytes())
+		}
+	}
+	if lines != 2 {
+		t.Errorf("got %d lines, want 2", lines)
+	}
+
+	if err := (&FileExporter{}).Provision(uni.Context{}); err == nil {
+		t.Error("expected error without filename")
+	}
+}
@@ -0,0 +1,204 @@
+package uni
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// RegisterNamespace declares that modules in the given
+// namespace must implement T, which must be an interface
+// type. Host modules which load modules from the namespace
+// should register it from an init function, so that
+// CheckModules can report modules which do not fit. It
+// panics if T is not an interface, or if the namespace is
+// already registered with a different interface.
+func RegisterNamespace[T any](namespace string) {
+	iface := reflect.TypeFor[T]()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("namespace %s: %s is not an interface type", namespace, iface))
+	}
+
+	moduleNamespacesMu.Lock()
+	defer moduleNamespacesMu.Unlock()
+	if existing, ok := moduleNamespaces[namespace]; ok && existing != iface {
+		panic(fmt.Sprintf("namespace %s already registered with interface %s", namespace, existing))
+	}
+	moduleNamespaces[namespace] = iface
+}
+
+// NamespaceInterface returns the interface that modules in
+// the given namespace must implement, if it was registered.
+func NamespaceInterface(namespace string) (reflect.Type, bool) {
+	moduleNamespacesMu.RLock()
+	defer moduleNamespacesMu.RUnlock()
+	iface, ok := moduleNamespaces[namespace]
+	return iface, ok
+}
+
+// ModuleProblem is an inconsistency between a host and the
+// modules it loads, found by CheckModules.
+type ModuleProblem struct {
+	// Host is the type containing the module field, or the
+	// module that does not fit its namespace.
+	Host string `json:"host"`
+
+	// Field is the name of the module field, if any.
+	Field string `json:"field,omitempty"`
+
+	// Namespace is the namespace of the field or module.
+	Namespace string `json:"namespace"`
+
+	// Problem describes what is wrong.
+	Problem string `json:"problem"`
+}
+
+func (p ModuleProblem) String() string {
+	host := p.Host
+	if p.Field != "" {
+		host += "." + p.Field
+	}
+	return fmt.Sprintf("%s (namespace %s): %s", host, p.Namespace, p.Problem)
+}
+
+// CheckModules checks the module fields of all registered
+// modules and of Config, along with the structs they contain,
+// and the modules of all registered namespaces. It reports
+// module fields with a malformed tag or with a namespace that
+// has no registered modules, and modules which do not
+// implement the interface registered for their namespace (see
+// RegisterNamespace). It is meant to be run at startup or in
+// tests, to find modules whose IDs do not match the namespaces
+// their hosts load them from.
+func CheckModules() []ModuleProblem {
+	var problems []ModuleProblem
+
+	walkModuleFields(func(host reflect.Type, field reflect.StructField, tag string) {
+		problem := ModuleProblem{Host: host.String(), Field: field.Name}
+		opts, err := ParseStructTag(tag)
+		if err != nil {
+			problem.Problem = fmt.Sprintf("malformed tag: %v", err)
+			problems = append(problems, problem)
+			return
+		}
+		namespace, ok := opts["namespace"]
+		if !ok {
+			problem.Problem = "missing 'namespace' key in tag"
+			problems = append(problems, problem)
+			return
+		}
+		problem.Namespace = namespace
+		// apps are the top-level modules, and a build
+		// without any can still be used as a library
+		if namespace != "" && len(GetModules(namespace)) == 0 {
+			problem.Problem = "no modules registered in namespace"
+			problems = append(problems, problem)
+		}
+	})
+
+	moduleNamespacesMu.RLock()
+	names := make([]string, 0, len(moduleNamespaces))
+	for name := range moduleNamespaces {
+		names = append(names, name)
+	}
+	moduleNamespacesMu.RUnlock()
+	sort.Strings(names)
+
+	for _, namespace := range names {
+		iface, _ := NamespaceInterface(namespace)
+		for _, mi := range GetModules(namespace) {
+			if typ := reflect.TypeOf(mi.New()); !typ.Implements(iface) {
+				problems = append(problems, ModuleProblem{
+					Host:      string(mi.ID),
+					Namespace: namespace,
+					Problem:   fmt.Sprintf("module (%s) does not implement %s", typ, iface),
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(problems, func(a, b ModuleProblem) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return problems
+}
+
+// walkModuleFields calls fn for each exported field with a
+// module struct tag in Config, in the registered modules and
+// in the structs they contain, along with the struct type the
+// field belongs to.
+func walkModuleFields(fn func(host reflect.Type, field reflect.StructField, tag string)) {
+	visited := make(map[reflect.Type]bool)
+	var walk func(typ reflect.Type)
+	walk = func(typ reflect.Type) {
+		for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice ||
+			typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+			if typ == JSONRawMessageType {
+				return
+			}
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || visited[typ] {
+			return
+		}
+		visited[typ] = true
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if tag, ok := moduleTag(field); ok {
+				fn(typ, field, tag)
+			} else {
+				walk(field.Type)
+			}
+		}
+	}
+
+	walk(reflect.TypeFor[Config]())
+	for _, id := range Modules() {
+		mi, err := GetModule(id)
+		if err != nil {
+			continue
+		}
+		walk(reflect.TypeOf(mi.New()))
+	}
+}
+
+// moduleTag returns the module struct tag of field: the "uni"
+// tag or, if there is none, the "caddy" tag, which is still
+// accepted as an alias.
+func moduleTag(field reflect.StructField) (string, bool) {
+	if tag, ok := field.Tag.Lookup("uni"); ok {
+		return tag, true
+	}
+	return field.Tag.Lookup("caddy")
+}
+
+var (
+	moduleNamespaces   = make(map[string]reflect.Type)
+	moduleNamespacesMu sync.RWMutex
+)
+
+// warnModuleProblems logs the problems found by CheckModules
+// as warnings. Modules are registered when the program starts,
+// so it does so only for the first config that is run.
+func warnModuleProblems() {
+	checkModulesOnce.Do(func() {
+		for _, p := range CheckModules() {
+			Log().Warn("module does not fit its host or namespace",
+				zap.String("host", p.Host),
+				zap.String("field", p.Field),
+				zap.String("namespace", p.Namespace),
+				zap.String("problem", p.Problem))
+		}
+	})
+}
+
+var checkModulesOnce sync.Once
@@ -0,0 +1,94 @@
+package uni
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type checkHostModule struct {
+	Good    json.RawMessage   `uni:"namespace=check.writers inline_key=name"`
+	Alias   json.RawMessage   `caddy:"namespace=check.writers inline_key=name"`
+	Missing []json.RawMessage `uni:"namespace=check.nothing inline_key=name"`
+	Nested  struct {
+		Malformed json.RawMessage `uni:"namespace"`
+	}
+}
+
+func (checkHostModule) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "check.host", New: func() Module { return new(checkHostModule) }}
+}
+
+func TestCheckModules(t *testing.T) {
+	origRegistry := defaultRegistry
+	defaultRegistry = NewRegistry()
+	moduleNamespacesMu.Lock()
+	origNamespaces := maps.Clone(moduleNamespaces)
+	moduleNamespacesMu.Unlock()
+	defer func() {
+		defaultRegistry = origRegistry
+		moduleNamespacesMu.Lock()
+		moduleNamespaces = origNamespaces
+		moduleNamespacesMu.Unlock()
+	}()
+
+	RegisterModule(checkHostModule{})
+	RegisterModule(testMod{info: ModuleInfo{ID: "check.writers.plain", New: func() Module { return testMod{} }}})
+	RegisterNamespace[io.Writer]("check.writers")
+
+	var got []string
+	for _, p := range CheckModules() {
+		if p.Host == "uni.checkHostModule" || p.Host == "check.writers.plain" {
+			got = append(got, p.String())
+		}
+	}
+	want := []string{
+		"check.writers.plain (namespace check.writers): module (uni.testMod) does not implement io.Writer",
+		"uni.checkHostModule.Missing (namespace check.nothing): no modules registered in namespace",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CheckModules() =\n%q\nwant\n%q", got, want)
+	}
+
+	var malformed bool
+	for _, p := range CheckModules() {
+		malformed = malformed || p.Field == "Malformed"
+	}
+	if !malformed {
+		t.Error("malformed tag in nested struct not reported")
+	}
+
+	// the first config that is run warns about the problems
+	core, logs := observer.New(zapcore.WarnLevel)
+	defaultLoggerMu.Lock()
+	savedLogger := defaultLogger.logger
+	defaultLogger.logger = zap.New(core)
+	defaultLoggerMu.Unlock()
+	checkModulesOnce = sync.Once{}
+	ctx, err := Run(&Config{})
+	defaultLoggerMu.Lock()
+	defaultLogger.logger = savedLogger
+	defaultLoggerMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Close()
+	warned := logs.FilterField(zap.String("host", "check.writers.plain")).All()
+	if len(warned) != 1 || warned[0].ContextMap()["problem"] != "module (uni.testMod) does not implement io.Writer" {
+		t.Errorf("Run() warned %v, want the problem of check.writers.plain", warned)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for conflicting interface")
+		}
+	}()
+	RegisterNamespace[io.Reader]("check.writers")
+}
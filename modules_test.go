@@ -182,11 +182,12 @@ func TestModuleID_Name(t *testing.T) {
 
 // func RegisterModule(instance Module)
 func TestRegisterModule(t *testing.T) {
-	modulesMu.Lock()
-	modules = map[string]ModuleInfo{
+	t.Parallel()
+
+	r := NewRegistry()
+	r.modules = map[string]ModuleInfo{
 		"a.b.c": {ID: "a.b.c"},
 	}
-	modulesMu.Unlock()
 
 	tests := []struct {
 		name             string
@@ -259,6 +260,30 @@ func TestRegisterModule(t *testing.T) {
 			shouldPanic:      true,
 			panicMsgContains: "already registered",
 		},
+		{
+			name: "alias is a registered module",
+			mod: testMod{
+				info: ModuleInfo{
+					ID:      "a.b.d",
+					Aliases: []ModuleID{"a.b.c"},
+					New:     func() Module { return testMod{} },
+				},
+			},
+			shouldPanic:      true,
+			panicMsgContains: "alias already registered",
+		},
+		{
+			name: "alias is the module's own ID",
+			mod: testMod{
+				info: ModuleInfo{
+					ID:      "a.b.e",
+					Aliases: []ModuleID{"a.b.e"},
+					New:     func() Module { return testMod{} },
+				},
+			},
+			shouldPanic:      true,
+			panicMsgContains: "alias already registered",
+		},
 	}
 
 	for _, tt := range tests {
@@ -278,15 +303,17 @@ func TestRegisterModule(t *testing.T) {
 				}
 			}()
 
-			RegisterModule(tt.mod)
+			r.RegisterModule(tt.mod)
 		})
 	}
 }
 
 // func GetModule(name string) (ModuleInfo, error)
 func TestGetModule(t *testing.T) {
-	modulesMu.Lock()
-	modules = map[string]ModuleInfo{
+	t.Parallel()
+
+	r := NewRegistry()
+	r.modules = map[string]ModuleInfo{
 		"a":      {ID: "a"},
 		"a.b":    {ID: "a.b"},
 		"a.b.c":  {ID: "a.b.c"},
@@ -299,7 +326,6 @@ func TestGetModule(t *testing.T) {
 		"b.a.c":  {ID: "b.a.c"},
 		"c":      {ID: "c"},
 	}
-	modulesMu.Unlock()
 
 	tests := []struct {
 		name        string
@@ -329,7 +355,7 @@ func TestGetModule(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GetModule(tt.moduleName)
+			got, err := r.GetModule(tt.moduleName)
 
 			if tt.expectError {
 				if err == nil {
@@ -352,8 +378,10 @@ func TestGetModule(t *testing.T) {
 
 // func GetModules(scope string) []ModuleInfo
 func TestGetModules(t *testing.T) {
-	modulesMu.Lock()
-	modules = map[string]ModuleInfo{
+	t.Parallel()
+
+	r := NewRegistry()
+	r.modules = map[string]ModuleInfo{
 		"a":      {ID: "a"},
 		"a.b":    {ID: "a.b"},
 		"a.b.c":  {ID: "a.b.c"},
@@ -366,7 +394,6 @@ func TestGetModules(t *testing.T) {
 		"b.a.c":  {ID: "b.a.c"},
 		"c":      {ID: "c"},
 	}
-	modulesMu.Unlock()
 
 	for i, tc := range []struct {
 		input  string
@@ -409,7 +436,7 @@ func TestGetModules(t *testing.T) {
 			input: "asdf",
 		},
 	} {
-		actual := GetModules(tc.input)
+		actual := r.GetModules(tc.input)
 		if !reflect.DeepEqual(actual, tc.expect) {
 			t.Errorf("Test %d: Expected %v but got %v", i, tc.expect, actual)
 		}
@@ -516,9 +543,10 @@ func TestGetModuleID(t *testing.T) {
 
 // func Modules() []string
 func TestModules(t *testing.T) {
-	clear(modules)
-	modulesMu.Lock()
-	modules = map[string]ModuleInfo{
+	t.Parallel()
+
+	r := NewRegistry()
+	r.modules = map[string]ModuleInfo{
 		"a":      {ID: "a"},
 		"a.b":    {ID: "a.b"},
 		"a.b.c":  {ID: "a.b.c"},
@@ -531,11 +559,10 @@ func TestModules(t *testing.T) {
 		"b.a.c":  {ID: "b.a.c"},
 		"c":      {ID: "c"},
 	}
-	modulesMu.Unlock()
 
 	want := []string{"a", "a.b", "a.b.c", "a.b.cd", "a.c", "a.d", "b", "b.a", "b.a.c", "b.b", "c"}
 
-	got := Modules()
+	got := r.Modules()
 	fmt.Println(got)
 	if !slices.Equal(want, got) {
 		t.Fatalf("no")
@@ -5,18 +5,20 @@
 package uni
 
 import (
-	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/yonomesh/uuid"
 	"go.uber.org/zap"
 )
 
@@ -26,10 +28,9 @@ func NewReplacer() *Replacer {
 		static:   make(map[string]any),
 		mapMutex: &sync.RWMutex{},
 	}
-	rep.providers = []replacementProvider{
-		globalDefaultReplacementProvider{},
-		fileReplacementProvider{},
-		ReplacerFunc(rep.fromStatic),
+	rep.providers = []PlaceholderProvider{
+		namespacesProvider{},
+		staticProvider{},
 	}
 	return rep
 }
@@ -41,8 +42,8 @@ func NewEmptyReplacer() *Replacer {
 		static:   make(map[string]any),
 		mapMutex: &sync.RWMutex{},
 	}
-	rep.providers = []replacementProvider{
-		ReplacerFunc(rep.fromStatic),
+	rep.providers = []PlaceholderProvider{
+		staticProvider{},
 	}
 	return rep
 }
@@ -50,28 +51,74 @@ func NewEmptyReplacer() *Replacer {
 // Replacer can replace values in strings.
 // A default/empty Replacer is not valid;
 // use NewReplacer to make one.
+//
+// A Replacer is safe for concurrent use, except that Map
+// must not be called while it is in use.
 type Replacer struct {
-	providers []replacementProvider
+	providers []PlaceholderProvider
 	static    map[string]any
 	mapMutex  *sync.RWMutex
+
+	// withoutFile disables the {file.*} placeholders, also
+	// when they are looked up in a parent (see Child)
+	withoutFile bool
 }
 
 // WithoutFile returns a copy of the current Replacer
 // without support for the {file.*} placeholder, which
-// may be unsafe in some contexts.
+// may be unsafe in some contexts. The copy shares the
+// static values with r: values set on either are
+// visible in both.
 //
 // EXPERIMENTAL: Subject to change or removal.
 func (r *Replacer) WithoutFile() *Replacer {
-	rep := &Replacer{static: r.static}
-	for _, v := range r.providers {
-		if _, ok := v.(fileReplacementProvider); ok {
-			continue
-		}
-		rep.providers = append(rep.providers, v)
+	return &Replacer{
+		providers:   slices.Clone(r.providers),
+		static:      r.static,
+		mapMutex:    r.mapMutex,
+		withoutFile: true,
+	}
+}
+
+// Clone returns an independent copy of r: it has the same
+// providers and a copy of the static values, and values
+// set on one are not visible in the other.
+func (r *Replacer) Clone() *Replacer {
+	rep := &Replacer{
+		providers:   slices.Clone(r.providers),
+		mapMutex:    &sync.RWMutex{},
+		withoutFile: r.withoutFile,
 	}
+
+	r.mapMutex.RLock()
+	rep.static = make(map[string]any, len(r.static))
+	for k, v := range r.static {
+		rep.static[k] = v
+	}
+	r.mapMutex.RUnlock()
+
 	return rep
 }
 
+// Child returns a new Replacer which provides all values of
+// r, as they are at the time of replacement, but whose own
+// values, which take precedence, are set and deleted only in
+// the child. Deleting a value from the child makes the value
+// of r, if any, visible again. Children are cheap to create,
+// for example per request or per connection. Children of a
+// replacer without {file.*} placeholders do not have them
+// either.
+func (r *Replacer) Child() *Replacer {
+	return &Replacer{
+		providers: []PlaceholderProvider{
+			staticProvider{},
+			parentProvider{r},
+		},
+		mapMutex:    &sync.RWMutex{},
+		withoutFile: r.withoutFile,
+	}
+}
+
 // Map adds mapFunc to the list of value providers.
 // mapFunc will be executed only at replace-time.
 func (r *Replacer) Map(mapFunc ReplacerFunc) {
@@ -81,6 +128,9 @@ func (r *Replacer) Map(mapFunc ReplacerFunc) {
 // Set sets a custom variable to a static value.
 func (r *Replacer) Set(variable string, value any) {
 	r.mapMutex.Lock()
+	if r.static == nil {
+		r.static = make(map[string]any)
+	}
 	r.static[variable] = value
 	r.mapMutex.Unlock()
 }
@@ -88,8 +138,27 @@ func (r *Replacer) Set(variable string, value any) {
 // Get gets a value from the replacer. It returns
 // the value and whether the variable was known.
 func (r *Replacer) Get(variable string) (any, bool) {
-	for _, mapFunc := range r.providers {
-		if val, ok := mapFunc.replace(variable); ok {
+	return r.lookup(variable, r.withoutFile)
+}
+
+// lookup gets a value from the providers of r. If withoutFile
+// is true, {file.*} placeholders are not provided, not even by
+// the parents of r.
+func (r *Replacer) lookup(variable string, withoutFile bool) (any, bool) {
+	for _, provider := range r.providers {
+		var val any
+		var ok bool
+		switch p := provider.(type) {
+		case staticProvider:
+			val, ok = r.fromStatic(variable)
+		case namespacesProvider:
+			val, ok = p.replace(variable, withoutFile)
+		case parentProvider:
+			val, ok = p.parent.lookup(variable, withoutFile || p.parent.withoutFile)
+		default:
+			val, ok = p.Replace(variable)
+		}
+		if ok {
 			return val, true
 		}
 	}
@@ -119,9 +188,33 @@ func (r *Replacer) fromStatic(key string) (any, bool) {
 	return val, ok
 }
 
+// staticProvider marks where the values set with Set are
+// looked up among the providers of a replacer. It provides
+// the values of whichever replacer it belongs to, so copies
+// of the replacer need not rebind it; the lookup itself is
+// done by Replacer.lookup.
+type staticProvider struct{}
+
+// Replace implements PlaceholderProvider. It provides nothing,
+// since it does not know the replacer it belongs to.
+func (staticProvider) Replace(string) (any, bool) { return nil, false }
+
+// parentProvider provides the values of the parent of a child
+// replacer (see Child). Like staticProvider, it is handled by
+// Replacer.lookup, so that the child's settings apply to the
+// lookup in the parent as well.
+type parentProvider struct{ parent *Replacer }
+
+// Replace implements PlaceholderProvider.
+func (pp parentProvider) Replace(key string) (any, bool) {
+	return pp.parent.Get(key)
+}
+
 // ReplaceOrErr is like ReplaceAll, but any placeholders
 // that are empty or not recognized will cause an error to
-// be returned.
+// be returned. If either check is enabled, placeholders
+// whose value can not be produced (see PlaceholderError)
+// or whose transform fails are an error as well.
 func (r *Replacer) ReplaceOrErr(input string, errOnEmpty, errOnUnknown bool) (string, error) {
 	return r.replace(input, "", false, errOnEmpty, errOnUnknown, nil)
 }
@@ -210,11 +303,31 @@ scan:
 		// write the substring from the last cursor to this point
 		sb.WriteString(input[lastWriteCursor:i])
 
-		// trim opening bracket
-		key := input[i+1 : end]
+		// trim opening bracket, and separate the key from
+		// its default value and transforms, if any
+		expr := parsePlaceholder(input[i+1 : end])
+		key := expr.key
 
 		// try to get a value for this key, handle empty values accordingly
 		val, found := r.Get(key)
+		if perr, ok := val.(PlaceholderError); ok {
+			// the provider failed to produce a value; unless
+			// there is a default, this is an error if errors
+			// are wanted, and an empty value otherwise
+			if !expr.hasDefault {
+				if errOnEmpty || errOnUnknown {
+					return "", fmt.Errorf("evaluating placeholder %s%s%s: %w",
+						string(phOpen), key, string(phClose), perr.Err)
+				}
+				Log().Error("placeholder: evaluation failed",
+					zap.String("placeholder", key),
+					zap.Error(perr.Err))
+			}
+			val = nil
+		}
+		if expr.hasDefault && (!found || ToString(val) == "") {
+			val, found = expr.def, true
+		}
 		if !found {
 			// placeholder is unknown (unrecognized); handle accordingly
 			if errOnUnknown {
@@ -228,6 +341,22 @@ scan:
 			}
 		}
 
+		// run the value through the placeholder's pipeline; if a
+		// transform fails, the value is treated as empty
+		if found && len(expr.transforms) > 0 {
+			out, err := applyTransforms(ToString(val), expr.transforms)
+			if err != nil {
+				if errOnEmpty || errOnUnknown {
+					return "", fmt.Errorf("evaluating placeholder %s%s%s: %v",
+						string(phOpen), key, string(phClose), err)
+				}
+				Log().Error("placeholder: transform failed",
+					zap.String("placeholder", key),
+					zap.Error(err))
+			}
+			val = out
+		}
+
 		// apply any transformations
 		if f != nil {
 			var err error
@@ -272,6 +401,10 @@ func ToString(val any) string {
 		return ""
 	case string:
 		return v
+	case PlaceholderError:
+		return ""
+	case Secret:
+		return v.Reveal()
 	case fmt.Stringer:
 		return v.String()
 	case error:
@@ -314,54 +447,42 @@ func ToString(val any) string {
 // not recognize the key, false should be returned.
 type ReplacerFunc func(key string) (any, bool)
 
-func (f ReplacerFunc) replace(key string) (any, bool) {
+// Replace implements PlaceholderProvider.
+func (f ReplacerFunc) Replace(key string) (any, bool) {
 	return f(key)
 }
 
-// replacementProvider is a type that can provide replacements
-// for placeholders. Allows for type assertion to determine
-// which type of provider it is.
-type replacementProvider interface {
-	replace(key string) (any, bool)
-}
-
-// fileReplacementProvider handles {file.*} replacements,
-// reading a file from disk and replacing with its contents.
-type fileReplacementProvider struct{}
-
-func (f fileReplacementProvider) replace(key string) (any, bool) {
-	if !strings.HasPrefix(key, filePrefix) {
-		return nil, false
-	}
-
-	filename := key[len(filePrefix):]
-	maxSize := 1024 * 1024
-	body, err := readFileIntoBuffer(filename, maxSize)
-	if err != nil {
-		wd, _ := os.Getwd()
-		Log().Error("placeholder: failed to read file",
-			zap.String("file", filename),
-			zap.String("working_dir", wd),
-			zap.Error(err))
-		return nil, true
-	}
-	body = bytes.TrimSuffix(body, []byte("\n"))
-	body = bytes.TrimSuffix(body, []byte("\r"))
-	return string(body), true
-}
-
 // globalDefaultReplacementProvider handles replacements
 // that can be used in any context, such as system variables,
 // time, or environment variables.
 type globalDefaultReplacementProvider struct{}
 
-func (f globalDefaultReplacementProvider) replace(key string) (any, bool) {
+// Replace implements PlaceholderProvider.
+func (f globalDefaultReplacementProvider) Replace(key string) (any, bool) {
 	// check environment variable
 	const envPrefix = "env."
 	if strings.HasPrefix(key, envPrefix) {
 		return os.Getenv(key[len(envPrefix):]), true
 	}
 
+	const timeFormatPrefix = "time.now.format."
+	if strings.HasPrefix(key, timeFormatPrefix) {
+		return nowFunc().Format(key[len(timeFormatPrefix):]), true
+	}
+
+	const randomHexPrefix = "random.hex."
+	if strings.HasPrefix(key, randomHexPrefix) {
+		n, err := strconv.Atoi(key[len(randomHexPrefix):])
+		if err != nil || n < 1 || n > maxRandomHexLength {
+			return nil, false
+		}
+		b := make([]byte, (n+1)/2)
+		if _, err := rand.Read(b); err != nil {
+			return PlaceholderError{Err: err}, true
+		}
+		return hex.EncodeToString(b)[:n], true
+	}
+
 	switch key {
 	case "system.hostname":
 		// OK if there is an error; just return empty string
@@ -377,6 +498,30 @@ func (f globalDefaultReplacementProvider) replace(key string) (any, bool) {
 		return wd, true
 	case "system.arch":
 		return runtime.GOARCH, true
+	case "system.cpus":
+		return strconv.Itoa(runtime.NumCPU()), true
+	case "system.memory":
+		// OK if the total is unknown; just return empty string
+		return systemMemory(), true
+	case "process.pid":
+		return strconv.Itoa(os.Getpid()), true
+	case "process.uptime":
+		return strconv.FormatInt(int64(nowFunc().Sub(processStart)/time.Second), 10), true
+	case "uni.version":
+		simple, _ := Version()
+		return simple, true
+	case "uuid.v4":
+		id, err := uuid.NewV4()
+		if err != nil {
+			return PlaceholderError{Err: err}, true
+		}
+		return id.String(), true
+	case "uuid.v7":
+		id, err := uuid.NewV7()
+		if err != nil {
+			return PlaceholderError{Err: err}, true
+		}
+		return id.String(), true
 	case "time.now":
 		return nowFunc(), true
 	case "time.now.http":
@@ -384,6 +529,8 @@ func (f globalDefaultReplacementProvider) replace(key string) (any, bool) {
 		// to generate the correct format.
 		// https://github.com/caddyserver/caddy/issues/5773
 		return nowFunc().UTC().Format(http.TimeFormat), true
+	case "time.now.rfc3339":
+		return nowFunc().Format(time.RFC3339), true
 	case "time.now.common_log":
 		return nowFunc().Format("02/Jan/2006:15:04:05 -0700"), true
 	case "time.now.year":
@@ -397,23 +544,19 @@ func (f globalDefaultReplacementProvider) replace(key string) (any, bool) {
 	return nil, false
 }
 
-// readFileIntoBuffer reads the file at filePath into a size limited buffer.
-func readFileIntoBuffer(filename string, size int) ([]byte, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+// PlaceholderError may be returned as the value of a placeholder
+// by a PlaceholderProvider that recognizes the key but fails to
+// produce a value, for example because a file does not exist.
+// ReplaceOrErr returns the error, unless the placeholder has a
+// default value; otherwise the placeholder is treated as empty.
+type PlaceholderError struct {
+	Err error
+}
 
-	buffer := make([]byte, size)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return nil, err
-	}
+func (e PlaceholderError) Error() string { return e.Err.Error() }
 
-	// slice the buffer to the actual size
-	return buffer[:n], nil
-}
+// Unwrap returns the underlying error.
+func (e PlaceholderError) Unwrap() error { return e.Err }
 
 // ReplacementFunc is a function that is called when a
 // replacement is being performed. It receives the
@@ -423,6 +566,34 @@ func readFileIntoBuffer(filename string, size int) ([]byte, error) {
 // that errors are sometimes ignored by replacers.
 type ReplacementFunc func(variable string, val any) (any, error)
 
+// systemMemory returns the total physical memory in bytes, or
+// an empty string if it can not be determined. Only Linux is
+// supported for now.
+func systemMemory() string {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(data)) {
+		rest, ok := strings.CutPrefix(line, "MemTotal:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatInt(kb*1024, 10)
+	}
+	return ""
+}
+
+// maxRandomHexLength limits the length of {random.hex.N}.
+const maxRandomHexLength = 1024
+
+// processStart is the time the process started, roughly.
+var processStart = time.Now()
+
 // nowFunc is a variable so tests can change it
 // in order to obtain a deterministic time.
 var nowFunc = time.Now
@@ -431,5 +602,3 @@ var nowFunc = time.Now
 const ReplacerCtxKey CtxKey = "replacer"
 
 const phOpen, phClose, phEscape = '{', '}', '\\'
-
-const filePrefix = "file."
@@ -0,0 +1,41 @@
+package uni
+
+import "testing"
+
+const benchTemplate = `{"host": "{host}", "port": {port}, "escaped": "\{not a placeholder\}", "path": "{path:/index.html}"}`
+
+func benchReplacer() *Replacer {
+	rep := NewEmptyReplacer()
+	rep.Set("host", "example.com")
+	rep.Set("port", "8080")
+	return rep
+}
+
+func BenchmarkReplacerReplaceAll(b *testing.B) {
+	rep := benchReplacer()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = rep.ReplaceAll(benchTemplate, "")
+	}
+}
+
+func BenchmarkTemplateReplaceAll(b *testing.B) {
+	tmpl, err := benchReplacer().Compile(benchTemplate)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = tmpl.ReplaceAll("")
+	}
+}
+
+func BenchmarkReplacerChild(b *testing.B) {
+	rep := benchReplacer()
+	b.ReportAllocs()
+	for b.Loop() {
+		child := rep.Child()
+		child.Set("path", "/")
+		_ = child.ReplaceAll(benchTemplate, "")
+	}
+}
@@ -0,0 +1,364 @@
+package uni
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FilePlaceholders configures the {file.*} placeholders, which
+// are replaced with the contents of a file (without a trailing
+// newline).
+//
+// Without this config, any file that uni can read may be used,
+// up to 1 MiB. Setting Roots restricts the placeholders to files
+// within the given directories.
+//
+// File contents are cached and only read again when the size or
+// modification time of the file changes.
+type FilePlaceholders struct {
+	// The directories that files must be in. Relative paths
+	// in placeholders are resolved against the working
+	// directory before they are checked.
+	Roots []string `json:"roots,omitempty"`
+
+	// How symbolic links are treated when Roots are set:
+	//
+	// - `follow` (default): links are followed, as long as
+	//   they do not lead out of the root
+	// - `deny`: files whose path contains a link below the
+	//   root are rejected
+	// - `allow`: links are followed anywhere; only the path
+	//   given in the placeholder has to be within a root
+	Symlinks string `json:"symlinks,omitempty"`
+
+	// The maximum size of a file, in bytes. Placeholders for
+	// larger files cause an error. Default: 1 MiB.
+	MaxSize int64 `json:"max_size,omitempty"`
+
+	roots []string // absolute
+
+	cacheMu sync.Mutex
+	cache   map[string]cachedFile
+}
+
+// cachedFile is the contents of a file, valid for as
+// long as the size and modification time match.
+type cachedFile struct {
+	size    int64
+	modTime time.Time
+	value   string
+}
+
+// Provision checks the roots and makes the policy apply to
+// {file.*} placeholders, replacing any previous policy.
+func (fp *FilePlaceholders) Provision(ctx Context) error {
+	if err := fp.setup(); err != nil {
+		return err
+	}
+
+	activeFilePlaceholdersMu.Lock()
+	activeFilePlaceholders = fp
+	activeFilePlaceholdersMu.Unlock()
+
+	return nil
+}
+
+// setup checks the roots and fills in the defaults of the
+// policy, without making it apply to placeholders.
+func (fp *FilePlaceholders) setup() error {
+	switch fp.Symlinks {
+	case "":
+		fp.Symlinks = "follow"
+	case "follow", "deny", "allow":
+	default:
+		return fmt.Errorf("unrecognized symlinks mode: %s", fp.Symlinks)
+	}
+	if fp.MaxSize == 0 {
+		fp.MaxSize = defaultMaxPlaceholderFileSize
+	}
+
+	fp.roots = nil
+	for _, root := range fp.Roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return fmt.Errorf("root %s: %v", root, err)
+		}
+		if fi, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("root %s: %v", root, err)
+		} else if !fi.IsDir() {
+			return fmt.Errorf("root %s: not a directory", root)
+		}
+		fp.roots = append(fp.roots, abs)
+	}
+	return nil
+}
+
+// Cleanup restores the default policy, unless the policy was
+// replaced by another one already.
+func (fp *FilePlaceholders) Cleanup() error {
+	activeFilePlaceholdersMu.Lock()
+	if activeFilePlaceholders == fp {
+		activeFilePlaceholders = nil
+	}
+	activeFilePlaceholdersMu.Unlock()
+	return nil
+}
+
+// readFile returns the contents of the named file, if the
+// policy allows reading it.
+func (fp *FilePlaceholders) readFile(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := fp.open(name, abs)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return "", fmt.Errorf("%s is a directory", name)
+	}
+	if fi.Size() > fp.MaxSize {
+		return "", fmt.Errorf("%s is larger than %d bytes", name, fp.MaxSize)
+	}
+
+	fp.cacheMu.Lock()
+	cached, ok := fp.cache[abs]
+	fp.cacheMu.Unlock()
+	if ok && cached.size == fi.Size() && cached.modTime.Equal(fi.ModTime()) {
+		return cached.value, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(f, fp.MaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > fp.MaxSize {
+		return "", fmt.Errorf("%s is larger than %d bytes", name, fp.MaxSize)
+	}
+	body = bytes.TrimSuffix(body, []byte("\n"))
+	body = bytes.TrimSuffix(body, []byte("\r"))
+	value := string(body)
+
+	fp.cacheMu.Lock()
+	if fp.cache == nil || len(fp.cache) >= maxCachedPlaceholderFiles {
+		fp.cache = make(map[string]cachedFile)
+	}
+	fp.cache[abs] = cachedFile{size: fi.Size(), modTime: fi.ModTime(), value: value}
+	fp.cacheMu.Unlock()
+
+	return value, nil
+}
+
+// open opens the file at abs, the absolute form of name,
+// enforcing the roots and symlinks mode.
+func (fp *FilePlaceholders) open(name, abs string) (*os.File, error) {
+	if len(fp.roots) == 0 {
+		return os.Open(abs)
+	}
+
+	var (
+		root string
+		rel  string
+		ok   bool
+	)
+	for _, root = range fp.roots {
+		if rel, ok = pathWithin(abs, root); ok {
+			break
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s is not within an allowed root", name)
+	}
+	if fp.Symlinks == "allow" {
+		return os.Open(abs)
+	}
+
+	f, err := openInRoot(root, rel, fp.Symlinks == "follow")
+	switch {
+	case errors.Is(err, errSymlink):
+		return nil, fmt.Errorf("%s: symbolic links are not allowed", name)
+	case errors.Is(err, errOutsideRoot):
+		return nil, fmt.Errorf("%s: symbolic link leads out of the allowed root", name)
+	}
+	return f, err
+}
+
+var (
+	errSymlink     = errors.New("symbolic link")
+	errOutsideRoot = errors.New("outside of root")
+)
+
+// maxSymlinks is how many symbolic links openInRoot follows
+// for one path.
+const maxSymlinks = 40
+
+// openInRoot opens the file at rel within the directory dir.
+// Symbolic links below dir are resolved one at a time if
+// follow is true, and are an error (errSymlink) otherwise.
+// Links may not lead out of dir (errOutsideRoot).
+//
+// All lookups go through an os.Root, so nothing outside of
+// dir is opened even if links are swapped in concurrently,
+// and the opened file must be the one that was checked.
+func openInRoot(dir, rel string, follow bool) (*os.File, error) {
+	r, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var (
+		resolved []string // path elements that are not links
+		links    int
+	)
+	pending := strings.Split(rel, string(filepath.Separator))
+	for len(pending) > 0 {
+		elem := pending[0]
+		pending = pending[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return nil, errOutsideRoot
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		path := filepath.Join(append(resolved, elem)...)
+		fi, err := r.Lstat(path)
+		if err != nil {
+			return nil, err
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			resolved = append(resolved, elem)
+			continue
+		}
+		if !follow {
+			return nil, errSymlink
+		}
+		if links++; links > maxSymlinks {
+			return nil, fmt.Errorf("too many symbolic links")
+		}
+		target, err := r.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.IsAbs(target) {
+			target = filepath.Clean(target)
+			relTarget, ok := pathWithin(target, dir)
+			if !ok {
+				// dir itself may be reached through links
+				if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+					relTarget, ok = pathWithin(target, realDir)
+				}
+			}
+			if !ok {
+				return nil, errOutsideRoot
+			}
+			target = relTarget
+			resolved = nil
+		}
+		pending = append(strings.Split(target, string(filepath.Separator)), pending...)
+	}
+
+	path := filepath.Join(resolved...)
+	if path == "" {
+		path = "."
+	}
+	checked, err := r.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := f.Stat(); err != nil || !os.SameFile(fi, checked) {
+		f.Close()
+		return nil, fmt.Errorf("%s changed while it was opened", path)
+	}
+	return f, nil
+}
+
+// pathWithin returns path relative to dir, if path is within dir.
+// Both must be absolute and clean.
+func pathWithin(path, dir string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+// fileReplacementProvider handles {file.*} replacements,
+// reading a file from disk and replacing with its contents,
+// subject to the active FilePlaceholders policy.
+type fileReplacementProvider struct {
+	// policy, if set, is applied instead of the active one
+	policy *FilePlaceholders
+}
+
+// Replace implements PlaceholderProvider.
+func (f fileReplacementProvider) Replace(key string) (any, bool) {
+	if !strings.HasPrefix(key, filePrefix) {
+		return nil, false
+	}
+
+	fp := f.policy
+	if fp == nil {
+		activeFilePlaceholdersMu.RLock()
+		fp = activeFilePlaceholders
+		activeFilePlaceholdersMu.RUnlock()
+	}
+	if fp == nil {
+		fp = defaultFilePlaceholders
+	}
+
+	val, err := fp.readFile(key[len(filePrefix):])
+	if err != nil {
+		return PlaceholderError{Err: err}, true
+	}
+	return val, true
+}
+
+const (
+	filePrefix = "file."
+
+	defaultMaxPlaceholderFileSize = 1024 * 1024
+	maxCachedPlaceholderFiles     = 1024
+)
+
+var (
+	// defaultFilePlaceholders is the policy used when none is configured
+	defaultFilePlaceholders = &FilePlaceholders{
+		Symlinks: "allow",
+		MaxSize:  defaultMaxPlaceholderFileSize,
+	}
+
+	activeFilePlaceholders   *FilePlaceholders
+	activeFilePlaceholdersMu sync.RWMutex
+)
+
+// Interface guards
+var (
+	_ Provisioner  = (*FilePlaceholders)(nil)
+	_ CleanerUpper = (*FilePlaceholders)(nil)
+)
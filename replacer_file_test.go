@@ -0,0 +1,173 @@
+package uni
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilePlaceholders(t *testing.T) {
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "secret"), "outside")
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "name"), "uni\n")
+	writeFile(t, filepath.Join(root, "big"), "0123456789")
+	if err := os.Symlink(filepath.Join(root, "name"), filepath.Join(root, "inner_link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "outer_link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "sub", "name"), "sub")
+	if err := os.Symlink("sub", filepath.Join(root, "dir_link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "..", filepath.Base(outside), "secret"), filepath.Join(root, "sub", "up_link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		symlinks string
+		file     string
+		want     string
+		wantErr  bool
+	}{
+		{file: "name", want: "uni"},
+		{file: "inner_link", want: "uni"},
+		{file: "outer_link", wantErr: true},
+		{file: "dir_link/name", want: "sub"},
+		{file: "dir_link/up_link", wantErr: true},
+		{file: "big", wantErr: true},
+		{file: "missing", wantErr: true},
+		{file: "../" + filepath.Base(outside) + "/secret", wantErr: true},
+		{symlinks: "deny", file: "name", want: "uni"},
+		{symlinks: "deny", file: "inner_link", wantErr: true},
+		{symlinks: "deny", file: "dir_link/name", wantErr: true},
+		{symlinks: "deny", file: "sub/name", want: "sub"},
+		{symlinks: "allow", file: "outer_link", want: "outside"},
+		{symlinks: "allow", file: "../" + filepath.Base(outside) + "/secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symlinks+"/"+tt.file, func(t *testing.T) {
+			fp := &FilePlaceholders{Roots: []string{root}, Symlinks: tt.symlinks, MaxSize: 8}
+			if err := fp.Provision(Context{}); err != nil {
+				t.Fatal(err)
+			}
+			defer fp.Cleanup()
+
+			got, err := NewReplacer().ReplaceOrErr("{file."+filepath.Join(root, tt.file)+"}", false, true)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ReplaceOrErr() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if err := (&FilePlaceholders{Symlinks: "sometimes"}).Provision(Context{}); err == nil {
+		t.Error("expected error for unknown symlinks mode")
+	}
+	if err := (&FilePlaceholders{Roots: []string{filepath.Join(root, "name")}}).Provision(Context{}); err == nil {
+		t.Error("expected error for root that is not a directory")
+	}
+}
+
+func TestRunFilePlaceholders(t *testing.T) {
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "secret"), "outside")
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "name"), "uni")
+
+	ctx, err := Run(&Config{FilePlaceholders: &FilePlaceholders{Roots: []string{root}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Close()
+
+	rep := NewReplacer()
+	if got, err := rep.ReplaceOrErr("{file."+filepath.Join(root, "name")+"}", false, true); err != nil || got != "uni" {
+		t.Errorf("file within root: got %q, %v", got, err)
+	}
+	if got, err := rep.ReplaceOrErr("{file."+filepath.Join(outside, "secret")+"}", false, true); err == nil {
+		t.Errorf("file outside the roots of the running config: got %q, want error", got)
+	}
+}
+
+func TestFilePlaceholdersErrorsAndDefaults(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	rep := NewReplacer()
+
+	_, err := rep.ReplaceOrErr("{file."+missing+"}", false, true)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReplaceOrErr() error = %v, want not exist", err)
+	}
+	if got, err := rep.ReplaceOrErr("{file."+missing+":fallback}", true, true); err != nil || got != "fallback" {
+		t.Errorf("ReplaceOrErr() with default = %q, %v", got, err)
+	}
+	if got := rep.ReplaceKnown("[{file."+missing+"}]", ""); got != "[]" {
+		t.Errorf("ReplaceKnown() = %q, want empty value", got)
+	}
+}
+
+func TestFilePlaceholdersCache(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "f")
+	writeFile(t, name, "one")
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	fp := &FilePlaceholders{}
+	if err := fp.Provision(Context{}); err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Cleanup()
+
+	read := func() string {
+		t.Helper()
+		val, err := fp.readFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return val
+	}
+	if got := read(); got != "one" {
+		t.Fatalf("readFile() = %q", got)
+	}
+
+	// same size and modification time: served from the cache
+	writeFile(t, name, "two")
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "one" {
+		t.Errorf("readFile() = %q, want cached value", got)
+	}
+
+	// a newer modification time invalidates the cache
+	if err := os.Chtimes(name, mtime.Add(time.Second), mtime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "two" {
+		t.Errorf("readFile() = %q, want new contents", got)
+	}
+}
+
+func writeFile(t *testing.T, name, contents string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,221 @@
+package uni
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+func init() {
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "env",
+		Description: "Environment variables",
+		Placeholders: []PlaceholderDoc{
+			{Key: "env.*", Description: "The value of the environment variable *, or empty if it is not set"},
+		},
+		Provider: globalDefaultReplacementProvider{},
+	})
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "system",
+		Description: "Information about the system uni is running on",
+		Placeholders: []PlaceholderDoc{
+			{Key: "system.hostname", Description: "The system's host name"},
+			{Key: "system.slash", Description: "The system's path separator"},
+			{Key: "system.os", Description: "The operating system, as in GOOS"},
+			{Key: "system.wd", Description: "The current working directory"},
+			{Key: "system.arch", Description: "The system architecture, as in GOARCH"},
+			{Key: "system.cpus", Description: "The number of logical CPUs"},
+			{Key: "system.memory", Description: "The total physical memory in bytes, or empty if unknown"},
+		},
+		Provider: globalDefaultReplacementProvider{},
+	})
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "time",
+		Description: "The current time",
+		Placeholders: []PlaceholderDoc{
+			{Key: "time.now", Description: "The current time as a Go time.Time value"},
+			{Key: "time.now.http", Description: "The current time in the format used in HTTP headers"},
+			{Key: "time.now.rfc3339", Description: "The current time in RFC 3339 format"},
+			{Key: "time.now.common_log", Description: "The current time in Common Log Format"},
+			{Key: "time.now.format.*", Description: "The current time in the Go time layout *; colons in the layout must be escaped as \\:"},
+			{Key: "time.now.year", Description: "The current year"},
+			{Key: "time.now.unix", Description: "The current time as a Unix timestamp in seconds"},
+			{Key: "time.now.unix_ms", Description: "The current time as a Unix timestamp in milliseconds"},
+		},
+		Provider: globalDefaultReplacementProvider{},
+	})
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "process",
+		Description: "Information about the uni process",
+		Placeholders: []PlaceholderDoc{
+			{Key: "process.pid", Description: "The process ID"},
+			{Key: "process.uptime", Description: "The time since the process started, in whole seconds"},
+		},
+		Provider: globalDefaultReplacementProvider{},
+	})
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "uni",
+		Description: "Information about uni itself",
+		Placeholders: []PlaceholderDoc{
+			{Key: "uni.version", Description: "The version of uni"},
+		},
+		Provider: globalDefaultReplacementProvider{},
+	})
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "uuid",
+		Description: "Newly generated UUIDs; each placeholder yields a new one",
+		Placeholders: []PlaceholderDoc{
+			{Key: "uuid.v4", Description: "A random (version 4) UUID"},
+			{Key: "uuid.v7", Description: "A time-ordered (version 7) UUID"},
+		},
+		Provider: globalDefaultReplacementProvider{},
+	})
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "random",
+		Description: "Random values from a cryptographically secure source",
+		Placeholders: []PlaceholderDoc{
+			{Key: "random.hex.*", Description: "* random hexadecimal digits, up to 1024"},
+		},
+		Provider: globalDefaultReplacementProvider{},
+	})
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "file",
+		Description: "Contents of files",
+		Placeholders: []PlaceholderDoc{
+			{Key: "file.*", Description: "The contents of the file at path *, without a trailing newline"},
+		},
+		Provider: fileReplacementProvider{},
+	})
+}
+
+// PlaceholderProvider provides values for placeholders.
+type PlaceholderProvider interface {
+	// Replace returns the value of the placeholder with
+	// the given key (without braces) and true if the
+	// provider recognizes the key, even if the value is
+	// empty. If the key is not recognized, it returns
+	// false.
+	Replace(key string) (any, bool)
+}
+
+// PlaceholderNamespace describes a group of placeholders
+// that share a prefix, such as {system.*}, and the
+// provider of their values.
+type PlaceholderNamespace struct {
+	// Name is the prefix of all placeholders in the
+	// namespace, without the trailing dot: the namespace
+	// "router" covers {router.*}. It may contain dots.
+	Name string `json:"name"`
+
+	// Description briefly describes the namespace.
+	Description string `json:"description,omitempty"`
+
+	// Placeholders documents the placeholders in the
+	// namespace. A key ending in "*" documents a family
+	// of placeholders, for example "env.*".
+	Placeholders []PlaceholderDoc `json:"placeholders,omitempty"`
+
+	// Provider provides the values of the placeholders.
+	// It receives the full key, including the namespace.
+	Provider PlaceholderProvider `json:"-"`
+}
+
+// PlaceholderDoc documents a placeholder.
+type PlaceholderDoc struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+}
+
+// RegisterPlaceholderNamespace registers a namespace of
+// placeholders, which replacers made with NewReplacer will
+// provide. It should be called from an init function.
+//
+// It panics if the namespace is invalid, or if it is the
+// same as, contains or is contained by a namespace that is
+// already registered, since placeholders in one of them
+// could not be told apart from placeholders in the other.
+func RegisterPlaceholderNamespace(ns PlaceholderNamespace) {
+	if ns.Name == "" || strings.HasPrefix(ns.Name, ".") || strings.HasSuffix(ns.Name, ".") ||
+		strings.ContainsAny(ns.Name, "{}:| ") {
+		panic(fmt.Sprintf("invalid placeholder namespace: %q", ns.Name))
+	}
+	if ns.Provider == nil {
+		panic(fmt.Sprintf("placeholder namespace %s: provider is nil", ns.Name))
+	}
+	for _, doc := range ns.Placeholders {
+		if !strings.HasPrefix(doc.Key, ns.Name+".") {
+			panic(fmt.Sprintf("placeholder namespace %s: placeholder %s is outside of the namespace", ns.Name, doc.Key))
+		}
+	}
+
+	placeholderNamespacesMu.Lock()
+	defer placeholderNamespacesMu.Unlock()
+	for name := range placeholderNamespaces {
+		if name == ns.Name ||
+			strings.HasPrefix(name, ns.Name+".") ||
+			strings.HasPrefix(ns.Name, name+".") {
+			panic(fmt.Sprintf("placeholder namespace %s conflicts with registered namespace %s", ns.Name, name))
+		}
+	}
+	placeholderNamespaces[ns.Name] = ns
+}
+
+// PlaceholderNamespaces returns all registered placeholder
+// namespaces, ordered by name.
+func PlaceholderNamespaces() []PlaceholderNamespace {
+	placeholderNamespacesMu.RLock()
+	defer placeholderNamespacesMu.RUnlock()
+	all := make([]PlaceholderNamespace, 0, len(placeholderNamespaces))
+	for _, ns := range placeholderNamespaces {
+		all = append(all, ns)
+	}
+	slices.SortFunc(all, func(a, b PlaceholderNamespace) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return all
+}
+
+// lookupPlaceholderNamespace returns the namespace that key
+// belongs to, if any. Since registered namespaces never
+// contain each other, at most one can match.
+func lookupPlaceholderNamespace(key string) (PlaceholderNamespace, bool) {
+	placeholderNamespacesMu.RLock()
+	defer placeholderNamespacesMu.RUnlock()
+	for i := 0; i < len(key); i++ {
+		if key[i] != '.' {
+			continue
+		}
+		if ns, ok := placeholderNamespaces[key[:i]]; ok {
+			return ns, true
+		}
+	}
+	return PlaceholderNamespace{}, false
+}
+
+// namespacesProvider provides the placeholders of all
+// registered namespaces.
+type namespacesProvider struct{}
+
+// Replace implements PlaceholderProvider.
+func (np namespacesProvider) Replace(key string) (any, bool) {
+	return np.replace(key, false)
+}
+
+// replace is like Replace, but if withoutFile is true, it does
+// not provide the {file.*} placeholders.
+func (namespacesProvider) replace(key string, withoutFile bool) (any, bool) {
+	ns, ok := lookupPlaceholderNamespace(key)
+	if !ok {
+		return nil, false
+	}
+	if _, isFile := ns.Provider.(fileReplacementProvider); isFile && withoutFile {
+		return nil, false
+	}
+	return ns.Provider.Replace(key)
+}
+
+var (
+	placeholderNamespaces   = make(map[string]PlaceholderNamespace)
+	placeholderNamespacesMu sync.RWMutex
+)
@@ -0,0 +1,235 @@
+package uni
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Template is a string with placeholders that has been parsed
+// ahead of time by Replacer.Compile. Replacing the placeholders
+// of a Template does not need to scan the input again, so it is
+// well suited to hot paths that evaluate the same string over
+// and over, like log encoders.
+//
+// A Template is safe for concurrent use if its Replacer is.
+type Template struct {
+	rep      *Replacer
+	source   string
+	segments []templateSegment
+}
+
+// templateSegment is either literal text or, if ph is set, a
+// placeholder, in which case text is the placeholder as it
+// appeared in the source (braces included).
+type templateSegment struct {
+	text string
+	ph   *compiledPlaceholder
+
+	// if the placeholder is unknown and left in the output,
+	// Replacer.replace scans the text after its opening brace
+	// again; if that text has placeholders or escapes of its
+	// own, inner is that text compiled
+	inner *Template
+}
+
+// compiledPlaceholder is a placeholderExpr whose transforms
+// have been looked up already.
+type compiledPlaceholder struct {
+	placeholderExpr
+	funcs []PlaceholderTransform
+
+	// the default value, boxed once so evaluating
+	// the placeholder doesn't have to allocate
+	defValue any
+}
+
+// Compile parses template into a Template that replaces its
+// placeholders with values from r. Placeholders and escaped
+// braces are handled like they are by ReplaceAll. An error is
+// returned if a placeholder uses an unknown transform.
+func (r *Replacer) Compile(template string) (*Template, error) {
+	t := &Template{rep: r, source: template}
+
+	var (
+		lit             strings.Builder
+		lastWriteCursor int
+		unclosedCount   int
+	)
+	flushLiteral := func() {
+		if lit.Len() > 0 {
+			t.segments = append(t.segments, templateSegment{text: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	// this scan mirrors the one in Replacer.replace
+scan:
+	for i := 0; i < len(template); i++ {
+		// check for escaped braces
+		if i > 0 && template[i-1] == phEscape && (template[i] == phClose || template[i] == phOpen) {
+			lit.WriteString(template[lastWriteCursor : i-1])
+			lastWriteCursor = i
+			continue
+		}
+
+		if template[i] != phOpen {
+			continue
+		}
+
+		if unclosedCount > 100 {
+			return nil, fmt.Errorf("too many unclosed placeholders")
+		}
+
+		// find the end of the placeholder
+		end := strings.Index(template[i:], string(phClose)) + i
+		if end < i {
+			unclosedCount++
+			continue
+		}
+
+		// if necessary look for the first closing brace that is not escaped
+		for end > 0 && end < len(template)-1 && template[end-1] == phEscape {
+			nextEnd := strings.Index(template[end+1:], string(phClose))
+			if nextEnd < 0 {
+				unclosedCount++
+				continue scan
+			}
+			end += nextEnd + 1
+		}
+
+		lit.WriteString(template[lastWriteCursor:i])
+		flushLiteral()
+
+		expr := parsePlaceholder(template[i+1 : end])
+		ph := &compiledPlaceholder{placeholderExpr: expr, defValue: expr.def}
+		for _, name := range ph.transforms {
+			transformsMu.RLock()
+			transform, ok := transforms[name]
+			transformsMu.RUnlock()
+			if !ok {
+				return nil, fmt.Errorf("placeholder %s: unknown placeholder transform: %s", template[i:end+1], name)
+			}
+			ph.funcs = append(ph.funcs, transform)
+		}
+		seg := templateSegment{text: template[i : end+1], ph: ph}
+		if strings.ContainsAny(template[i+1:end], "{\\") {
+			var err error
+			if seg.inner, err = r.Compile(template[i+1 : end+1]); err != nil {
+				return nil, err
+			}
+		}
+		t.segments = append(t.segments, seg)
+
+		// advance cursor to end of placeholder
+		i = end
+		lastWriteCursor = i + 1
+	}
+
+	lit.WriteString(template[lastWriteCursor:])
+	flushLiteral()
+
+	return t, nil
+}
+
+// String returns the source of the template.
+func (t *Template) String() string { return t.source }
+
+// ReplaceAll is like Replacer.ReplaceAll: it replaces all
+// placeholders, substituting empty for values that are empty
+// or unknown.
+func (t *Template) ReplaceAll(empty string) string {
+	return t.replace(empty, true)
+}
+
+// ReplaceKnown is like Replacer.ReplaceKnown: placeholders
+// that are not recognized remain in the output.
+func (t *Template) ReplaceKnown(empty string) string {
+	return t.replace(empty, false)
+}
+
+// Value returns the value of the placeholder if the template
+// consists of exactly one placeholder, without converting it
+// to a string first; otherwise it returns the same as
+// ReplaceKnown(""). The boolean result reports whether the
+// value is known.
+func (t *Template) Value() (any, bool) {
+	if len(t.segments) == 1 && t.segments[0].ph != nil {
+		return t.evaluate(t.segments[0].ph)
+	}
+	return t.ReplaceKnown(""), true
+}
+
+func (t *Template) replace(empty string, treatUnknownAsEmpty bool) string {
+	switch len(t.segments) {
+	case 0:
+		return ""
+	case 1:
+		if t.segments[0].ph == nil {
+			return t.segments[0].text
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(t.source))
+
+	for i := range t.segments {
+		seg := &t.segments[i]
+		if seg.ph == nil {
+			sb.WriteString(seg.text)
+			continue
+		}
+
+		val, found := t.evaluate(seg.ph)
+		if !found && !treatUnknownAsEmpty {
+			if seg.inner != nil {
+				sb.WriteByte(phOpen)
+				sb.WriteString(seg.inner.replace(empty, false))
+			} else {
+				sb.WriteString(seg.text)
+			}
+			continue
+		}
+		if valStr := ToString(val); valStr != "" {
+			sb.WriteString(valStr)
+		} else {
+			sb.WriteString(empty)
+		}
+	}
+
+	return sb.String()
+}
+
+// evaluate returns the value of ph: its value in the replacer
+// or its default, run through its transforms.
+func (t *Template) evaluate(ph *compiledPlaceholder) (any, bool) {
+	val, found := t.rep.Get(ph.key)
+	if perr, ok := val.(PlaceholderError); ok {
+		if !ph.hasDefault {
+			Log().Error("placeholder: evaluation failed",
+				zap.String("placeholder", ph.key),
+				zap.Error(perr.Err))
+		}
+		val = nil
+	}
+	if ph.hasDefault && (!found || ToString(val) == "") {
+		val, found = ph.defValue, true
+	}
+	if !found || len(ph.funcs) == 0 {
+		return val, found
+	}
+
+	str := ToString(val)
+	for i, transform := range ph.funcs {
+		var err error
+		if str, err = transform(str); err != nil {
+			Log().Error("placeholder: transform failed",
+				zap.String("placeholder", ph.key),
+				zap.String("transform", ph.transforms[i]),
+				zap.Error(err))
+			return "", true
+		}
+	}
+	return str, true
+}
@@ -0,0 +1,370 @@
+package uni
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplacerDefaultsAndTransforms(t *testing.T) {
+	t.Setenv("UNI_TEST_NAME", "  Alice ")
+	t.Setenv("UNI_TEST_EMPTY", "")
+
+	rep := NewReplacer()
+	rep.Set("url", "http://example.com:8080")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "{env.UNI_TEST_UNSET:8080}", want: "8080"},
+		{input: "{env.UNI_TEST_EMPTY:8080}", want: "8080"},
+		{input: "{env.UNI_TEST_NAME:Bob}", want: "  Alice "},
+		{input: "{env.UNI_TEST_NAME|trim|lower}", want: "alice"},
+		{input: "{env.UNI_TEST_UNSET:Bob|upper}", want: "BOB"},
+		{input: "{url|url_escape}", want: "http%3A%2F%2Fexample.com%3A8080"},
+		{input: "{env.UNI_TEST_UNSET:http://x:1/}", want: "http://x:1/"},
+		{input: `{env.UNI_TEST_UNSET:a\|b}`, want: "a|b"},
+		{input: "{unknown:fallback}", want: "fallback"},
+		{input: "{env.UNI_TEST_UNSET:aGk=|base64_decode}", want: "hi"},
+		{input: "{env.UNI_TEST_UNSET:hi|base64}", want: "aGk="},
+		{input: "{env.UNI_TEST_UNSET:hi|hex}", want: "6869"},
+		{input: "{env.UNI_TEST_UNSET:6869|hex_decode}", want: "hi"},
+		{input: `{env.UNI_TEST_UNSET:say "hi"|json_escape}`, want: `say \"hi\"`},
+		{input: "{env.UNI_TEST_UNSET:abc|sha256}", want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{input: "port={env.UNI_TEST_UNSET:80}, host={unknown}", want: "port=80, host=<empty>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := rep.ReplaceAll(tt.input, "<empty>"); got != tt.want {
+				t.Fatalf("ReplaceAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacerTransformErrors(t *testing.T) {
+	rep := NewEmptyReplacer()
+	rep.Set("v", "not base64!")
+
+	if got := rep.ReplaceKnown("[{v|base64_decode}]", ""); got != "[]" {
+		t.Errorf("ReplaceKnown() = %q, want failed transform to yield empty value", got)
+	}
+	if _, err := rep.ReplaceOrErr("{v|base64_decode}", false, true); err == nil {
+		t.Error("expected error from failed transform")
+	}
+	_, err := rep.ReplaceOrErr("{v|nope}", false, true)
+	if err == nil || !strings.Contains(err.Error(), "unknown placeholder transform: nope") {
+		t.Errorf("ReplaceOrErr() error = %v, want unknown transform", err)
+	}
+	if got := rep.ReplaceKnown("{unknown|upper}", ""); got != "{unknown|upper}" {
+		t.Errorf("ReplaceKnown() = %q, want unknown placeholder to be kept", got)
+	}
+}
+
+func TestRegisterPlaceholderTransform(t *testing.T) {
+	RegisterPlaceholderTransform("test_reverse", func(s string) (string, error) {
+		r := []rune(s)
+		slices.Reverse(r)
+		return string(r), nil
+	})
+	defer func() {
+		transformsMu.Lock()
+		delete(transforms, "test_reverse")
+		transformsMu.Unlock()
+	}()
+
+	rep := NewEmptyReplacer()
+	rep.Set("v", "abc")
+	if got := rep.ReplaceAll("{v|test_reverse|upper}", ""); got != "CBA" {
+		t.Errorf("ReplaceAll() = %q, want %q", got, "CBA")
+	}
+	if !slices.Contains(PlaceholderTransforms(), "test_reverse") {
+		t.Error("registered transform is not listed")
+	}
+
+	for _, name := range []string{"", "a|b", "upper", "test_reverse"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic registering %q", name)
+				}
+			}()
+			RegisterPlaceholderTransform(name, func(s string) (string, error) { return s, nil })
+		}()
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	t.Setenv("UNI_TEST_NAME", "Alice")
+
+	rep := NewReplacer()
+	rep.Set("num", 42)
+	rep.Set("empty", "")
+
+	inputs := []string{
+		"",
+		"no placeholders",
+		"{env.UNI_TEST_NAME}",
+		"hello {env.UNI_TEST_NAME|upper}!",
+		"{num}/{empty}/{unknown}",
+		`escaped \{num\} and {num}`,
+		`{key\}with brace}`,
+		"{unknown {num}}",
+		"unclosed {num",
+		"}{num}{",
+		"{env.UNI_TEST_UNSET:dflt|upper} {num:7}",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			tmpl, err := rep.Compile(input)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got, want := tmpl.ReplaceAll("-"), rep.ReplaceAll(input, "-"); got != want {
+				t.Errorf("ReplaceAll() = %q, want %q", got, want)
+			}
+			if got, want := tmpl.ReplaceKnown("-"), rep.ReplaceKnown(input, "-"); got != want {
+				t.Errorf("ReplaceKnown() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	tmpl, _ := rep.Compile("{num}")
+	if val, ok := tmpl.Value(); !ok || val != 42 {
+		t.Errorf("Value() = %v, %v; want 42, true", val, ok)
+	}
+	if _, err := rep.Compile("{num|nope}"); err == nil {
+		t.Error("expected error for unknown transform")
+	}
+}
+
+func TestTemplateAllocs(t *testing.T) {
+	rep := NewEmptyReplacer()
+	rep.Set("host", "example.com")
+	rep.Set("port", "8080")
+	tmpl, err := rep.Compile("https://{host}:{port}/{path:index.html}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = tmpl.ReplaceAll("")
+	})
+	if allocs > 1 {
+		t.Errorf("ReplaceAll() allocated %v times, want only the output", allocs)
+	}
+}
+
+func TestRegisterPlaceholderNamespace(t *testing.T) {
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:         "test.router",
+		Placeholders: []PlaceholderDoc{{Key: "test.router.name"}},
+		Provider: ReplacerFunc(func(key string) (any, bool) {
+			if key == "test.router.name" {
+				return "main", true
+			}
+			return nil, false
+		}),
+	})
+	defer func() {
+		placeholderNamespacesMu.Lock()
+		delete(placeholderNamespaces, "test.router")
+		placeholderNamespacesMu.Unlock()
+	}()
+
+	rep := NewReplacer()
+	if got := rep.ReplaceKnown("{test.router.name}/{test.router.other}", ""); got != "main/{test.router.other}" {
+		t.Errorf("ReplaceKnown() = %q", got)
+	}
+	if got := NewEmptyReplacer().ReplaceKnown("{test.router.name}", ""); got != "{test.router.name}" {
+		t.Errorf("empty replacer provided a namespace: %q", got)
+	}
+	if _, ok := rep.WithoutFile().Get("file./etc/hostname"); ok {
+		t.Error("WithoutFile() still provides {file.*}")
+	}
+	if _, ok := rep.WithoutFile().Get("test.router.name"); !ok {
+		t.Error("WithoutFile() dropped other namespaces")
+	}
+
+	var names []string
+	for _, ns := range PlaceholderNamespaces() {
+		names = append(names, ns.Name)
+	}
+	if !slices.IsSorted(names) || !slices.Contains(names, "test.router") || !slices.Contains(names, "env") {
+		t.Errorf("PlaceholderNamespaces() = %v", names)
+	}
+
+	provider := ReplacerFunc(func(string) (any, bool) { return nil, false })
+	for _, ns := range []PlaceholderNamespace{
+		{Name: "", Provider: provider},
+		{Name: "a.", Provider: provider},
+		{Name: "nil"},
+		{Name: "test.router", Provider: provider},
+		{Name: "test", Provider: provider},
+		{Name: "test.router.sub", Provider: provider},
+		{Name: "ok", Provider: provider, Placeholders: []PlaceholderDoc{{Key: "other.key"}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic registering %+v", ns)
+				}
+			}()
+			RegisterPlaceholderNamespace(ns)
+		}()
+	}
+	// a namespace that merely shares a string prefix does not conflict
+	RegisterPlaceholderNamespace(PlaceholderNamespace{Name: "test.routers", Provider: provider})
+	placeholderNamespacesMu.Lock()
+	delete(placeholderNamespaces, "test.routers")
+	placeholderNamespacesMu.Unlock()
+}
+
+func TestGlobalPlaceholders(t *testing.T) {
+	now := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	defer func(orig func() time.Time) { nowFunc = orig }(nowFunc)
+	nowFunc = func() time.Time { return now }
+	defer func(orig time.Time) { processStart = orig }(processStart)
+	processStart = now.Add(-90 * time.Second)
+
+	version, _ := Version()
+	rep := NewReplacer()
+	for _, tt := range []struct {
+		input, want string
+	}{
+		{input: "{time.now.rfc3339}", want: "2025-03-04T05:06:07Z"},
+		{input: `{time.now.format.2006-01-02 15\:04}`, want: "2025-03-04 05:06"},
+		{input: "{time.now.format.Jan 2|upper}", want: "MAR 4"},
+		{input: "{process.uptime}", want: "90"},
+		{input: "{process.pid}", want: strconv.Itoa(os.Getpid())},
+		{input: "{uni.version}", want: version},
+		{input: "{system.cpus}", want: strconv.Itoa(runtime.NumCPU())},
+		{input: "{random.hex.0}", want: "{random.hex.0}"},
+		{input: "{random.hex.x}", want: "{random.hex.x}"},
+	} {
+		if got := rep.ReplaceKnown(tt.input, ""); got != tt.want {
+			t.Errorf("ReplaceKnown(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, n := range []int{1, 7, 32} {
+		got := rep.ReplaceAll("{random.hex."+strconv.Itoa(n)+"}", "")
+		if len(got) != n || strings.Trim(got, "0123456789abcdef") != "" {
+			t.Errorf("{random.hex.%d} = %q", n, got)
+		}
+	}
+
+	for _, version := range []string{"v4", "v7"} {
+		a, b := rep.ReplaceAll("{uuid."+version+"}", ""), rep.ReplaceAll("{uuid."+version+"}", "")
+		if len(a) != 36 || a[14] != version[1] || a == b {
+			t.Errorf("{uuid.%s} = %q, %q", version, a, b)
+		}
+	}
+
+	if mem := rep.ReplaceAll("{system.memory}", ""); runtime.GOOS == "linux" {
+		if n, err := strconv.ParseInt(mem, 10, 64); err != nil || n <= 0 {
+			t.Errorf("{system.memory} = %q", mem)
+		}
+	}
+}
+
+func TestReplacerCloneAndChild(t *testing.T) {
+	parent := NewReplacer()
+	parent.Set("a", "parent-a")
+	parent.Set("b", "parent-b")
+
+	clone := parent.Clone()
+	clone.Set("a", "clone-a")
+	if got := parent.ReplaceAll("{a}", ""); got != "parent-a" {
+		t.Errorf("parent sees value set on clone: %q", got)
+	}
+	if got := clone.ReplaceAll("{a} {b} {system.os}", ""); got != "clone-a parent-b "+runtime.GOOS {
+		t.Errorf("clone: got %q", got)
+	}
+
+	child := parent.Child()
+	child.Set("a", "child-a")
+	parent.Set("c", "parent-c")
+	if got := child.ReplaceAll("{a} {b} {c} {system.os}", ""); got != "child-a parent-b parent-c "+runtime.GOOS {
+		t.Errorf("child: got %q", got)
+	}
+	if got := parent.ReplaceAll("{a}", ""); got != "parent-a" {
+		t.Errorf("parent sees value set on child: %q", got)
+	}
+	child.Delete("a")
+	if got := child.ReplaceAll("{a}", ""); got != "parent-a" {
+		t.Errorf("after Delete, child: got %q", got)
+	}
+
+	noFile := parent.WithoutFile()
+	noFile.Set("d", "shared")
+	if got := parent.ReplaceAll("{d}", ""); got != "shared" {
+		t.Errorf("WithoutFile() copy does not share values: %q", got)
+	}
+}
+
+func TestReplacerWithoutFileCopies(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "f")
+	writeFile(t, name, "contents")
+	key := "file." + name
+
+	rep := NewReplacer()
+	rep.Set("a", "set")
+	if got, _ := rep.GetString(key); got != "contents" {
+		t.Fatalf("{%s} = %q, want contents", key, got)
+	}
+
+	for name, r := range map[string]*Replacer{
+		"Child().WithoutFile()":         rep.Child().WithoutFile(),
+		"WithoutFile().Child()":         rep.WithoutFile().Child(),
+		"WithoutFile().Child().Child()": rep.WithoutFile().Child().Child(),
+		"Clone().WithoutFile()":         rep.Clone().WithoutFile(),
+		"WithoutFile().Clone()":         rep.WithoutFile().Clone(),
+	} {
+		if _, ok := r.Get(key); ok {
+			t.Errorf("%s still provides {file.*}", name)
+		}
+		if got, _ := r.GetString("a"); got != "set" {
+			t.Errorf("%s: {a} = %q, want the value set on the original", name, got)
+		}
+		r.Set("b", "own")
+		if got, ok := r.GetString("b"); !ok || got != "own" {
+			t.Errorf("%s: {b} = %q, %t after Set", name, got, ok)
+		}
+	}
+	if _, ok := rep.Get(key); !ok {
+		t.Error("original replacer lost {file.*}")
+	}
+}
+
+func TestReplacerConcurrentChildren(t *testing.T) {
+	parent := NewReplacer()
+	parent.Set("host", "example.com")
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			child := parent.Child()
+			for j := range 100 {
+				child.Set("n", j)
+				want := "example.com/" + strconv.Itoa(j)
+				if got := child.ReplaceAll("{host}/{n}", ""); got != want {
+					t.Errorf("goroutine %d: got %q, want %q", i, got, want)
+					return
+				}
+				parent.Set("other", j)
+			}
+		}()
+	}
+	wg.Wait()
+}
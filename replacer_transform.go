@@ -0,0 +1,168 @@
+package uni
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+func init() {
+	RegisterPlaceholderTransform("upper", func(s string) (string, error) { return strings.ToUpper(s), nil })
+	RegisterPlaceholderTransform("lower", func(s string) (string, error) { return strings.ToLower(s), nil })
+	RegisterPlaceholderTransform("trim", func(s string) (string, error) { return strings.TrimSpace(s), nil })
+	RegisterPlaceholderTransform("base64", func(s string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(s)), nil
+	})
+	RegisterPlaceholderTransform("base64_decode", func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		return string(b), err
+	})
+	RegisterPlaceholderTransform("hex", func(s string) (string, error) {
+		return hex.EncodeToString([]byte(s)), nil
+	})
+	RegisterPlaceholderTransform("hex_decode", func(s string) (string, error) {
+		b, err := hex.DecodeString(strings.TrimSpace(s))
+		return string(b), err
+	})
+	RegisterPlaceholderTransform("json_escape", jsonEscape)
+	RegisterPlaceholderTransform("url_escape", func(s string) (string, error) { return url.QueryEscape(s), nil })
+	RegisterPlaceholderTransform("sha256", func(s string) (string, error) {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:]), nil
+	})
+}
+
+// PlaceholderTransform transforms the value of a placeholder.
+// Transforms are applied in a pipeline, for example
+// {env.NAME|trim|lower}, each one receiving the output of
+// the one before it.
+type PlaceholderTransform func(string) (string, error)
+
+// RegisterPlaceholderTransform makes a transform available
+// under the given name in placeholder pipelines. It should
+// be called from an init function, and it panics if the
+// name is invalid or already registered.
+func RegisterPlaceholderTransform(name string, transform PlaceholderTransform) {
+	if name == "" || strings.ContainsAny(name, ":|{}\\ ") {
+		panic(fmt.Sprintf("invalid placeholder transform name: %q", name))
+	}
+	if transform == nil {
+		panic("placeholder transform must not be nil")
+	}
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+	if _, ok := transforms[name]; ok {
+		panic(fmt.Sprintf("placeholder transform already registered: %s", name))
+	}
+	transforms[name] = transform
+}
+
+// PlaceholderTransforms returns the names of all registered
+// placeholder transforms in ascending lexicographical order.
+func PlaceholderTransforms() []string {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+	names := make([]string, 0, len(transforms))
+	for name := range transforms {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyTransforms runs val through the named transforms, in order.
+func applyTransforms(val string, names []string) (string, error) {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+	for _, name := range names {
+		transform, ok := transforms[name]
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder transform: %s", name)
+		}
+		var err error
+		if val, err = transform(val); err != nil {
+			return "", fmt.Errorf("placeholder transform %s: %v", name, err)
+		}
+	}
+	return val, nil
+}
+
+// jsonEscape escapes s so it can be used inside a JSON
+// string, without adding the surrounding quotes.
+func jsonEscape(s string) (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b[1 : len(b)-1]), nil
+}
+
+// placeholderExpr is the parsed body of a placeholder
+// of the form {key:default|transform1|transform2}.
+type placeholderExpr struct {
+	key        string
+	def        string
+	hasDefault bool
+	transforms []string
+}
+
+// parsePlaceholder parses the body of a placeholder (without
+// the braces). The first colon separates the key from the
+// default value, which is used if the key is unknown or its
+// value is empty; each pipe starts a transform. A colon or
+// pipe that is part of the key or default can be escaped
+// with a backslash.
+func parsePlaceholder(body string) placeholderExpr {
+	if !strings.ContainsAny(body, ":|") {
+		return placeholderExpr{key: body}
+	}
+
+	var (
+		expr  placeholderExpr
+		sb    strings.Builder
+		field int // 0: key, 1: default, 2: transform
+	)
+	flush := func() {
+		switch field {
+		case 0:
+			expr.key = sb.String()
+		case 1:
+			expr.def = sb.String()
+		default:
+			expr.transforms = append(expr.transforms, strings.TrimSpace(sb.String()))
+		}
+		sb.Reset()
+	}
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == phEscape && i+1 < len(body) && (body[i+1] == ':' || body[i+1] == '|'):
+			sb.WriteByte(body[i+1])
+			i++
+		case c == ':' && field == 0:
+			flush()
+			field = 1
+			expr.hasDefault = true
+		case c == '|':
+			flush()
+			field = 2
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	flush()
+
+	return expr
+}
+
+var (
+	transforms   = make(map[string]PlaceholderTransform)
+	transformsMu sync.RWMutex
+)
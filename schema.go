@@ -0,0 +1,425 @@
+package uni
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSONSchemaDialect is the JSON Schema dialect of the
+// schemas made by SchemaGenerator.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema, or a part of one. Only the
+// keywords used by SchemaGenerator are supported.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Type is a type name, or a list of them.
+	Type  any `json:"type,omitempty"`
+	Const any `json:"const,omitempty"`
+
+	Properties map[string]*JSONSchema `json:"properties,omitempty"`
+	Required   []string               `json:"required,omitempty"`
+
+	// AdditionalProperties and UnevaluatedProperties are
+	// either a *JSONSchema or false.
+	AdditionalProperties  any `json:"additionalProperties,omitempty"`
+	UnevaluatedProperties any `json:"unevaluatedProperties,omitempty"`
+
+	Items *JSONSchema   `json:"items,omitempty"`
+	OneOf []*JSONSchema `json:"oneOf,omitempty"`
+
+	Defs map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// SchemaGenerator generates JSON Schemas for modules and for
+// the config, from the Go types of the registered modules and
+// their module struct tags (see Context.LoadModule).
+//
+// Each field that holds modules is described as a choice
+// between the modules of its namespace, selected by the
+// inline key or, in module maps, by the map key. Doc comments
+// are included if they were loaded with LoadDocs.
+type SchemaGenerator struct {
+	// Docs are the doc comments of types and struct fields,
+	// keyed by "<import path>.<type>" and
+	// "<import path>.<type>.<field>".
+	Docs map[string]string
+
+	defs map[string]*JSONSchema
+}
+
+// NewSchemaGenerator returns a new SchemaGenerator.
+func NewSchemaGenerator() *SchemaGenerator {
+	return &SchemaGenerator{Docs: make(map[string]string)}
+}
+
+// ConfigSchema returns the schema of the whole config.
+func (g *SchemaGenerator) ConfigSchema() *JSONSchema {
+	g.defs = make(map[string]*JSONSchema)
+	root := g.structSchema(reflect.TypeFor[Config](), false)
+	root.Schema = JSONSchemaDialect
+	root.Title = "uni config"
+	root.Defs = g.defs
+	return root
+}
+
+// ModuleSchema returns the schema of the config of the module
+// with the given ID, not including its inline key.
+func (g *SchemaGenerator) ModuleSchema(id string) (*JSONSchema, error) {
+	mi, err := GetModule(id)
+	if err != nil {
+		return nil, err
+	}
+	g.defs = make(map[string]*JSONSchema)
+	return &JSONSchema{
+		Schema: JSONSchemaDialect,
+		Title:  id,
+		Ref:    g.moduleRef(mi),
+		Defs:   g.defs,
+	}, nil
+}
+
+// moduleRef returns a reference to the definition of the
+// module's config, adding the definition if necessary.
+func (g *SchemaGenerator) moduleRef(mi ModuleInfo) string {
+	name := string(mi.ID)
+	ref := "#/$defs/" + name
+	if _, ok := g.defs[name]; ok {
+		return ref
+	}
+
+	typ := reflect.TypeOf(mi.New())
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	// add a placeholder first, so recursive references to
+	// the module (e.g. through its namespace) terminate
+	def := &JSONSchema{}
+	g.defs[name] = def
+	if typ.Kind() == reflect.Struct {
+		// no additional properties restriction here, since
+		// users of the definition add the inline key; they
+		// forbid unevaluated properties instead
+		*def = *g.structSchema(typ, true)
+	}
+	def.Title = name
+	return ref
+}
+
+// modulesSchema returns the schema of a single module in the
+// given namespace, selected by inlineKey.
+func (g *SchemaGenerator) modulesSchema(namespace, inlineKey string) *JSONSchema {
+	s := &JSONSchema{Type: "object"}
+	for _, mi := range GetModules(namespace) {
+		s.OneOf = append(s.OneOf, &JSONSchema{
+			Ref: g.moduleRef(mi),
+			Properties: map[string]*JSONSchema{
+				inlineKey: {Const: mi.ID.Name()},
+			},
+			Required:              []string{inlineKey},
+			UnevaluatedProperties: false,
+		})
+	}
+	if len(s.OneOf) == 0 {
+		s.Description = fmt.Sprintf("No modules are registered in namespace %s.", namespace)
+	}
+	return s
+}
+
+// moduleMapSchema returns the schema of a map of modules in
+// the given namespace, keyed by module name.
+func (g *SchemaGenerator) moduleMapSchema(namespace string) *JSONSchema {
+	s := &JSONSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema),
+		AdditionalProperties: false,
+	}
+	for _, mi := range GetModules(namespace) {
+		s.Properties[mi.ID.Name()] = &JSONSchema{
+			Ref:                   g.moduleRef(mi),
+			UnevaluatedProperties: false,
+		}
+	}
+	return s
+}
+
+// moduleFieldSchema returns the schema of a field holding
+// modules, as described by its module struct tag.
+func (g *SchemaGenerator) moduleFieldSchema(typ reflect.Type, tag string) *JSONSchema {
+	opts, err := ParseStructTag(tag)
+	namespace, ok := opts["namespace"]
+	if err != nil || !ok {
+		return &JSONSchema{}
+	}
+	inlineKey := opts["inline_key"]
+
+	switch {
+	case isJSONRawMessage(typ):
+		return g.modulesSchema(namespace, inlineKey)
+	case typ.Kind() == reflect.Slice && isJSONRawMessage(typ.Elem()):
+		return &JSONSchema{Type: "array", Items: g.modulesSchema(namespace, inlineKey)}
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Slice && isJSONRawMessage(typ.Elem().Elem()):
+		return &JSONSchema{Type: "array", Items: &JSONSchema{Type: "array", Items: g.modulesSchema(namespace, inlineKey)}}
+	case typ.Kind() == reflect.Map && inlineKey != "":
+		return &JSONSchema{Type: "object", AdditionalProperties: g.modulesSchema(namespace, inlineKey)}
+	case typ.Kind() == reflect.Map:
+		return g.moduleMapSchema(namespace)
+	case typ.Kind() == reflect.Slice && isModuleMapType(typ.Elem()):
+		return &JSONSchema{Type: "array", Items: g.moduleMapSchema(namespace)}
+	}
+	return &JSONSchema{}
+}
+
+// typeSchema returns the schema of values of typ.
+func (g *SchemaGenerator) typeSchema(typ reflect.Type) *JSONSchema {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ {
+	case reflect.TypeFor[Duration]():
+		return &JSONSchema{
+			Type:        []string{"string", "integer"},
+			Description: "A duration string such as \"1m30s\", or a number of nanoseconds.",
+		}
+	case reflect.TypeFor[time.Duration]():
+		return &JSONSchema{Type: "integer", Description: "A number of nanoseconds."}
+	case JSONRawMessageType:
+		return &JSONSchema{}
+	}
+
+	ptr := reflect.PointerTo(typ)
+	if ptr.Implements(reflect.TypeFor[json.Unmarshaler]()) {
+		return &JSONSchema{}
+	}
+	if ptr.Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string", Description: "Base64-encoded bytes."}
+		}
+		return &JSONSchema{Type: "array", Items: g.typeSchema(typ.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: g.typeSchema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ, false)
+		}
+		name := schemaDefName(typ)
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = &JSONSchema{}
+			*g.defs[name] = *g.structSchema(typ, false)
+		}
+		return &JSONSchema{Ref: "#/$defs/" + name}
+	}
+	return &JSONSchema{}
+}
+
+// structSchema returns the schema of the struct type typ.
+// Unless open is true, properties other than the struct's
+// fields are not allowed.
+func (g *SchemaGenerator) structSchema(typ reflect.Type, open bool) *JSONSchema {
+	s := &JSONSchema{
+		Type:        "object",
+		Description: g.Docs[typeDocKey(typ)],
+		Properties:  make(map[string]*JSONSchema),
+	}
+	if !open {
+		s.AdditionalProperties = false
+	}
+	g.addFields(s, typ)
+	return s
+}
+
+// addFields adds the JSON fields of the struct type typ,
+// including the ones of embedded structs, to s.
+func (g *SchemaGenerator) addFields(s *JSONSchema, typ reflect.Type) {
+	for _, f := range structJSONFields(typ) {
+		var fs *JSONSchema
+		if tag, ok := moduleTag(f.field); ok {
+			fs = g.moduleFieldSchema(f.field.Type, tag)
+		} else {
+			fs = g.typeSchema(f.field.Type)
+		}
+		if doc := g.Docs[typeDocKey(f.owner)+"."+f.field.Name]; doc != "" {
+			if fs.Ref != "" {
+				// keywords next to $ref are fine in 2020-12,
+				// but keep the referenced schema unchanged
+				fs = &JSONSchema{Ref: fs.Ref}
+			}
+			fs.Description = doc
+		}
+		s.Properties[f.name] = fs
+	}
+}
+
+// LoadDocs reads the doc comments of the types and struct
+// fields in the Go module whose root directory (containing
+// go.mod) is root, so that they are included in schemas.
+func (g *SchemaGenerator) LoadDocs(root string) error {
+	docs, err := LoadDocComments(root)
+	if err != nil {
+		return err
+	}
+	maps.Copy(g.Docs, docs)
+	return nil
+}
+
+// LoadDocComments reads the doc comments of the types and
+// struct fields in the Go module whose root directory
+// (containing go.mod) is root. They are keyed by
+// "<import path>.<type>" and "<import path>.<type>.<field>".
+func LoadDocComments(root string) (map[string]string, error) {
+	modulePath, err := readModulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make(map[string]string)
+	err = filepath.WalkDir(root, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if name := d.Name(); dir != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+			name == "testdata" || name == "vendor") {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		importPath := modulePath
+		if rel != "." {
+			importPath = path.Join(modulePath, filepath.ToSlash(rel))
+		}
+		return addPackageDocs(docs, importPath, dir)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// addPackageDocs adds the doc comments of the package with
+// the given import path in dir, if there is one, to docs.
+func addPackageDocs(docs map[string]string, importPath, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	pkg, err := doc.NewFromFiles(fset, files, importPath, doc.PreserveAST)
+	if err != nil {
+		return err
+	}
+	for _, t := range pkg.Types {
+		key := importPath + "." + t.Name
+		if text := strings.TrimSpace(t.Doc); text != "" {
+			docs[key] = text
+		}
+		for _, spec := range t.Decl.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				text := strings.TrimSpace(field.Doc.Text())
+				if text == "" {
+					continue
+				}
+				for _, name := range field.Names {
+					docs[key+"."+name.Name] = text
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readModulePath returns the module path declared in
+// the go.mod file at name.
+func readModulePath(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: no module directive", name)
+}
+
+// typeDocKey returns the key of the doc comment of typ.
+func typeDocKey(typ reflect.Type) string {
+	return typ.PkgPath() + "." + typ.Name()
+}
+
+// schemaDefName returns the name of the definition of the
+// named struct type typ, which is unique and usable in a
+// JSON pointer.
+func schemaDefName(typ reflect.Type) string {
+	return strings.NewReplacer("/", "_", "~", "_").Replace(typeDocKey(typ))
+}
@@ -0,0 +1,98 @@
+package uni
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// schemaTestEncoder is a module which wraps another module
+// of its own namespace, like the filter encoder does.
+type schemaTestEncoder struct {
+	WrappedRaw json.RawMessage            `json:"wrap,omitempty" uni:"namespace=schema_test.encoders inline_key=format"`
+	ByName     map[string]json.RawMessage `json:"by_name,omitempty" uni:"namespace=schema_test.encoders"`
+	Prefix     string                     `json:"prefix,omitempty"`
+	Timeout    Duration                   `json:"timeout,omitempty"`
+	Tags       []string                   `json:"tags,omitempty"`
+	ignored    int
+}
+
+func (schemaTestEncoder) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "schema_test.encoders.wrap", New: func() Module { return new(schemaTestEncoder) }}
+}
+
+func TestSchemaGenerator(t *testing.T) {
+	RegisterModule(schemaTestEncoder{})
+	defer func() {
+		defaultRegistry.mu.Lock()
+		delete(defaultRegistry.modules, "schema_test.encoders.wrap")
+		defaultRegistry.mu.Unlock()
+	}()
+
+	gen := NewSchemaGenerator()
+	if err := gen.LoadDocs("."); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := gen.ModuleSchema("schema_test.encoders.wrap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["$ref"] != "#/$defs/schema_test.encoders.wrap" {
+		t.Fatalf("$ref = %v", got["$ref"])
+	}
+	def := got["$defs"].(map[string]any)["schema_test.encoders.wrap"].(map[string]any)
+	props := def["properties"].(map[string]any)
+
+	wrap := props["wrap"].(map[string]any)
+	choice := wrap["oneOf"].([]any)[0].(map[string]any)
+	if choice["$ref"] != "#/$defs/schema_test.encoders.wrap" ||
+		choice["properties"].(map[string]any)["format"].(map[string]any)["const"] != "wrap" ||
+		choice["unevaluatedProperties"] != false {
+		t.Errorf("wrap choice = %v", choice)
+	}
+
+	byName := props["by_name"].(map[string]any)
+	if _, ok := byName["properties"].(map[string]any)["wrap"]; !ok || byName["additionalProperties"] != false {
+		t.Errorf("by_name = %v", byName)
+	}
+
+	for name, want := range map[string]string{
+		"prefix":  `{"type":"string"}`,
+		"timeout": `["string","integer"]`,
+		"tags":    `{"items":{"type":"string"},"type":"array"}`,
+	} {
+		b, _ := json.Marshal(props[name])
+		if name == "timeout" {
+			b, _ = json.Marshal(props[name].(map[string]any)["type"])
+		}
+		if string(b) != want {
+			t.Errorf("%s = %s, want %s", name, b, want)
+		}
+	}
+	if _, ok := props["ignored"]; ok {
+		t.Error("unexported field in schema")
+	}
+
+	config := gen.ConfigSchema()
+	if config.AdditionalProperties != false || config.Properties["logging"] == nil {
+		t.Errorf("config schema = %+v", config)
+	}
+	if !strings.HasPrefix(config.Description, "Config is") ||
+		!strings.HasPrefix(config.Properties["logging"].Description, "Logging configures") {
+		t.Errorf("doc comments missing: %q, %q", config.Description, config.Properties["logging"].Description)
+	}
+
+	if _, err := gen.ModuleSchema("schema_test.nope"); err == nil {
+		t.Error("expected error for unknown module")
+	}
+}
@@ -0,0 +1,239 @@
+package uni
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterPlaceholderNamespace(PlaceholderNamespace{
+		Name:        "secret",
+		Description: "Secrets from the configured secret stores",
+		Placeholders: []PlaceholderDoc{
+			{Key: "secret.*", Description: "The secret from a secret store, as <store>.<key>; the key format depends on the store"},
+		},
+		Provider: secretReplacementProvider{},
+	})
+	RegisterNamespace[SecretStore]("secrets.stores")
+}
+
+// SecretStore is a source of secrets. Modules in the
+// `secrets.stores` namespace must implement it.
+type SecretStore interface {
+	// Secret returns the value of the secret with the given
+	// key. If the store does not have the secret, the error
+	// must wrap ErrSecretNotFound. Errors must never contain
+	// secret values.
+	Secret(ctx context.Context, key string) ([]byte, error)
+}
+
+// ErrSecretNotFound is returned by secret stores that do
+// not have the requested secret.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Secret holds a secret value. Its String, GoString and
+// MarshalText methods redact the value, so that a Secret
+// which ends up in a log entry or in JSON output does not
+// reveal it; use Reveal to get the value. Replacers reveal
+// secrets when they replace placeholders, so output which
+// contains replaced {secret.*} placeholders must be treated
+// as sensitive. The same goes for the output of transforms
+// applied to secrets.
+type Secret struct {
+	value string
+}
+
+// NewSecret returns a Secret holding value.
+func NewSecret(value string) Secret { return Secret{value: value} }
+
+// Reveal returns the value of the secret.
+func (s Secret) Reveal() string { return s.value }
+
+// String implements fmt.Stringer, redacting the secret.
+func (Secret) String() string { return redactedSecret }
+
+// GoString implements fmt.GoStringer, redacting the secret.
+func (Secret) GoString() string { return redactedSecret }
+
+// MarshalText implements encoding.TextMarshaler, redacting
+// the secret.
+func (Secret) MarshalText() ([]byte, error) { return []byte(redactedSecret), nil }
+
+const redactedSecret = "[REDACTED]"
+
+// Secrets configures the secret stores which provide the
+// values of {secret.<store>.<key>} placeholders.
+//
+// The config only ever contains placeholders, never secret
+// values: secrets are fetched when placeholders are replaced,
+// and kept in memory for up to CacheTTL.
+type Secrets struct {
+	// The secret stores, keyed by the name used to refer
+	// to them in placeholders. Names must not contain dots.
+	StoresRaw map[string]json.RawMessage `json:"stores,omitempty" uni:"namespace=secrets.stores inline_key=source"`
+
+	// How long secrets are cached after they are fetched
+	// from a store. Default: 5m. A negative value disables
+	// caching.
+	CacheTTL Duration `json:"cache_ttl,omitempty"`
+
+	stores map[string]*cachedSecretStore
+}
+
+// Provision loads the secret stores and makes them available
+// to {secret.*} placeholders, replacing the stores of any
+// previous Secrets config.
+func (s *Secrets) Provision(ctx Context) error {
+	ttl := time.Duration(s.CacheTTL)
+	if ttl == 0 {
+		ttl = defaultSecretCacheTTL
+	}
+
+	for name := range s.StoresRaw {
+		if name == "" || strings.Contains(name, ".") {
+			return fmt.Errorf("invalid secret store name %q: must be non-empty and must not contain dots", name)
+		}
+	}
+
+	stores, err := LoadModuleMapAs[SecretStore](ctx, s, "StoresRaw")
+	if err != nil {
+		return fmt.Errorf("loading secret stores: %w", err)
+	}
+	s.stores = make(map[string]*cachedSecretStore)
+	for name, store := range stores {
+		s.stores[name] = &cachedSecretStore{
+			store:   store,
+			ttl:     ttl,
+			entries: make(map[string]cachedSecret),
+		}
+	}
+
+	activeSecretsMu.Lock()
+	activeSecrets = s
+	activeSecretsMu.Unlock()
+
+	return nil
+}
+
+// Cleanup stops providing the secret stores to placeholders,
+// unless they were replaced by another Secrets config already,
+// and forgets all cached secrets.
+func (s *Secrets) Cleanup() error {
+	activeSecretsMu.Lock()
+	if activeSecrets == s {
+		activeSecrets = nil
+	}
+	activeSecretsMu.Unlock()
+
+	for _, store := range s.stores {
+		store.purge()
+	}
+	return nil
+}
+
+// Secret returns the secret with the given key from the named
+// store, using the cache if possible.
+func (s *Secrets) Secret(ctx context.Context, store, key string) (Secret, error) {
+	cs, ok := s.stores[store]
+	if !ok {
+		return Secret{}, fmt.Errorf("unknown secret store: %s", store)
+	}
+	return cs.get(ctx, key)
+}
+
+// cachedSecretStore caches the secrets fetched from a store.
+type cachedSecretStore struct {
+	store SecretStore
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value   Secret
+	expires time.Time
+}
+
+func (cs *cachedSecretStore) get(ctx context.Context, key string) (Secret, error) {
+	now := nowFunc()
+
+	cs.mu.Lock()
+	entry, ok := cs.entries[key]
+	cs.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	val, err := cs.store.Secret(ctx, key)
+	if err != nil {
+		return Secret{}, err
+	}
+	secret := NewSecret(string(val))
+
+	if cs.ttl > 0 {
+		cs.mu.Lock()
+		cs.entries[key] = cachedSecret{value: secret, expires: now.Add(cs.ttl)}
+		cs.mu.Unlock()
+	}
+
+	return secret, nil
+}
+
+func (cs *cachedSecretStore) purge() {
+	cs.mu.Lock()
+	clear(cs.entries)
+	cs.mu.Unlock()
+}
+
+// secretReplacementProvider handles {secret.*} replacements
+// using the stores of the active Secrets config.
+type secretReplacementProvider struct{}
+
+// Replace implements PlaceholderProvider.
+func (secretReplacementProvider) Replace(key string) (any, bool) {
+	const prefix = "secret."
+	storeName, secretKey, ok := strings.Cut(strings.TrimPrefix(key, prefix), ".")
+	if !ok || secretKey == "" {
+		return nil, false
+	}
+
+	activeSecretsMu.RLock()
+	secrets := activeSecrets
+	activeSecretsMu.RUnlock()
+	if secrets == nil {
+		return nil, false
+	}
+	if _, ok := secrets.stores[storeName]; !ok {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretFetchTimeout)
+	defer cancel()
+	secret, err := secrets.Secret(ctx, storeName, secretKey)
+	if err != nil {
+		return PlaceholderError{Err: fmt.Errorf("secret store %s: %w", storeName, err)}, true
+	}
+	return secret, true
+}
+
+const (
+	defaultSecretCacheTTL = 5 * time.Minute
+	secretFetchTimeout    = 30 * time.Second
+)
+
+var (
+	activeSecrets   *Secrets
+	activeSecretsMu sync.RWMutex
+)
+
+// Interface guards
+var (
+	_ Provisioner  = (*Secrets)(nil)
+	_ CleanerUpper = (*Secrets)(nil)
+)
@@ -0,0 +1,146 @@
+package uni
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeSecretStore serves the secrets in its config and
+// counts how often it is asked for them.
+type fakeSecretStore struct {
+	Values map[string]string `json:"values,omitempty"`
+	calls  int
+}
+
+func (*fakeSecretStore) UniModule() ModuleInfo {
+	return ModuleInfo{
+		ID:  "secrets.stores.test_fake",
+		New: func() Module { return new(fakeSecretStore) },
+	}
+}
+
+func (f *fakeSecretStore) Secret(_ context.Context, key string) ([]byte, error) {
+	f.calls++
+	val, ok := f.Values[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, key)
+	}
+	return []byte(val), nil
+}
+
+func TestSecretRedaction(t *testing.T) {
+	s := NewSecret("hunter2")
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	zap.New(core).Info("msg", zap.Any("secret", s))
+
+	b, _ := json.Marshal(map[string]any{"secret": s})
+	for _, out := range []string{
+		fmt.Sprint(s),
+		fmt.Sprintf("%#v", s),
+		string(b),
+		fmt.Sprint(logs.All()[0].ContextMap()),
+	} {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("secret revealed in %q", out)
+		}
+	}
+	if ToString(s) != "hunter2" {
+		t.Errorf("ToString() = %q, want the secret", ToString(s))
+	}
+}
+
+func TestSecretsPlaceholders(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterModule(new(fakeSecretStore))
+
+	ctx, cancel := NewContext(Context{Context: context.Background()}.WithRegistry(r))
+	defer cancel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer func(orig func() time.Time) { nowFunc = orig }(nowFunc)
+	nowFunc = func() time.Time { return now }
+
+	secrets := &Secrets{
+		StoresRaw: map[string]json.RawMessage{
+			"fake": json.RawMessage(`{"source":"test_fake","values":{"db":"hunter2"}}`),
+		},
+		CacheTTL: Duration(time.Minute),
+	}
+	if err := secrets.Provision(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store := secrets.stores["fake"].store.(*fakeSecretStore)
+
+	rep := NewReplacer()
+	for range 3 {
+		if got := rep.ReplaceAll("pw={secret.fake.db}", ""); got != "pw=hunter2" {
+			t.Fatalf("ReplaceAll() = %q", got)
+		}
+	}
+	if store.calls != 1 {
+		t.Errorf("store was called %d times, want 1 (cached)", store.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	rep.ReplaceAll("{secret.fake.db}", "")
+	if store.calls != 2 {
+		t.Errorf("store was called %d times after TTL expired, want 2", store.calls)
+	}
+
+	if got := rep.ReplaceKnown("{secret.nope.db}", ""); got != "{secret.nope.db}" {
+		t.Errorf("unknown store: got %q", got)
+	}
+	if got := rep.ReplaceKnown("[{secret.fake.missing}]", ""); got != "[]" {
+		t.Errorf("missing secret: got %q", got)
+	}
+
+	if err := secrets.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rep.ReplaceKnown("{secret.fake.db}", ""); got != "{secret.fake.db}" {
+		t.Errorf("secrets still provided after cleanup: %q", got)
+	}
+
+	bad := &Secrets{StoresRaw: map[string]json.RawMessage{
+		"a.b": json.RawMessage(`{"source":"test_fake"}`),
+	}}
+	if err := bad.Provision(ctx); err == nil {
+		t.Error("expected error for store name with a dot")
+	}
+}
+
+func TestRunSecrets(t *testing.T) {
+	RegisterModule(new(fakeSecretStore))
+	defer func() {
+		defaultRegistry.mu.Lock()
+		delete(defaultRegistry.modules, "secrets.stores.test_fake")
+		defaultRegistry.mu.Unlock()
+	}()
+
+	cfg := &Config{Secrets: &Secrets{StoresRaw: map[string]json.RawMessage{
+		"fake": json.RawMessage(`{"source":"test_fake","values":{"db":"hunter2"}}`),
+	}}}
+	ctx, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := NewReplacer()
+	if got := rep.ReplaceKnown("{secret.fake.db}", ""); got != "hunter2" {
+		t.Errorf("ReplaceKnown() = %q, want the secret of the running config", got)
+	}
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rep.ReplaceKnown("{secret.fake.db}", ""); got != "{secret.fake.db}" {
+		t.Errorf("secrets still provided after the config stopped: %q", got)
+	}
+}
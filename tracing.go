@@ -0,0 +1,368 @@
+package uni
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	RegisterNamespace[SpanExporter]("uni.tracing.exporters")
+}
+
+// DefaultSlowProvision is how long a module may take to
+// provision and validate before a warning is logged, unless
+// Tracing.SlowProvision says otherwise. Provisioning should
+// be imperceptible (see Provisioner).
+const DefaultSlowProvision = 100 * time.Millisecond
+
+// Tracing configures how the loading of modules is traced. The
+// time each module spends in Provision and Validate is always
+// recorded in the uni_module_provision_duration_seconds
+// histogram; with an exporter, every module load is also
+// exported as a span, whose parent is the span of the module
+// which loaded it.
+type Tracing struct {
+	// Modules which take longer than this to provision and
+	// validate, not counting the time spent loading their
+	// submodules, are logged with a warning. Default: 100ms.
+	// A negative value disables the warning.
+	SlowProvision Duration `json:"slow_provision,omitempty"`
+
+	// The module which exports the spans. If not set, no
+	// spans are recorded.
+	ExporterRaw json.RawMessage `json:"exporter,omitempty" uni:"namespace=uni.tracing.exporters inline_key=exporter"`
+
+	exporter SpanExporter
+	spans    chan Span
+	done     chan struct{}
+}
+
+// SpanExporter exports spans, for example to an OpenTelemetry
+// collector. Modules in the `uni.tracing.exporters` namespace
+// must implement it.
+type SpanExporter interface {
+	// ExportSpans exports a batch of finished spans. It is
+	// never called concurrently.
+	ExportSpans(ctx context.Context, spans []Span) error
+}
+
+// Span is an operation that was traced, such as loading a
+// module. Its fields follow the OpenTelemetry data model.
+type Span struct {
+	// TraceID and SpanID identify the span, and ParentSpanID
+	// is the ID of the span it is part of, if any.
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID
+
+	Name       string
+	Start, End time.Time
+	Attributes []SpanAttribute
+
+	// Err is the error the operation failed with, if any.
+	Err string
+}
+
+// SpanAttribute is a key/value pair describing a span.
+type SpanAttribute struct {
+	Key   string
+	Value string
+}
+
+// TraceID identifies a trace: a tree of spans.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// String returns the ID in hex, or "" if it is zero.
+func (id TraceID) String() string {
+	if id == (TraceID{}) {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// String returns the ID in hex, or "" if it is zero.
+func (id SpanID) String() string {
+	if id == (SpanID{}) {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	binary.LittleEndian.PutUint64(id[:8], rand.Uint64())
+	binary.LittleEndian.PutUint64(id[8:], rand.Uint64())
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	binary.LittleEndian.PutUint64(id[:], rand.Uint64()|1) // never zero
+	return id
+}
+
+var (
+	activeTracing   *Tracing
+	activeTracingMu sync.RWMutex
+)
+
+// Provision loads the exporter, if any, and makes the Tracing
+// the one which module loads are traced with, replacing the
+// Tracing of any previous config.
+func (t *Tracing) Provision(ctx Context) error {
+	if t.ExporterRaw != nil {
+		var err error
+		t.exporter, err = LoadModuleAs[SpanExporter](ctx, t, "ExporterRaw")
+		if err != nil {
+			return fmt.Errorf("loading span exporter: %w", err)
+		}
+		t.spans = make(chan Span, spanQueueSize)
+		t.done = make(chan struct{})
+		go t.export(t.spans)
+	}
+
+	activeTracingMu.Lock()
+	activeTracing = t
+	activeTracingMu.Unlock()
+
+	return nil
+}
+
+// Cleanup stops tracing module loads with t, unless another
+// Tracing replaced it already, and exports the remaining spans.
+func (t *Tracing) Cleanup() error {
+	// once t is not active, no more spans are recorded with
+	// it (see record), so its queue can be closed
+	activeTracingMu.Lock()
+	if activeTracing == t {
+		activeTracing = nil
+	}
+	activeTracingMu.Unlock()
+
+	if t.spans != nil {
+		close(t.spans)
+		<-t.done
+		t.spans = nil
+	}
+	return nil
+}
+
+const (
+	// spanQueueSize is how many spans can wait to be exported;
+	// more are dropped
+	spanQueueSize = 2048
+
+	// spanBatchSize and spanBatchDelay limit how many spans
+	// are exported at once, and how long they may wait for
+	// more spans to be exported with
+	spanBatchSize  = 256
+	spanBatchDelay = time.Second
+)
+
+// record queues span for export, dropping it if the queue
+// is full. Spans of a Tracing which is not the active one
+// anymore are dropped too, since its queue may be closed.
+func (t *Tracing) record(span Span) {
+	activeTracingMu.RLock()
+	defer activeTracingMu.RUnlock()
+	if activeTracing != t {
+		return
+	}
+	select {
+	case t.spans <- span:
+	default:
+	}
+}
+
+// export exports the spans queued in spans in batches until
+// the queue is closed.
+func (t *Tracing) export(spans <-chan Span) {
+	defer close(t.done)
+
+	ticker := time.NewTicker(spanBatchDelay)
+	defer ticker.Stop()
+
+	var batch []Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := t.exporter.ExportSpans(ctx, batch); err != nil {
+			Log().Error("exporting spans", zap.Int("spans", len(batch)), zap.Error(err))
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case span, ok := <-spans:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= spanBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// provisionTiming is how long loading a module took.
+type provisionTiming struct {
+	start time.Time
+
+	// provision and validate are the time spent in the
+	// module's Provision and Validate methods; children
+	// is the part of it spent loading submodules
+	provision, validate, children time.Duration
+}
+
+// traceProvision records the timing of loading the module
+// instance node: in the histogram, as a warning if the module
+// is slow, and as a span if there is an exporter.
+func traceProvision(node *instanceNode, timing provisionTiming, err error) {
+	if timing.provision > 0 {
+		moduleMetrics.provisionDuration.WithLabelValues(node.id, "provision").Observe(timing.provision.Seconds())
+	}
+	if timing.validate > 0 {
+		moduleMetrics.provisionDuration.WithLabelValues(node.id, "validate").Observe(timing.validate.Seconds())
+	}
+
+	activeTracingMu.RLock()
+	t := activeTracing
+	activeTracingMu.RUnlock()
+
+	budget := DefaultSlowProvision
+	if t != nil && t.SlowProvision != 0 {
+		budget = time.Duration(t.SlowProvision)
+	}
+	if own := timing.provision + timing.validate - timing.children; budget > 0 && own > budget {
+		Log().Warn("module is slow to provision",
+			zap.String("module", node.id),
+			zap.String("config_pointer", node.pointer),
+			zap.Duration("provision", timing.provision),
+			zap.Duration("validate", timing.validate),
+			zap.Duration("submodules", timing.children),
+			zap.Duration("budget", budget))
+	}
+
+	if t == nil || t.exporter == nil {
+		return
+	}
+	span := Span{
+		TraceID: node.traceID,
+		SpanID:  node.spanID,
+		Name:    "provision " + node.id,
+		Start:   timing.start,
+		End:     time.Now(),
+		Attributes: []SpanAttribute{
+			{Key: "uni.module.id", Value: node.id},
+			{Key: "uni.module.instance", Value: strconv.FormatUint(node.num, 10)},
+			{Key: "uni.module.provision_duration", Value: timing.provision.String()},
+			{Key: "uni.module.validate_duration", Value: timing.validate.String()},
+		},
+	}
+	if node.parent != nil {
+		span.ParentSpanID = node.parent.spanID
+	}
+	if node.pointer != "" {
+		span.Attributes = append(span.Attributes, SpanAttribute{Key: "uni.config.pointer", Value: node.pointer})
+	}
+	if err != nil {
+		span.Err = err.Error()
+	}
+	t.record(span)
+}
+
+// MarshalOTLPJSON encodes spans as an OTLP/HTTP JSON export
+// request (ExportTraceServiceRequest), which OpenTelemetry
+// collectors accept at /v1/traces.
+func MarshalOTLPJSON(spans []Span) ([]byte, error) {
+	type otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	type otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	type otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	type otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	attrs := func(list []SpanAttribute) []otlpAttribute {
+		var out []otlpAttribute
+		for _, a := range list {
+			out = append(out, otlpAttribute{Key: a.Key, Value: otlpValue{StringValue: a.Value}})
+		}
+		return out
+	}
+
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		os := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			ParentSpanID:      s.ParentSpanID.String(),
+			Name:              s.Name,
+			Kind:              1, // SPAN_KIND_INTERNAL
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        attrs(s.Attributes),
+		}
+		if s.Err != "" {
+			os.Status = otlpStatus{Code: 2, Message: s.Err} // STATUS_CODE_ERROR
+		}
+		out = append(out, os)
+	}
+
+	_, version := Version()
+	return json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": attrs([]SpanAttribute{
+					{Key: "service.name", Value: "uni"},
+					{Key: "service.version", Value: version},
+				}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/yonomesh/uni"},
+				"spans": out,
+			}},
+		}},
+	})
+}
+
+// Interface guards
+var (
+	_ Provisioner  = (*Tracing)(nil)
+	_ CleanerUpper = (*Tracing)(nil)
+)
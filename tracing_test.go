@@ -0,0 +1,258 @@
+package uni
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type tracingTestExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func (*tracingTestExporter) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "uni.tracing.exporters.tracing_test", New: func() Module { return tracingTestExporterInstance }}
+}
+
+func (e *tracingTestExporter) ExportSpans(_ context.Context, spans []Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+var tracingTestExporterInstance = new(tracingTestExporter)
+
+type tracingTestModule struct {
+	Sleep    Duration        `json:"sleep,omitempty"`
+	Fail     bool            `json:"fail,omitempty"`
+	ChildRaw json.RawMessage `json:"child,omitempty" uni:"namespace=tracing_test inline_key=module"`
+}
+
+func (tracingTestModule) UniModule() ModuleInfo {
+	return ModuleInfo{ID: "tracing_test.module", New: func() Module { return new(tracingTestModule) }}
+}
+
+func (m *tracingTestModule) Provision(ctx Context) error {
+	if m.ChildRaw != nil {
+		if _, err := ctx.LoadModule(m, "ChildRaw"); err != nil {
+			return err
+		}
+	}
+	time.Sleep(time.Duration(m.Sleep))
+	return nil
+}
+
+func (m *tracingTestModule) Validate() error {
+	if m.Fail {
+		return errors.New("invalid")
+	}
+	return nil
+}
+
+// not parallel: tracing and the default logger are global
+func TestTraceProvision(t *testing.T) {
+	tracingTestExporterInstance.spans = nil
+
+	r := NewRegistry()
+	r.RegisterModule(tracingTestExporterInstance)
+	r.RegisterModule(tracingTestModule{})
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	defaultLoggerMu.Lock()
+	origLogger := defaultLogger.logger
+	defaultLogger.logger = zap.New(core)
+	defaultLoggerMu.Unlock()
+	defer func() {
+		defaultLoggerMu.Lock()
+		defaultLogger.logger = origLogger
+		defaultLoggerMu.Unlock()
+	}()
+
+	ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+	defer cancel()
+
+	tracing := &Tracing{
+		SlowProvision: Duration(20 * time.Millisecond),
+		ExporterRaw:   json.RawMessage(`{"exporter": "tracing_test"}`),
+	}
+	if err := tracing.Provision(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the parent is slow only because of its child, which is
+	// slow itself, so only the child is reported
+	_, err := ctx.WithConfigPointer("outer").LoadModuleByID("tracing_test.module",
+		json.RawMessage(`{"child": {"module": "module", "sleep": "30ms"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctx.LoadModuleByID("tracing_test.module", json.RawMessage(`{"fail": true}`)); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if err := tracing.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+
+	slow := logs.FilterMessage("module is slow to provision").All()
+	if len(slow) != 1 {
+		t.Fatalf("got %d slow module warnings, want 1", len(slow))
+	}
+	if ptr := slow[0].ContextMap()["config_pointer"]; ptr != "/outer/child" {
+		t.Errorf("slow module config_pointer = %v, want /outer/child", ptr)
+	}
+
+	spans := tracingTestExporterInstance.spans
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	child, parent, failed := spans[0], spans[1], spans[2]
+	if child.TraceID != parent.TraceID || child.ParentSpanID != parent.SpanID || parent.ParentSpanID != (SpanID{}) {
+		t.Errorf("child span is not part of the parent's: %+v, %+v", child, parent)
+	}
+	if failed.TraceID == parent.TraceID || failed.Err == "" || parent.Err != "" {
+		t.Errorf("unexpected spans: %+v, %+v", parent, failed)
+	}
+	if d := child.End.Sub(child.Start); d < 30*time.Millisecond {
+		t.Errorf("child span took %s, want at least 30ms", d)
+	}
+
+	families, err := ctx.metricsRegistry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var observed uint64
+	for _, mf := range families {
+		if mf.GetName() != "uni_module_provision_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "module" && l.GetValue() == "tracing_test.module" {
+					observed += m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	// provision and validate of each of the three modules
+	if observed < 6 {
+		t.Errorf("observed %d provision durations, want at least 6", observed)
+	}
+}
+
+func TestRunTracing(t *testing.T) {
+	cfg := &Config{Tracing: &Tracing{}}
+	ctx, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeTracingMu.RLock()
+	active := activeTracing
+	activeTracingMu.RUnlock()
+	if active != cfg.Tracing {
+		t.Error("the tracing of the running config is not active")
+	}
+
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	activeTracingMu.RLock()
+	active = activeTracing
+	activeTracingMu.RUnlock()
+	if active != nil {
+		t.Error("tracing still active after the config stopped")
+	}
+}
+
+func TestMarshalOTLPJSON(t *testing.T) {
+	start := time.Unix(1, 500)
+	body, err := MarshalOTLPJSON([]Span{{
+		TraceID:    TraceID{1},
+		SpanID:     SpanID{2},
+		Name:       "provision a",
+		Start:      start,
+		End:        start.Add(time.Second),
+		Attributes: []SpanAttribute{{Key: "uni.module.id", Value: "a"}},
+		Err:        "failed",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []map[string]any `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	for key, want := range map[string]any{
+		"traceId":           "01000000000000000000000000000000",
+		"spanId":            "0200000000000000",
+		"startTimeUnixNano": "1000000500",
+		"endTimeUnixNano":   "2000000500",
+		"status":            map[string]any{"code": float64(2), "message": "failed"},
+	} {
+		got, _ := json.Marshal(span[key])
+		wantJSON, _ := json.Marshal(want)
+		if string(got) != string(wantJSON) {
+			t.Errorf("%s = %s, want %s", key, got, wantJSON)
+		}
+	}
+	if _, ok := span["parentSpanId"]; ok {
+		t.Error("root span has a parentSpanId")
+	}
+}
+
+// not parallel: tracing is global
+func TestTracingCleanupWhileLoading(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterModule(tracingTestExporterInstance)
+	r.RegisterModule(tracingTestModule{})
+
+	for range 20 {
+		ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+		tracing := &Tracing{ExporterRaw: json.RawMessage(`{"exporter": "tracing_test"}`)}
+		if err := tracing.Provision(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		// keep loading modules while the tracing is cleaned up
+		var stop atomic.Bool
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				ctx, cancel := NewContext(Context{Context: context.Background(), cfg: &Config{}}.WithRegistry(r))
+				defer cancel()
+				for !stop.Load() {
+					_, _ = ctx.LoadModuleByID("tracing_test.module", nil)
+				}
+			})
+		}
+		time.Sleep(time.Millisecond)
+		if err := tracing.Cleanup(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		stop.Store(true)
+		wg.Wait()
+
+		// a module load that read the tracing before it was
+		// cleaned up records its span afterwards
+		tracing.record(Span{})
+		cancel()
+	}
+}
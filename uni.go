@@ -2,12 +2,21 @@ package uni
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/yonomesh/uuid"
 	"go.uber.org/zap"
 )
 
+func init() {
+	RegisterNamespace[App]("")
+}
+
 // Config is the top (or beginning) of the Uni configuration structure.
 //
 // Many parts of this config are extensible through the use of Kaze modules.
@@ -28,11 +37,49 @@ import (
 // with `json` struct tags) if employing the module lifecycle (e.g. Provision
 // method calls).
 type Config struct {
+	// AppsRaw are the apps that Uni will load and run. The
+	// app module name is the key, and the app's config is the
+	// associated value.
+	AppsRaw ModuleMap `json:"apps,omitempty" uni:"namespace="`
+
+	// Admin configures the admin endpoint. If not set, the
+	// admin endpoint is not served.
+	Admin *AdminConfig `json:"admin,omitempty"`
+
+	// Logging configures the logs, including the audit
+	// log (see AuditLogName).
+	Logging *Logging `json:"logging,omitempty"`
+
+	// Secrets configures the secret stores that provide
+	// {secret.*} placeholders.
+	Secrets *Secrets `json:"secrets,omitempty"`
+
+	// Tracing configures how long modules may take to
+	// provision, and where spans of loading them go.
+	Tracing *Tracing `json:"tracing,omitempty"`
+
+	// FilePlaceholders restricts which files {file.*}
+	// placeholders may read.
+	FilePlaceholders *FilePlaceholders `json:"file_placeholders,omitempty"`
+
+	// ExpandPlaceholders, if set, makes the config expand
+	// global placeholders in its string values when it is
+	// loaded (see Load and ExpandConfigJSON).
+	ExpandPlaceholders *ConfigExpansion `json:"expand_placeholders,omitempty"`
+
 	apps map[string]App
 
 	// failedApps is a map of apps that failed to provision with their underlying error.
 	failedApps   map[string]error
 	eventEmitter eventEmitter
+
+	// loadingApps are the names of the apps being loaded
+	// by Context.App, innermost last
+	loadingApps []string
+
+	// auditLog receives the entries written with Context.Audit;
+	// it is nil if the config has no audit log.
+	auditLog *AuditLog
 }
 
 // App is a thing that Caddy runs.
@@ -41,6 +88,16 @@ type App interface {
 	Stop() error
 }
 
+// AppDependencies is implemented by apps which use other apps
+// while running, so that Run starts those apps first, and stops
+// them last. Apps that are depended on but not configured are
+// loaded with their default config.
+type AppDependencies interface {
+	// DependsOn returns the names of the apps this app
+	// depends on.
+	DependsOn() []string
+}
+
 // Event represents something that has happened or is happening.
 // An Event value is not synchronized, so it should be copied if
 // being used in goroutines.
@@ -79,6 +136,261 @@ type Event struct {
 // code is emitted.
 func exitProcess(ctx context.Context, logger *zap.Logger) {}
 
+// Load decodes cfgJSON, expanding its placeholders first if it
+// opts in to that (see Config.ExpandPlaceholders), and runs the
+// resulting config (see Run). The config is decoded before it
+// is expanded too, so that errors in the JSON are located in
+// cfgJSON rather than in the expanded config.
+func Load(cfgJSON []byte) (Context, error) {
+	var cfg Config
+	if err := StrictUnmarshalJSON(cfgJSON, &cfg); err != nil {
+		return Context{}, err
+	}
+	expanded, report, err := ExpandConfigJSON(cfgJSON)
+	if err != nil {
+		return Context{}, fmt.Errorf("expanding config: %w", err)
+	}
+	if len(report.Substitutions) > 0 {
+		Log().Info("expanded config placeholders", zap.Int("values", len(report.Substitutions)))
+	}
+	cfg = Config{}
+	if err := StrictUnmarshalJSON(expanded, &cfg); err != nil {
+		return Context{}, err
+	}
+	return Run(&cfg)
+}
+
+// Run provisions cfg in a new context and starts its apps, each
+// after the apps it depends on (see AppDependencies) and then in
+// order of name. Closing the returned context stops the apps in
+// the reverse order and then cleans up the config. If any part
+// of the config fails to load or any app fails to start, the
+// apps that were started are stopped again, the context is
+// closed and the error is returned. The config must not be run
+// more than once.
+func Run(cfg *Config) (Context, error) {
+	cfg.apps = make(map[string]App)
+	cfg.failedApps = make(map[string]error)
+
+	ctx, _ := NewContext(Context{Context: context.Background(), cfg: cfg})
+
+	// what Run sets up is undone in reverse order once ctx is
+	// canceled, before the modules of ctx are cleaned up
+	var undo []func() error
+	ctx.OnCancel(func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				Log().Error("stopping config", zap.Error(err))
+			}
+		}
+	})
+	fail := func(err error) (Context, error) {
+		FlushBufferedLog(err)
+		_ = ctx.Close()
+		return ctx, err
+	}
+
+	// entries logged while the config loads are written to
+	// its logs once they are installed
+	var startupBuffer *StartupLogBuffer
+	if cfg.Logging != nil {
+		startupBuffer = cfg.Logging.StartupBuffer
+	}
+	level, maxEntries, err := startupBuffer.settings()
+	if err != nil {
+		return fail(err)
+	}
+	bufferedLog(level, maxEntries)
+	warnModuleProblems()
+
+	// tracing comes first, so that it covers all modules
+	if cfg.Tracing != nil {
+		if err := cfg.Tracing.Provision(ctx.WithConfigPointer("tracing")); err != nil {
+			return fail(fmt.Errorf("setting up tracing: %w", err))
+		}
+		undo = append(undo, cfg.Tracing.Cleanup)
+	}
+	if cfg.FilePlaceholders != nil {
+		if err := cfg.FilePlaceholders.Provision(ctx.WithConfigPointer("file_placeholders")); err != nil {
+			return fail(fmt.Errorf("setting up file placeholders: %w", err))
+		}
+		undo = append(undo, cfg.FilePlaceholders.Cleanup)
+	}
+	if cfg.Secrets != nil {
+		if err := cfg.Secrets.Provision(ctx.WithConfigPointer("secrets")); err != nil {
+			return fail(fmt.Errorf("setting up secrets: %w", err))
+		}
+		undo = append(undo, cfg.Secrets.Cleanup)
+	}
+	if cfg.Logging != nil {
+		auditLog, err := cfg.Logging.openAuditLog(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		if auditLog != nil {
+			cfg.auditLog = auditLog
+			undo = append(undo, func() error {
+				return errors.Join(auditLog.Close(), cfg.Logging.Logs[AuditLogName].Cleanup())
+			})
+		}
+		closeLogs, err := cfg.Logging.openLogs(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		undo = append(undo, closeLogs)
+	}
+	if cfg.Admin != nil {
+		stopAdmin, err := cfg.Admin.serve(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		undo = append(undo, stopAdmin)
+	}
+
+	order, err := ctx.loadApps()
+	if err != nil {
+		return fail(err)
+	}
+	for _, name := range order {
+		app := cfg.apps[name]
+		if err := app.Start(); err != nil {
+			return fail(fmt.Errorf("%s app module: start: %w", name, err))
+		}
+		undo = append(undo, func() error {
+			if err := app.Stop(); err != nil {
+				return fmt.Errorf("%s app module: stop: %w", name, err)
+			}
+			return nil
+		})
+	}
+
+	FlushBufferedLog(nil)
+	return ctx, nil
+}
+
+// loadApps loads the configured apps, and the apps they depend
+// on, and returns the names of all loaded apps in the order to
+// start them in.
+func (ctx Context) loadApps() ([]string, error) {
+	for _, name := range slices.Sorted(maps.Keys(ctx.cfg.AppsRaw)) {
+		if _, err := ctx.App(name); err != nil {
+			return nil, err
+		}
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+	var order, path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		// dependencies may be named by an alias of the app
+		name, _ = ctx.appKey(name)
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return appCycleError(path, name)
+		}
+		if _, err := ctx.App(name); err != nil {
+			return err
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		if deps, ok := ctx.cfg.apps[name].(AppDependencies); ok {
+			for _, dep := range deps.DependsOn() {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		order = append(order, name)
+		return nil
+	}
+
+	// apps may load other apps, so keep going until
+	// all apps are in order
+	for len(order) < len(ctx.cfg.apps) {
+		for _, name := range slices.Sorted(maps.Keys(ctx.cfg.apps)) {
+			if err := visit(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return order, nil
+}
+
+// appCycleError returns the error for an app that depends on
+// itself through the apps in path.
+func appCycleError(path []string, name string) error {
+	cycle := slices.Concat(path[slices.Index(path, name):], []string{name})
+	return fmt.Errorf("app dependency cycle: %s", strings.Join(cycle, " -> "))
+}
+
+// Validate loads and provisions the modules of cfg in a context
+// of their own, without putting the config into effect, and then
+// cleans them up again. Errors in the config are returned with a
+// *ConfigError, whose pointer is relative to the JSON of cfg. The
+// raw module configs of cfg are cleared by loading them, so cfg
+// should not be used again afterwards.
+func Validate(cfg *Config) error {
+	cfg.apps = make(map[string]App)
+	cfg.failedApps = make(map[string]error)
+
+	ctx, cancel := NewContext(Context{Context: context.Background(), cfg: cfg})
+	defer cancel()
+
+	if cfg.Logging != nil {
+		if _, _, err := cfg.Logging.StartupBuffer.settings(); err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(cfg.Logging.Logs)) {
+			cl := cfg.Logging.Logs[name]
+			if cl == nil {
+				continue
+			}
+			err := cl.Provision(ctx.WithConfigPointer("logging", "logs", name))
+			if cerr := cl.Cleanup(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("setting up log %s: %w", name, err)
+			}
+		}
+	}
+
+	// the stores are loaded directly, since provisioning
+	// the Secrets would make them the active ones
+	if cfg.Secrets != nil {
+		_, err := LoadModuleMapAs[SecretStore](ctx.WithConfigPointer("secrets"), cfg.Secrets, "StoresRaw")
+		if err != nil {
+			return fmt.Errorf("loading secret stores: %w", err)
+		}
+	}
+
+	// likewise, provisioning the Tracing would make it trace
+	// the modules of the running config
+	if cfg.Tracing != nil && cfg.Tracing.ExporterRaw != nil {
+		_, err := ctx.WithConfigPointer("tracing").LoadModule(cfg.Tracing, "ExporterRaw")
+		if err != nil {
+			return fmt.Errorf("loading span exporter: %w", err)
+		}
+	}
+
+	if _, err := ctx.loadApps(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // CtxKey is a value type for use with context.WithValue.
 type CtxKey string
 
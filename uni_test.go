@@ -0,0 +1,89 @@
+package uni
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// not parallel: Run uses the default registry
+func TestRunAppOrder(t *testing.T) {
+	origRegistry := defaultRegistry
+	defaultRegistry = NewRegistry()
+	defer func() { defaultRegistry = origRegistry }()
+
+	var log []string
+	for _, m := range []appTestModule{
+		{info: ModuleInfo{ID: "web"}, deps: []string{"tls"}},
+		{info: ModuleInfo{ID: "tls"}, deps: []string{"storage"}},
+		{info: ModuleInfo{ID: "storage"}},
+		{info: ModuleInfo{ID: "events"}},
+		{info: ModuleInfo{ID: "broken"}, deps: []string{"tls"}, failStart: true},
+		{info: ModuleInfo{ID: "p"}, deps: []string{"q"}},
+		{info: ModuleInfo{ID: "q"}, deps: []string{"p"}},
+		{info: ModuleInfo{ID: "cache", Aliases: []ModuleID{"old_cache"}}},
+		{info: ModuleInfo{ID: "api"}, deps: []string{"old_cache"}},
+	} {
+		m.log = &log
+		RegisterModule(newAppTestModule(m))
+	}
+	apps := func(names ...string) ModuleMap {
+		m := make(ModuleMap)
+		for _, name := range names {
+			m[name] = json.RawMessage(`{}`)
+		}
+		return m
+	}
+
+	ctx, err := Run(&Config{AppsRaw: apps("web", "events", "tls")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"start events", "start storage", "start tls", "start web",
+		"stop web", "stop tls", "stop storage", "stop events",
+	}
+	if !slices.Equal(log, want) {
+		t.Errorf("got %v, want %v", log, want)
+	}
+
+	log = nil
+	if _, err := Run(&Config{AppsRaw: apps("broken")}); err == nil || !strings.Contains(err.Error(), "broken app module: start: failed") {
+		t.Errorf("Run() error = %v, want start error", err)
+	}
+	want = []string{"start storage", "start tls", "stop tls", "stop storage"}
+	if !slices.Equal(log, want) {
+		t.Errorf("got %v, want %v", log, want)
+	}
+
+	// apps may be configured and depended on by an alias
+	log = nil
+	ctx, err = Run(&Config{AppsRaw: apps("api", "old_cache")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"start cache", "start api", "stop api", "stop cache"}
+	if !slices.Equal(log, want) {
+		t.Errorf("got %v, want %v", log, want)
+	}
+
+	log = nil
+	cfg := &Config{AppsRaw: apps("p")}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "app dependency cycle: p -> q -> p") {
+		t.Errorf("Validate() error = %v, want dependency cycle", err)
+	}
+	if len(log) != 0 {
+		t.Errorf("Validate() started apps: %v", log)
+	}
+	if got := slices.Sorted(maps.Keys(cfg.apps)); !slices.Equal(got, []string{"p", "q"}) {
+		t.Errorf("loaded apps %v, want [p q]", got)
+	}
+}
@@ -0,0 +1,384 @@
+package unicmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yonomesh/uni"
+)
+
+func cmdLogs(fl Flags) (int, error) {
+	params := url.Values{}
+	for _, name := range []string{"buffer", "level", "logger", "since", "until", "contains"} {
+		if v := fl.String(name); v != "" {
+			params.Set(name, v)
+		}
+	}
+	if limit := fl.Int("limit"); limit > 0 {
+		params.Set("limit", fmt.Sprint(limit))
+	}
+
+	uri := "/logs/recent"
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+
+	resp, err := AdminAPIRequest(fl.String("address"), http.MethodGet, uri, nil)
+	if err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+	defer resp.Body.Close()
+
+	var entries []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return uni.ExitCodeFailedStartup, fmt.Errorf("decoding response: %v", err)
+	}
+	for _, e := range entries {
+		fmt.Fprintln(os.Stdout, string(e))
+	}
+
+	return uni.ExitCodeSuccess, nil
+}
+
+func cmdVerifyAuditLog(fl Flags) (int, error) {
+	var in io.Reader = os.Stdin
+	if name := fl.String("file"); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return uni.ExitCodeFailedStartup, fmt.Errorf("opening audit log: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var key []byte
+	if name := fl.String("key-file"); name != "" {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return uni.ExitCodeFailedStartup, fmt.Errorf("reading audit key: %v", err)
+		}
+		key = bytes.TrimSuffix(bytes.TrimSuffix(b, []byte("\n")), []byte("\r"))
+	}
+
+	report, err := uni.VerifyAuditLog(in, key)
+	if err != nil {
+		return uni.ExitCodeFailedStartup, fmt.Errorf("reading audit log: %v", err)
+	}
+
+	if fl.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(report); err != nil {
+			return uni.ExitCodeFailedStartup, err
+		}
+	} else {
+		for _, p := range report.Problems {
+			fmt.Fprintln(os.Stdout, p)
+		}
+		fmt.Fprintf(os.Stdout, "%d entries in %d chain(s), %d problem(s)\n",
+			report.Entries, report.Chains, len(report.Problems))
+	}
+
+	if !report.OK() {
+		return uni.ExitCodeFailedStartup, fmt.Errorf("audit log verification failed")
+	}
+	return uni.ExitCodeSuccess, nil
+}
+
+func cmdListPlaceholders(fl Flags) (int, error) {
+	namespaces := uni.PlaceholderNamespaces()
+
+	if fl.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(namespaces); err != nil {
+			return uni.ExitCodeFailedStartup, err
+		}
+		return uni.ExitCodeSuccess, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, ns := range namespaces {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", ns.Name, ns.Description)
+		for _, doc := range ns.Placeholders {
+			fmt.Fprintf(w, "  {%s}\t%s\n", doc.Key, doc.Description)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+
+	return uni.ExitCodeSuccess, nil
+}
+
+func cmdExpandConfig(fl Flags) (int, error) {
+	cfgJSON, err := readConfig(fl.String("config"))
+	if err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+
+	opts := uni.ConfigExpansion{Strict: fl.Bool("strict")}
+	if list := fl.String("namespaces"); list != "" {
+		for _, ns := range strings.Split(list, ",") {
+			opts.Namespaces = append(opts.Namespaces, strings.TrimSpace(ns))
+		}
+	}
+
+	expanded, report, err := uni.ExpandConfigPlaceholders(cfgJSON, opts)
+	if err != nil {
+		return uni.ExitCodeFailedStartup, fmt.Errorf("expanding config: %v", err)
+	}
+
+	if fl.Bool("report") {
+		for _, sub := range report.Substitutions {
+			fmt.Fprintf(os.Stderr, "%s\t%s\n", sub.Path, strings.Join(sub.Placeholders, ", "))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, expanded, "", "\t"); err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+	buf.WriteByte('\n')
+	if _, err := buf.WriteTo(os.Stdout); err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+
+	return uni.ExitCodeSuccess, nil
+}
+
+func cmdValidate(fl Flags) (int, error) {
+	cfgJSON, err := readConfig(fl.String("config"))
+	if err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+
+	err = validateConfig(cfgJSON)
+	if err != nil {
+		// the config error is the most precise part of the error,
+		// and the only one that can report the line and column
+		var ce *uni.ConfigError
+		if errors.As(err, &ce) {
+			ce.Locate(cfgJSON)
+			if ce.Snippet != "" {
+				fmt.Fprintf(os.Stderr, "%s\n", ce.Snippet)
+			}
+			err = ce
+		}
+		return uni.ExitCodeFailedStartup, fmt.Errorf("invalid config: %w", err)
+	}
+
+	fmt.Println("Valid configuration")
+	return uni.ExitCodeSuccess, nil
+}
+
+// validateConfig decodes cfgJSON, expanding its placeholders if
+// it opts in to that, and validates the result. The config is
+// decoded before expanding it too, so that errors in the JSON
+// are located in cfgJSON rather than in the expanded config.
+func validateConfig(cfgJSON []byte) error {
+	var cfg uni.Config
+	if err := uni.StrictUnmarshalJSON(cfgJSON, &cfg); err != nil {
+		return err
+	}
+	expanded, _, err := uni.ExpandConfigJSON(cfgJSON)
+	if err != nil {
+		return fmt.Errorf("expanding config: %w", err)
+	}
+	cfg = uni.Config{}
+	if err := uni.StrictUnmarshalJSON(expanded, &cfg); err != nil {
+		return err
+	}
+	return uni.Validate(&cfg)
+}
+
+// readConfig reads the config file with the given name, or
+// standard input if name is empty or "-".
+func readConfig(name string) ([]byte, error) {
+	var in io.Reader = os.Stdin
+	if name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("opening config: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	cfgJSON, err := io.ReadAll(in)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %v", err)
+	}
+	return cfgJSON, nil
+}
+
+func cmdCheckModules(fl Flags) (int, error) {
+	problems := uni.CheckModules()
+
+	if fl.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(problems); err != nil {
+			return uni.ExitCodeFailedStartup, err
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stdout, p)
+		}
+	}
+
+	if len(problems) > 0 {
+		return uni.ExitCodeFailedStartup, fmt.Errorf("%d module problem(s) found", len(problems))
+	}
+	return uni.ExitCodeSuccess, nil
+}
+
+func cmdJSONSchema(fl Flags) (int, error) {
+	gen := uni.NewSchemaGenerator()
+	if dir := fl.String("source"); dir != "" {
+		if err := gen.LoadDocs(dir); err != nil {
+			return uni.ExitCodeFailedStartup, fmt.Errorf("loading doc comments: %v", err)
+		}
+	}
+
+	var schema *uni.JSONSchema
+	if id := fl.String("module"); id != "" {
+		var err error
+		schema, err = gen.ModuleSchema(id)
+		if err != nil {
+			return uni.ExitCodeFailedStartup, err
+		}
+	} else {
+		schema = gen.ConfigSchema()
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(schema); err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+	return uni.ExitCodeSuccess, nil
+}
+
+func cmdDocs(fl Flags) (int, error) {
+	if fl.NArg() == 0 {
+		for _, id := range uni.Modules() {
+			fmt.Fprintln(os.Stdout, id)
+		}
+		return uni.ExitCodeSuccess, nil
+	}
+
+	var docs map[string]string
+	if dir := fl.String("source"); dir != "" {
+		var err error
+		docs, err = uni.LoadDocComments(dir)
+		if err != nil {
+			return uni.ExitCodeFailedStartup, fmt.Errorf("loading doc comments: %v", err)
+		}
+	}
+
+	md, err := uni.DocumentModule(fl.Arg(0), docs)
+	if err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+
+	if fl.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(md); err != nil {
+			return uni.ExitCodeFailedStartup, err
+		}
+		return uni.ExitCodeSuccess, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", md.ID)
+	fmt.Fprintf(w, "Namespace:\t%s\n", md.Namespace)
+	if len(md.Aliases) > 0 {
+		fmt.Fprintf(w, "Aliases:\t%s\n", strings.Join(md.Aliases, ", "))
+	}
+	if md.Deprecated != nil {
+		fmt.Fprintf(w, "Status:\t%s\n", md.Deprecated)
+	}
+	fmt.Fprintf(w, "Package:\t%s\n", md.Package)
+	fmt.Fprintf(w, "Type:\t%s\n", md.Type)
+	fmt.Fprintf(w, "Implements:\t%s\n", strings.Join(md.Implements, ", "))
+	if md.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", md.Description)
+	}
+	if len(md.Fields) > 0 {
+		fmt.Fprintln(w, "\nFields:")
+		for _, f := range md.Fields {
+			var notes []string
+			if f.Namespace != "" {
+				note := "modules in " + f.Namespace
+				if f.InlineKey != "" {
+					note += fmt.Sprintf(" (inline key %q)", f.InlineKey)
+				}
+				notes = append(notes, note)
+			}
+			if f.Default != "" {
+				notes = append(notes, "default: "+f.Default)
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", f.Name, f.Type, strings.Join(notes, "; "))
+		}
+	}
+	if len(md.LoadedBy) > 0 {
+		fmt.Fprintln(w, "\nLoaded by:")
+		for _, h := range md.LoadedBy {
+			fmt.Fprintf(w, "  %s\t%s\n", h.Host, h.Field)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return uni.ExitCodeFailedStartup, err
+	}
+
+	return uni.ExitCodeSuccess, nil
+}
+
+// AdminAPIRequest makes an API request to the admin endpoint
+// at adminAddr (or DefaultAdminListen if empty) and returns
+// the response. Responses with a status code of 400 or
+// higher are turned into an error carrying the message from
+// the response body.
+func AdminAPIRequest(adminAddr, method, uri string, body io.Reader) (*http.Response, error) {
+	if adminAddr == "" {
+		adminAddr = uni.DefaultAdminListen
+	}
+	if !strings.Contains(adminAddr, "://") {
+		adminAddr = "http://" + adminAddr
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(adminAddr, "/")+uri, body)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("performing request: %v", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var apiErr uni.APIError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+			return nil, fmt.Errorf("admin API responded with error: HTTP %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("admin API responded with error: HTTP %d: %s", resp.StatusCode, apiErr.Message)
+	}
+
+	return resp, nil
+}
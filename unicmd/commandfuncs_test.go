@@ -0,0 +1,327 @@
+package unicmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yonomesh/uni"
+)
+
+type testWidget struct {
+	Size int `json:"size,omitempty"`
+}
+
+func (testWidget) UniModule() uni.ModuleInfo {
+	return uni.ModuleInfo{ID: "unicmd_test.widget", New: func() uni.Module { return new(testWidget) }}
+}
+
+func init() {
+	uni.RegisterModule(testWidget{})
+}
+
+// runCommand runs the default command with the given arguments
+// the way the uni binary does, and returns what it printed to
+// standard output.
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	factory := NewRootCmdFactory(func() *cobra.Command {
+		return &cobra.Command{Use: "uni", SilenceUsage: true, SilenceErrors: true}
+	})
+	RegisterDefaultCommands(factory)
+	root := factory.Build()
+	root.SetArgs(args)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	err = root.Execute()
+	w.Close()
+	return <-out, err
+}
+
+// writeFile writes content to a file in a temporary
+// directory and returns its name.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	name = filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestRegisterDefaultCommands(t *testing.T) {
+	factory := NewRootCmdFactory(func() *cobra.Command { return &cobra.Command{Use: "uni"} })
+	RegisterDefaultCommands(factory)
+
+	want := []string{"check-modules", "docs", "expand-config", "json-schema", "list-placeholders", "logs", "validate", "verify-audit-log"}
+	var names []string
+	for name := range factory.Commands() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, want) {
+		t.Errorf("registered %q, want %q", names, want)
+	}
+
+	root := factory.Build()
+	for _, name := range want {
+		cmd, _, err := root.Find([]string{name})
+		if err != nil || cmd.Name() != name {
+			t.Errorf("root command has no %s subcommand: %v", name, err)
+			continue
+		}
+		if cmd.RunE == nil {
+			t.Errorf("%s: no RunE", name)
+		}
+	}
+}
+
+func TestCmdLogs(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs/recent" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.RawQuery
+		if r.URL.Query().Get("buffer") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "no ring buffer named missing"}`))
+			return
+		}
+		w.Write([]byte(`[{"msg": "first"}, {"msg": "second"}]`))
+	}))
+	defer srv.Close()
+
+	out, err := runCommand(t, "logs", "--address", srv.URL, "--level", "warn", "--logger", "http", "-n", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"msg\": \"first\"}\n{\"msg\": \"second\"}\n"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if want := "level=warn&limit=2&logger=http"; query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+
+	_, err = runCommand(t, "logs", "--address", srv.URL, "--buffer", "missing")
+	if err == nil || !strings.Contains(err.Error(), "no ring buffer named missing") {
+		t.Errorf("got error %v, want the message of the admin API", err)
+	}
+}
+
+func TestCmdVerifyAuditLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg", TimeKey: "ts", EncodeTime: zapcore.EpochTimeEncoder})
+	al := uni.NewAuditLog(zapcore.NewCore(enc, f, zapcore.InfoLevel), []byte("secret"))
+	for range 3 {
+		if err := al.Write(uni.LogEntry{Category: "user-action", Msg: uni.LogMsg("user logged in")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := al.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	keyFile := writeFile(t, "key", "secret\n")
+
+	out, err := runCommand(t, "verify-audit-log", "--file", name, "-k", keyFile)
+	if err != nil {
+		t.Fatalf("%v; output:\n%s", err, out)
+	}
+	if want := "4 entries in 1 chain(s), 0 problem(s)\n"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	out, err = runCommand(t, "verify-audit-log", "--file", name, "--json")
+	if err == nil {
+		t.Error("expected error verifying the log without its key")
+	}
+	var report uni.AuditReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Entries != 4 || len(report.Problems) == 0 {
+		t.Errorf("report without key = %+v, want problems in 4 entries", report)
+	}
+
+	if _, err := runCommand(t, "verify-audit-log", "--file", name, "-k", filepath.Join(t.TempDir(), "none")); err == nil {
+		t.Error("expected error for a missing key file")
+	}
+}
+
+func TestCmdListPlaceholders(t *testing.T) {
+	out, err := runCommand(t, "list-placeholders", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var namespaces []uni.PlaceholderNamespace
+	if err := json.Unmarshal([]byte(out), &namespaces); err != nil {
+		t.Fatal(err)
+	}
+	if len(namespaces) != len(uni.PlaceholderNamespaces()) {
+		t.Errorf("listed %d namespaces, want %d", len(namespaces), len(uni.PlaceholderNamespaces()))
+	}
+
+	out, err = runCommand(t, "list-placeholders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "{env.*}") {
+		t.Errorf("output does not list {env.*}:\n%s", out)
+	}
+}
+
+func TestCmdExpandConfig(t *testing.T) {
+	t.Setenv("UNICMD_TEST_LISTEN", "localhost:2020")
+	config := writeFile(t, "config.json", `{"admin": {"listen": "{env.UNICMD_TEST_LISTEN}"}, "name": "{system.hostname}-{runtime.id}"}`)
+
+	out, err := runCommand(t, "expand-config", "--config", config, "--namespaces", "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expanded struct {
+		Admin struct{ Listen string }
+		Name  string
+	}
+	if err := json.Unmarshal([]byte(out), &expanded); err != nil {
+		t.Fatal(err)
+	}
+	if expanded.Admin.Listen != "localhost:2020" || expanded.Name != "{system.hostname}-{runtime.id}" {
+		t.Errorf("expanded config = %+v, want only the env placeholder expanded", expanded)
+	}
+
+	config = writeFile(t, "config.json", `{"admin": {"listen": "{system.nope}"}}`)
+	if _, err := runCommand(t, "expand-config", "-c", config); err != nil {
+		t.Errorf("unexpected error without --strict: %v", err)
+	}
+	if _, err := runCommand(t, "expand-config", "-c", config, "--strict"); err == nil {
+		t.Error("expected error expanding an unknown placeholder with --strict")
+	}
+}
+
+func TestCmdValidate(t *testing.T) {
+	out, err := runCommand(t, "validate", "--config", writeFile(t, "config.json", `{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Valid configuration\n" {
+		t.Errorf("output = %q", out)
+	}
+
+	_, err = runCommand(t, "validate", "--config", writeFile(t, "config.json", "{\n\t\"admin\": {\"port\": 2019}\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 2, column 12: /admin/port: ") {
+		t.Errorf("got error %v, want one locating the unknown field", err)
+	}
+}
+
+func TestCmdCheckModules(t *testing.T) {
+	problems := uni.CheckModules()
+	out, err := runCommand(t, "check-modules", "--json")
+	if (err != nil) != (len(problems) > 0) {
+		t.Errorf("got error %v with %d problems", err, len(problems))
+	}
+	var listed []uni.ModuleProblem
+	if err := json.Unmarshal([]byte(out), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != len(problems) {
+		t.Errorf("listed %d problems, want %d", len(listed), len(problems))
+	}
+}
+
+func TestCmdJSONSchema(t *testing.T) {
+	out, err := runCommand(t, "json-schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema uni.JSONSchema
+	if err := json.Unmarshal([]byte(out), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Properties["admin"] == nil {
+		t.Error("config schema has no admin property")
+	}
+
+	out, err = runCommand(t, "json-schema", "--module", "unicmd_test.widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema = uni.JSONSchema{}
+	if err := json.Unmarshal([]byte(out), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if widget := schema.Defs["unicmd_test.widget"]; widget == nil || widget.Properties["size"] == nil {
+		t.Errorf("module schema has no size property:\n%s", out)
+	}
+
+	if _, err := runCommand(t, "json-schema", "-m", "unicmd_test.nope"); err == nil {
+		t.Error("expected error for an unknown module")
+	}
+}
+
+func TestCmdDocs(t *testing.T) {
+	out, err := runCommand(t, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(strings.Split(out, "\n"), "unicmd_test.widget") {
+		t.Errorf("module list does not contain the test module:\n%s", out)
+	}
+
+	out, err = runCommand(t, "docs", "unicmd_test.widget", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var md uni.ModuleDoc
+	if err := json.Unmarshal([]byte(out), &md); err != nil {
+		t.Fatal(err)
+	}
+	if md.ID != "unicmd_test.widget" || len(md.Fields) != 1 || md.Fields[0].Name != "size" {
+		t.Errorf("docs = %+v, want the test module with its size field", md)
+	}
+
+	out, err = runCommand(t, "docs", "unicmd_test.widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out, "\n")
+	if !slices.ContainsFunc(lines, func(line string) bool {
+		return slices.Equal(strings.Fields(line), []string{"ID:", "unicmd_test.widget"})
+	}) || !slices.ContainsFunc(lines, func(line string) bool {
+		return slices.Equal(strings.Fields(line), []string{"size", "int"})
+	}) {
+		t.Errorf("output does not show the ID and fields of the module:\n%s", out)
+	}
+
+	if _, err := runCommand(t, "docs", "unicmd_test.nope"); err == nil {
+		t.Error("expected error for an unknown module")
+	}
+}
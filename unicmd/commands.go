@@ -0,0 +1,166 @@
+package unicmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// RegisterDefaultCommands registers the commands that
+// ship with uni on the given factory.
+func RegisterDefaultCommands(factory *RootCmdFactory) {
+	factory.RegisterCommand(Command{
+		Name:  "logs",
+		Usage: "[--address <interface>] [--buffer <name>] [--level <level>] [--logger <name>] [--since <time>] [--until <time>] [--contains <text>] [--limit <n>]",
+		Short: "Shows recent log entries retained in memory",
+		Long: `
+Queries the running instance's admin API for log entries retained
+by a ring buffer core (uni.logging.cores.ring_buffer) and prints
+them as JSON lines, oldest first. The config of the instance must
+serve the admin endpoint (see its "admin" setting).
+
+--since and --until accept RFC 3339 timestamps or durations relative
+to now, for example "10m". --logger matches a logger name and all of
+its children, so "http" matches "http.handlers" as well.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Flags().StringP("address", "", "", "The address of the admin API")
+			cmd.Flags().StringP("buffer", "b", "", "Name of the ring buffer to query")
+			cmd.Flags().StringP("level", "l", "", "Minimum level of entries to show")
+			cmd.Flags().StringP("logger", "", "", "Only show entries of this logger (and its children)")
+			cmd.Flags().StringP("since", "", "", "Only show entries at or after this time")
+			cmd.Flags().StringP("until", "", "", "Only show entries at or before this time")
+			cmd.Flags().StringP("contains", "c", "", "Only show entries containing this text")
+			cmd.Flags().IntP("limit", "n", 0, "Maximum number of (most recent) entries to show")
+			cmd.RunE = CommandFuncToCobraRunE(cmdLogs)
+		},
+	})
+	factory.RegisterCommand(Command{
+		Name:  "verify-audit-log",
+		Usage: "[--file <path>] [--key-file <path>] [--json]",
+		Short: "Verifies the hash chain of an audit log file",
+		Long: `
+Reads an audit log written by the log named "audit" (which must use
+the JSON encoder) and checks its hash chain. Every entry that was
+modified, is out of order or follows missing entries is reported,
+and the command exits with a non-zero status if any are found.
+
+If the log was written with an audit_key, --key-file must name a
+file holding the same key; a trailing newline is ignored.
+
+If --file is "-" or omitted, the log is read from standard input.
+With --json, the full report is printed as JSON.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Flags().StringP("file", "f", "", "The audit log file to verify")
+			cmd.Flags().StringP("key-file", "k", "", "File holding the key the audit log was written with")
+			cmd.Flags().BoolP("json", "", false, "Print the report as JSON")
+			cmd.RunE = CommandFuncToCobraRunE(cmdVerifyAuditLog)
+		},
+	})
+	factory.RegisterCommand(Command{
+		Name:  "list-placeholders",
+		Usage: "[--json]",
+		Short: "Lists the placeholders that are available in this build",
+		Long: `
+Lists the placeholder namespaces registered by the modules compiled
+into this binary, along with the placeholders in each namespace. A
+placeholder ending in "*" stands for a family of placeholders: for
+example {env.*} covers {env.HOME}, {env.PORT} and so on.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Flags().BoolP("json", "", false, "Print the namespaces as JSON")
+			cmd.RunE = CommandFuncToCobraRunE(cmdListPlaceholders)
+		},
+	})
+	factory.RegisterCommand(Command{
+		Name:  "expand-config",
+		Usage: "[--config <path>] [--namespaces <list>] [--strict] [--report]",
+		Short: "Expands placeholders in a JSON config",
+		Long: `
+Expands the {env.*}, {file.*} and {system.*} placeholders in all
+string values of a JSON config and prints the resulting config, the
+same way it is done at load time for configs which opt in to it.
+Other placeholders, such as ones only known at runtime, are left as
+they are. {secret.*} placeholders are never expanded.
+
+If --config is "-" or omitted, the config is read from standard input.
+--namespaces takes a comma-separated list of namespaces to expand
+instead of the default ones. With --strict, unknown placeholders in
+those namespaces, and placeholders whose value can not be produced,
+are an error. With --report, the config paths of all substituted
+values are printed to standard error.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Flags().StringP("config", "c", "", "The JSON config file to expand")
+			cmd.Flags().StringP("namespaces", "n", "", "Comma-separated placeholder namespaces to expand")
+			cmd.Flags().BoolP("strict", "", false, "Fail on unknown placeholders")
+			cmd.Flags().BoolP("report", "", false, "Print the substituted config paths to stderr")
+			cmd.RunE = CommandFuncToCobraRunE(cmdExpandConfig)
+		},
+	})
+	factory.RegisterCommand(Command{
+		Name:  "validate",
+		Usage: "[--config <path>]",
+		Short: "Tests whether a JSON config is valid",
+		Long: `
+Loads and provisions the modules of a JSON config without running
+it, expanding its placeholders first if it opts in to that. If the
+config is invalid, the error names the line and column of the
+offending value, which is also shown in the config.
+
+If --config is "-" or omitted, the config is read from standard input.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Flags().StringP("config", "c", "", "The JSON config file to validate")
+			cmd.RunE = CommandFuncToCobraRunE(cmdValidate)
+		},
+	})
+	factory.RegisterCommand(Command{
+		Name:  "check-modules",
+		Usage: "[--json]",
+		Short: "Checks that the modules in this build fit their hosts",
+		Long: `
+Checks the modules compiled into this binary for inconsistencies
+between host modules and the modules they load: module fields whose
+namespace has no registered modules, malformed module struct tags,
+and modules which do not implement the interface their namespace
+requires. Exits with a non-zero status if any are found.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Flags().BoolP("json", "", false, "Print the problems as JSON")
+			cmd.RunE = CommandFuncToCobraRunE(cmdCheckModules)
+		},
+	})
+	factory.RegisterCommand(Command{
+		Name:  "json-schema",
+		Usage: "[--module <id>] [--source <dir>]",
+		Short: "Prints a JSON Schema for the config or a module",
+		Long: `
+Prints a JSON Schema (draft 2020-12) for the config, covering all
+modules compiled into this binary, or with --module, for the config
+of a single module. Editors and CI can validate configs against it.
+
+With --source, doc comments are read from the Go sources of the
+module in the given directory (which must contain go.mod) and
+included as descriptions.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Flags().StringP("module", "m", "", "The ID of the module to describe")
+			cmd.Flags().StringP("source", "s", "", "Directory of the Go module to read doc comments from")
+			cmd.RunE = CommandFuncToCobraRunE(cmdJSONSchema)
+		},
+	})
+	factory.RegisterCommand(Command{
+		Name:  "docs",
+		Usage: "[<module-id>] [--source <dir>] [--json]",
+		Short: "Shows the documentation of a module",
+		Long: `
+Shows the documentation of the module with the given ID: its
+namespace, Go package and type, config fields, the interfaces it
+implements and the fields of other modules which can load it.
+Without a module ID, the IDs of all modules in this build are listed.
+
+With --source, doc comments are read from the Go sources of the
+module in the given directory (which must contain go.mod), to show
+descriptions and default values. The same information, without doc
+comments, is served by the admin API at /docs/<module-id>.`,
+		CobraFunc: func(cmd *cobra.Command) {
+			cmd.Args = cobra.MaximumNArgs(1)
+			cmd.Flags().StringP("source", "s", "", "Directory of the Go module to read doc comments from")
+			cmd.Flags().BoolP("json", "", false, "Print the documentation as JSON")
+			cmd.RunE = CommandFuncToCobraRunE(cmdDocs)
+		},
+	})
+}